@@ -18,12 +18,15 @@ package gubernator
 
 import (
 	"context"
+	"math"
 
 	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
 )
 
 // ### NOTE ###
@@ -33,6 +36,34 @@ import (
 // limit, but we do not set the remainder to 0 in the cache. The client can retry within the same window
 // with 100 emails and the request will succeed. You can override this default behavior with `DRAIN_OVER_LIMIT`
 
+// getCacheItem gets the rate limit from the cache, on a cache miss the rate limit is read
+// from the store and added to the cache.
+func getCacheItem(ctx context.Context, s Store, c Cache, r *RateLimitReq, hashKey string) (*CacheItem, bool) {
+	item, ok := c.GetItem(hashKey)
+	if ok {
+		cacheNames.inc(r.Name, "hit")
+		return item, true
+	}
+	cacheNames.inc(r.Name, "miss")
+	if s == nil {
+		return nil, false
+	}
+
+	// Cache miss.
+	// Check our store for the item.
+	item, ok = s.Get(ctx, r)
+	if counter, isCounter := c.(cacheStoreCounter); isCounter {
+		counter.countStoreAccess(ok)
+	}
+	if ok {
+		cacheNames.inc(r.Name, "store_hit")
+		c.Add(item)
+		return item, true
+	}
+	cacheNames.inc(r.Name, "store_miss")
+	return nil, false
+}
+
 // Implements token bucket algorithm for rate limiting. https://en.wikipedia.org/wiki/Token_bucket
 func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq, reqState RateLimitReqState) (resp *RateLimitResp, err error) {
 	tokenBucketTimer := prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("tokenBucket"))
@@ -40,15 +71,7 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq, reqStat
 
 	// Get rate limit from cache.
 	hashKey := r.HashKey()
-	item, ok := c.GetItem(hashKey)
-
-	if s != nil && !ok {
-		// Cache miss.
-		// Check our store for the item.
-		if item, ok = s.Get(ctx, r); ok {
-			c.Add(item)
-		}
-	}
+	item, ok := getCacheItem(ctx, s, c, r, hashKey)
 
 	// Sanity checks.
 	if ok {
@@ -269,15 +292,7 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq, reqStat
 
 	// Get rate limit from cache.
 	hashKey := r.HashKey()
-	item, ok := c.GetItem(hashKey)
-
-	if s != nil && !ok {
-		// Cache miss.
-		// Check our store for the item.
-		if item, ok = s.Get(ctx, r); ok {
-			c.Add(item)
-		}
-	}
+	item, ok := getCacheItem(ctx, s, c, r, hashKey)
 
 	// Sanity checks.
 	if ok {
@@ -491,3 +506,77 @@ func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq,
 
 	return &rl, nil
 }
+
+// leaseTokens returns the unused tokens of a previous lease to the rate limit, then removes up to
+// `r.Tokens` from the rate limit such that they can be spent by the client holding the lease.
+// Returns the status of the rate limit after the lease and the number of tokens granted.
+func leaseTokens(ctx context.Context, s Store, c Cache, r *LeaseReq, returned int64) (*RateLimitResp, int64, error) {
+	req := proto.Clone(r.RateLimit).(*RateLimitReq)
+	reqState := RateLimitReqState{IsOwner: true}
+
+	var algorithm func(context.Context, Store, Cache, *RateLimitReq, RateLimitReqState) (*RateLimitResp, error)
+	switch req.Algorithm {
+	case Algorithm_TOKEN_BUCKET:
+		algorithm = tokenBucket
+	case Algorithm_LEAKY_BUCKET:
+		algorithm = leakyBucket
+	default:
+		return nil, 0, notAppliedError{errors.Errorf("Invalid rate limit algorithm '%d'", req.Algorithm)}
+	}
+
+	if returned > 0 {
+		returnTokens(ctx, s, c, req, returned)
+	}
+
+	// Find out how many tokens remain without spending any.
+	req.Hits = 0
+	rl, err := algorithm(ctx, s, c, req, reqState)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	granted := r.Tokens
+	if granted > rl.Remaining {
+		granted = rl.Remaining
+	}
+	if granted <= 0 {
+		return rl, 0, nil
+	}
+
+	// The first call already applied the reset if requested.
+	SetBehavior(&req.Behavior, Behavior_RESET_REMAINING, false)
+	req.Hits = granted
+	rl, err = algorithm(ctx, s, c, req, reqState)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rl.Status == Status_OVER_LIMIT {
+		return rl, 0, nil
+	}
+	return rl, granted, nil
+}
+
+// returnTokens adds unused leased tokens back to the rate limit. If the rate limit is
+// neither in the cache nor the store, the tokens are dropped as the rate limit has been reset.
+func returnTokens(ctx context.Context, s Store, c Cache, r *RateLimitReq, tokens int64) {
+	item, ok := getCacheItem(ctx, s, c, r, r.HashKey())
+	if !ok {
+		return
+	}
+	if s != nil {
+		defer s.OnChange(ctx, r, item)
+	}
+
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		t.Remaining += tokens
+		if t.Remaining > t.Limit {
+			t.Remaining = t.Limit
+		}
+		if t.Remaining > 0 {
+			t.Status = Status_UNDER_LIMIT
+		}
+	case *LeakyBucketItem:
+		t.Remaining = math.Min(float64(t.Burst), t.Remaining+float64(tokens))
+	}
+}
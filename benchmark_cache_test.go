@@ -23,6 +23,13 @@ func BenchmarkCache(b *testing.B) {
 			},
 			LockRequired: true,
 		},
+		{
+			Name: "TinyLFUCache",
+			NewTestCache: func() gubernator.Cache {
+				return gubernator.NewTinyLFUCache(0)
+			},
+			LockRequired: true,
+		},
 	}
 
 	for _, testCase := range testCases {
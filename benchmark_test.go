@@ -17,14 +17,22 @@ limitations under the License.
 package gubernator_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	guber "github.com/gubernator-io/gubernator/v2"
 	"github.com/gubernator-io/gubernator/v2/cluster"
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/syncutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
 )
 
 func BenchmarkServer(b *testing.B) {
@@ -146,3 +154,149 @@ func BenchmarkServer(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkCacheEngine compares the throughput and tail latency of the cache engines
+// when many goroutines apply rate limits to few keys (contended) or many keys.
+func BenchmarkCacheEngine(b *testing.B) {
+	ctx := context.Background()
+	createdAt := epochMillis(clock.Now())
+
+	for _, engine := range []string{guber.EngineWorkerPool, guber.EngineShardedMutex} {
+		for _, numKeys := range []int{8, 10_000} {
+			b.Run(fmt.Sprintf("%s/keys=%d", engine, numKeys), func(b *testing.B) {
+				conf := &guber.Config{Engine: engine}
+				require.NoError(b, conf.SetDefaults())
+				e := guber.NewCacheEngine(conf)
+				defer e.Close()
+
+				keys := make([]string, numKeys)
+				for i := range keys {
+					keys[i] = guber.RandomString(10)
+				}
+
+				var mutex sync.Mutex
+				var latencies []time.Duration
+				b.SetParallelism(8)
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					local := make([]time.Duration, 0, 1024)
+					for i := 0; pb.Next(); i++ {
+						req := &guber.RateLimitReq{
+							Name:      "bench_engine",
+							UniqueKey: keys[i%numKeys],
+							Algorithm: guber.Algorithm_TOKEN_BUCKET,
+							Limit:     1_000_000,
+							Duration:  guber.Minute,
+							Hits:      1,
+							CreatedAt: &createdAt,
+						}
+						start := time.Now()
+						_, err := e.GetRateLimit(ctx, req, guber.RateLimitReqState{IsOwner: true})
+						local = append(local, time.Since(start))
+						if err != nil {
+							b.Errorf("Error in GetRateLimit: %s", err)
+						}
+					}
+					mutex.Lock()
+					latencies = append(latencies, local...)
+					mutex.Unlock()
+				})
+
+				b.StopTimer()
+				if len(latencies) == 0 {
+					return
+				}
+				sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+				b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+			})
+		}
+	}
+}
+
+// BenchmarkPeerCompression compares the CPU each codec of GUBER_PEER_COMPRESSION spends on a
+// batch of peer requests with the bytes it sends
+func BenchmarkPeerCompression(b *testing.B) {
+	createdAt := epochMillis(clock.Now())
+	var rateLimits guber.GetPeerRateLimitsReq
+	var globals guber.UpdatePeerGlobalsReq
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("requests_per_sec_%d", i%10)
+		key := fmt.Sprintf("account:%06d", i)
+		rateLimits.Requests = append(rateLimits.Requests, &guber.RateLimitReq{
+			Name:      name,
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Behavior:  guber.Behavior_GLOBAL,
+			Duration:  guber.Minute,
+			Limit:     1000,
+			Hits:      1,
+			CreatedAt: &createdAt,
+		})
+		globals.Globals = append(globals.Globals, &guber.UpdatePeerGlobal{
+			Key:       name + "_" + key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			CreatedAt: createdAt,
+			Status: &guber.RateLimitResp{
+				Limit:     1000,
+				Remaining: int64(1000 - i),
+				ResetTime: createdAt + guber.Minute,
+			},
+		})
+	}
+
+	messages := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{"GetPeerRateLimitsReq", &rateLimits},
+		{"UpdatePeerGlobalsReq", &globals},
+	}
+	for _, m := range messages {
+		raw, err := proto.Marshal(m.msg)
+		require.NoError(b, err)
+
+		for _, name := range []string{guber.CompressionGzip, guber.CompressionSnappy, guber.CompressionZstd} {
+			c := encoding.GetCompressor(name)
+			var compressed bytes.Buffer
+			w, err := c.Compress(&compressed)
+			require.NoError(b, err)
+			_, err = w.Write(raw)
+			require.NoError(b, err)
+			require.NoError(b, w.Close())
+
+			b.Run(fmt.Sprintf("%s/%s/compress", m.name, name), func(b *testing.B) {
+				var buf bytes.Buffer
+				b.SetBytes(int64(len(raw)))
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					buf.Reset()
+					w, err := c.Compress(&buf)
+					if err != nil {
+						b.Fatal(err)
+					}
+					_, _ = w.Write(raw)
+					_ = w.Close()
+				}
+				b.ReportMetric(float64(compressed.Len()), "bytes/msg")
+				b.ReportMetric(float64(len(raw))/float64(compressed.Len()), "ratio")
+			})
+
+			b.Run(fmt.Sprintf("%s/%s/decompress", m.name, name), func(b *testing.B) {
+				b.SetBytes(int64(len(raw)))
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					r, err := c.Decompress(bytes.NewReader(compressed.Bytes()))
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := io.Copy(io.Discard, r); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/pkg/errors"
+)
+
+// DefaultBoundedLoadFactor is the load each peer of the BoundedLoadHash may receive relative to
+// its share of the total load, before it moves keys to other peers.
+const DefaultBoundedLoadFactor = 1.25
+
+// boundedLoadSteps is the number of steps the fraction of the keys a peer moves to other peers
+// is rounded up to, such that small changes in load don't move keys back and forth.
+const boundedLoadSteps = 16
+
+// Implements PeerPicker using consistent hashing with bounded loads, see
+// https://research.google/blog/consistent-hashing-with-bounded-loads/
+// Keys are assigned to peers by a ReplicatedConsistentHash. Every instance counts the checks
+// it hashes to each peer, and periodically sums the counts of all instances with SetLoad().
+// When a peer receives more than `factor` times its share of the total load, a fraction of
+// its keys just large enough to bring it under the bound moves to the next peer on the ring
+// which is within its own bound.
+//
+// The instances sample their load at different times, so they would not agree on the
+// fractions of the keys to move if each computed its own. Instead the instance with the
+// lowest GRPC address computes the shed table from the load of every instance, and the other
+// instances replace their table with its table using SetShedTable(), such that every
+// instance moves the same keys. The load is smoothed and the fractions are rounded to reduce
+// how often keys move. Like a change of the peers, a change of the moved keys resets the
+// rate limits of those keys.
+type BoundedLoadHash struct {
+	ring   *ReplicatedConsistentHash
+	factor float64
+	load   *boundedLoad
+	// The count of checks hashed to each peer since the last SampleLoad()
+	counters map[string]*atomic.Int64
+	// The counters of the picker this picker was created from, such that counts carry over to the new picker
+	prevCounters map[string]*atomic.Int64
+}
+
+// boundedLoad is the load shared by a BoundedLoadHash and every picker created from it with New()
+type boundedLoad struct {
+	mutex     sync.Mutex
+	sampledAt time.Time // GUARDED_BY(mutex)
+	observed  atomic.Pointer[map[string]float64]
+	shed      atomic.Pointer[shedTable]
+}
+
+// shedTable is the fraction of the keys of each peer which are moved to other peers
+type shedTable struct {
+	// Changes whenever the table is computed, 0 until a table is computed
+	version int64
+	shed    map[string]float64
+}
+
+// NewBoundedLoadHash creates a BoundedLoadHash which bounds the load of each peer to `factor`
+// times its share of the total load, which must be greater than or equal to 1.
+func NewBoundedLoadHash(fn HashString64, replicas int, factor float64) *BoundedLoadHash {
+	if factor == 0 {
+		factor = DefaultBoundedLoadFactor
+	}
+
+	load := &boundedLoad{sampledAt: clock.Now()}
+	load.observed.Store(&map[string]float64{})
+	load.shed.Store(&shedTable{shed: map[string]float64{}})
+
+	return &BoundedLoadHash{
+		ring:     NewReplicatedConsistentHash(fn, replicas),
+		factor:   factor,
+		load:     load,
+		counters: make(map[string]*atomic.Int64),
+	}
+}
+
+func (bh *BoundedLoadHash) New() PeerPicker {
+	return &BoundedLoadHash{
+		ring:         bh.ring.New().(*ReplicatedConsistentHash),
+		factor:       bh.factor,
+		load:         bh.load,
+		counters:     make(map[string]*atomic.Int64),
+		prevCounters: bh.counters,
+	}
+}
+
+func (bh *BoundedLoadHash) Peers() []*PeerClient {
+	return bh.ring.Peers()
+}
+
+// Adds a peer to the hash
+func (bh *BoundedLoadHash) Add(peer *PeerClient) {
+	bh.ring.Add(peer)
+
+	addr := peer.Info().GRPCAddress
+	counter := bh.prevCounters[addr]
+	if counter == nil {
+		counter = new(atomic.Int64)
+	}
+	bh.counters[addr] = counter
+}
+
+// Returns number of peers in the picker
+func (bh *BoundedLoadHash) Size() int {
+	return bh.ring.Size()
+}
+
+// Returns the peer by hostname
+func (bh *BoundedLoadHash) GetByPeerInfo(peer PeerInfo) *PeerClient {
+	return bh.ring.GetByPeerInfo(peer)
+}
+
+// Ownership returns the fraction of the hash space owned by each peer, before the load is bounded
+func (bh *BoundedLoadHash) Ownership() map[string]float64 {
+	return bh.ring.Ownership()
+}
+
+// Given a key, return the peer that key is assigned too
+func (bh *BoundedLoadHash) Get(key string) (*PeerClient, error) {
+	if bh.Size() == 0 {
+		return nil, errors.New("unable to pick a peer; pool is empty")
+	}
+	hash := bh.ring.hashFunc(key)
+	idx := bh.ring.search(hash)
+	peer := bh.ring.peerKeys[idx].peer
+	bh.counters[peer.Info().GRPCAddress].Add(1)
+
+	// Peers move the keys with the lowest mixed hashes first, such that moving a larger
+	// fraction of the keys of a peer doesn't move the keys which were already moved again.
+	shed := bh.load.shed.Load().shed
+	if float64(mixHash64(hash)>>11)/(1<<53) >= shed[peer.Info().GRPCAddress] {
+		return peer, nil
+	}
+
+	for i := 1; i < len(bh.ring.peerKeys); i++ {
+		next := bh.ring.peerKeys[(idx+i)%len(bh.ring.peerKeys)].peer
+		if shed[next.Info().GRPCAddress] == 0 {
+			return next, nil
+		}
+	}
+	return peer, nil
+}
+
+// SampleLoad returns the rate of checks per second hashed to each peer since the previous sample
+func (bh *BoundedLoadHash) SampleLoad() map[string]float64 {
+	bh.load.mutex.Lock()
+	defer bh.load.mutex.Unlock()
+
+	now := clock.Now()
+	elapsed := now.Sub(bh.load.sampledAt).Seconds()
+	if elapsed <= 0 {
+		return bh.ObservedLoad()
+	}
+	bh.load.sampledAt = now
+
+	prev := bh.ObservedLoad()
+	observed := make(map[string]float64, len(bh.counters))
+	for addr, counter := range bh.counters {
+		rate := float64(counter.Swap(0)) / elapsed
+		// Average with the previous sample, such that a short burst of checks doesn't move keys
+		if prevRate, ok := prev[addr]; ok {
+			rate = (rate + prevRate) / 2
+		}
+		observed[addr] = rate
+	}
+	bh.load.observed.Store(&observed)
+	return observed
+}
+
+// ObservedLoad returns the result of the latest SampleLoad()
+func (bh *BoundedLoadHash) ObservedLoad() map[string]float64 {
+	return *bh.load.observed.Load()
+}
+
+// SetLoad sets the total rate of checks per second all instances hashed to each peer, and
+// moves keys away from the peers which are above their bound.
+func (bh *BoundedLoadHash) SetLoad(load map[string]float64) {
+	var total float64
+	var weights int
+	for addr, peer := range bh.ring.peers {
+		total += load[addr]
+		weights += peer.Info().weight()
+	}
+
+	shed := make(map[string]float64)
+	for addr, peer := range bh.ring.peers {
+		bound := bh.factor * total * float64(peer.Info().weight()) / float64(weights)
+		if load[addr] <= bound {
+			continue
+		}
+		// Move just enough keys to bring the peer under its bound. The load counts the
+		// checks hashed to the peer before any keys are moved, so the fraction doesn't
+		// change once the keys are moved.
+		fraction := math.Ceil((1-bound/load[addr])*boundedLoadSteps) / boundedLoadSteps
+		shed[addr] = math.Min(fraction, (boundedLoadSteps-1)/float64(boundedLoadSteps))
+	}
+
+	// The version only needs to differ from the previous versions, which the time of the
+	// computation does even when another instance computed the previous table.
+	version := clock.Now().UnixNano()
+	if prev := bh.load.shed.Load().version; version <= prev {
+		version = prev + 1
+	}
+	bh.load.shed.Store(&shedTable{version: version, shed: shed})
+}
+
+// ShedTable returns the version of the shed table and the fraction of the keys of each peer
+// which are moved to other peers
+func (bh *BoundedLoadHash) ShedTable() (int64, map[string]float64) {
+	table := bh.load.shed.Load()
+	return table.version, table.shed
+}
+
+// SetShedTable replaces the shed table with the table another instance computed with SetLoad()
+func (bh *BoundedLoadHash) SetShedTable(version int64, shed map[string]float64) {
+	if shed == nil {
+		shed = map[string]float64{}
+	}
+	bh.load.shed.Store(&shedTable{version: version, shed: shed})
+}
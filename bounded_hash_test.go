@@ -0,0 +1,327 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
+	"github.com/segmentio/fasthash/fnv1"
+	"github.com/segmentio/fasthash/fnv1a"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestBoundedLoadHash(t *testing.T) {
+	hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
+
+	t.Run("Size", func(t *testing.T) {
+		hash := NewBoundedLoadHash(nil, DefaultReplicas, 0)
+
+		for _, h := range hosts {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+		}
+
+		assert.Equal(t, len(hosts), hash.Size())
+	})
+
+	t.Run("Host", func(t *testing.T) {
+		hash := NewBoundedLoadHash(nil, DefaultReplicas, 0)
+		hostMap := map[string]*PeerClient{}
+
+		for _, h := range hosts {
+			peer := &PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}}
+			hash.Add(peer)
+			hostMap[h] = peer
+		}
+
+		for host, peer := range hostMap {
+			assert.Equal(t, peer, hash.GetByPeerInfo(PeerInfo{GRPCAddress: host}))
+		}
+	})
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+	}
+
+	t.Run("Same as replicated hash without load", func(t *testing.T) {
+		bounded := NewBoundedLoadHash(nil, DefaultReplicas, 0)
+		replicated := NewReplicatedConsistentHash(nil, DefaultReplicas)
+		for _, h := range hosts {
+			peer := &PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}}
+			bounded.Add(peer)
+			replicated.Add(peer)
+		}
+
+		for i := range keys {
+			expected, _ := replicated.Get(keys[i])
+			peer, _ := bounded.Get(keys[i])
+			assert.Equal(t, expected, peer)
+		}
+	})
+
+	t.Run("Bounds the load of an overloaded peer", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		const factor = 1.25
+		hash := NewBoundedLoadHash(nil, DefaultReplicas, factor)
+		for _, h := range hosts {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+		}
+
+		// Every key of a.svc.local receives 4 checks a second, while every other key receives 1
+		owners := make(map[string]string)
+		for i := range keys {
+			peer, _ := hash.ring.Get(keys[i])
+			owners[keys[i]] = peer.Info().GRPCAddress
+		}
+		checks := func(key string) int {
+			if owners[key] == "a.svc.local" {
+				return 4
+			}
+			return 1
+		}
+
+		// check sends a second of checks and returns the load on each peer, and the peer of each key
+		check := func() (map[string]float64, map[string]string) {
+			load := make(map[string]float64)
+			assigned := make(map[string]string)
+			for i := range keys {
+				for n := 0; n < checks(keys[i]); n++ {
+					peer, err := hash.Get(keys[i])
+					require.NoError(t, err)
+					load[peer.Info().GRPCAddress]++
+					assigned[keys[i]] = peer.Info().GRPCAddress
+				}
+			}
+			clock.Advance(time.Second)
+			return load, assigned
+		}
+
+		load, _ := check()
+		var total float64
+		for _, rate := range load {
+			total += rate
+		}
+		bound := factor * total / float64(len(hosts))
+		require.Greater(t, load["a.svc.local"], bound)
+		assert.Equal(t, load, hash.SampleLoad())
+		assert.Equal(t, load, hash.ObservedLoad())
+		hash.SetLoad(hash.SampleLoad())
+
+		load, moved := check()
+		for _, h := range hosts {
+			// Keys move in whole steps, so the bound may be exceeded by a fraction of a step
+			assert.LessOrEqual(t, load[h], bound*1.05, h)
+		}
+
+		// The load counts the peer each key hashed to before the load was bounded, so setting the
+		// load of the same checks again moves no keys
+		hash.SetLoad(hash.SampleLoad())
+		_, assigned := check()
+		assert.Equal(t, moved, assigned)
+
+		// Only keys of the overloaded peer moved
+		for key, owner := range moved {
+			if owner != owners[key] {
+				assert.Equal(t, "a.svc.local", owners[key])
+			}
+		}
+
+		// A new picker with the same peers keeps the load
+		picker := hash.New()
+		for _, p := range hash.Peers() {
+			picker.Add(p)
+		}
+		for i := range keys {
+			peer, _ := picker.Get(keys[i])
+			assert.Equal(t, moved[keys[i]], peer.Info().GRPCAddress)
+		}
+	})
+
+	t.Run("Counts carry over to a new picker", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		hash := NewBoundedLoadHash(nil, DefaultReplicas, 0)
+		for _, h := range hosts {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+		}
+		for i := range keys {
+			_, _ = hash.Get(keys[i])
+		}
+
+		// Replace c.svc.local with d.svc.local
+		picker := hash.New().(*BoundedLoadHash)
+		for _, p := range hash.Peers() {
+			if p.Info().GRPCAddress != "c.svc.local" {
+				picker.Add(p)
+			}
+		}
+		picker.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "d.svc.local"}}})
+
+		clock.Advance(time.Second)
+		load := picker.SampleLoad()
+		assert.Len(t, load, 3)
+		assert.Greater(t, load["a.svc.local"], 0.0)
+		assert.Greater(t, load["b.svc.local"], 0.0)
+		assert.Zero(t, load["d.svc.local"])
+		assert.InDelta(t, float64(len(keys)), load["a.svc.local"]+load["b.svc.local"], float64(len(keys))*0.4)
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		hash := NewBoundedLoadHash(nil, DefaultReplicas, 1.25)
+		weights := map[string]int{"a.svc.local": 1, "b.svc.local": 2, "c.svc.local": 1}
+		for h, w := range weights {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h, Weight: w}}})
+		}
+
+		// Each peer receives load in proportion to its weight, which is within its bound
+		hash.SetLoad(map[string]float64{"a.svc.local": 100, "b.svc.local": 200, "c.svc.local": 100})
+		_, shed := hash.ShedTable()
+		assert.Empty(t, shed)
+
+		// A peer of weight 1 receiving the load of a peer of weight 2 is above its bound
+		hash.SetLoad(map[string]float64{"a.svc.local": 200, "b.svc.local": 200, "c.svc.local": 100})
+		_, shed = hash.ShedTable()
+		require.Len(t, shed, 1)
+		// The bound is 1.25 * 500 / 4 = 156.25, so a.svc.local moves at least 1 - 156.25 / 200 of its keys
+		assert.Equal(t, 4.0/16.0, shed["a.svc.local"])
+	})
+}
+
+func TestBoundedLoadHashLeader(t *testing.T) {
+	// Two instances, which only sync their load when the test calls syncPeerLoad()
+	instances := make([]*V1Instance, 2)
+	servers := make([]*grpc.Server, 2)
+	peers := make([]PeerInfo, 2)
+	for i := range instances {
+		servers[i] = grpc.NewServer()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		instance, err := NewV1Instance(Config{
+			GRPCServers: []*grpc.Server{servers[i]},
+			LocalPicker: NewBoundedLoadHash(nil, DefaultReplicas, 0),
+			Behaviors:   BehaviorConfig{PeerLoadInterval: time.Hour},
+		})
+		require.NoError(t, err)
+		instances[i] = instance
+		peers[i] = PeerInfo{GRPCAddress: listener.Addr().String()}
+
+		go func(srv *grpc.Server) { _ = srv.Serve(listener) }(servers[i])
+		t.Cleanup(servers[i].Stop)
+		t.Cleanup(func() { _ = instance.Close() })
+	}
+	for i, instance := range instances {
+		info := slices.Clone(peers)
+		info[i].IsOwner = true
+		instance.SetPeers(info)
+	}
+	testutil.UntilPass(t, 50, 100*time.Millisecond, func(t testutil.TestingT) {
+		for _, instance := range instances {
+			for _, peer := range instance.conf.LocalPicker.Peers() {
+				assert.True(t, peer.Info().IsOwner || peer.HasCapability(CapabilityPeerLoadShed))
+			}
+		}
+	})
+
+	// The instance with the lowest address leads
+	leader, follower := instances[0], instances[1]
+	a, b := peers[0].GRPCAddress, peers[1].GRPCAddress
+	if b < a {
+		leader, follower = follower, leader
+		a, b = b, a
+	}
+
+	defer clock.Freeze(clock.Now()).Unfreeze()
+	observe := func(instance *V1Instance, load map[string]int64) {
+		picker := instance.conf.LocalPicker.(*BoundedLoadHash)
+		for addr, checks := range load {
+			picker.counters[addr].Store(checks)
+		}
+	}
+	shedTable := func(instance *V1Instance) (int64, map[string]float64) {
+		return instance.conf.LocalPicker.(peerLoadBalancer).ShedTable()
+	}
+
+	// The instances observe slightly different loads, and sync at different times, such that
+	// neither sees the same total. Yet both move the same keys.
+	for _, round := range []struct {
+		leader, follower map[string]int64
+	}{
+		{leader: map[string]int64{a: 300, b: 100}, follower: map[string]int64{a: 320, b: 80}},
+		{leader: map[string]int64{a: 310, b: 90}, follower: map[string]int64{a: 350, b: 70}},
+		{leader: map[string]int64{a: 280, b: 120}, follower: map[string]int64{a: 330, b: 90}},
+	} {
+		observe(leader, round.leader)
+		observe(follower, round.follower)
+		clock.Advance(time.Second)
+
+		leader.syncPeerLoad()
+		follower.syncPeerLoad()
+
+		version, shed := shedTable(leader)
+		assert.NotZero(t, version)
+		assert.NotEmpty(t, shed)
+		followerVersion, followerShed := shedTable(follower)
+		assert.Equal(t, version, followerVersion)
+		assert.Equal(t, shed, followerShed)
+	}
+
+	// Without the leader the follower computes its own table
+	version, _ := shedTable(follower)
+	servers[slices.Index(instances, leader)].Stop()
+	observe(follower, map[string]int64{a: 400, b: 100})
+	clock.Advance(time.Second)
+	follower.syncPeerLoad()
+	followerVersion, followerShed := shedTable(follower)
+	assert.NotEqual(t, version, followerVersion)
+	assert.NotEmpty(t, followerShed)
+}
+
+func BenchmarkBoundedLoadHash(b *testing.B) {
+	hashFuncs := map[string]HashString64{
+		"fasthash/fnv1a": fnv1a.HashString64,
+		"fasthash/fnv1":  fnv1.HashString64,
+	}
+
+	for name, hashFunc := range hashFuncs {
+		b.Run(name, func(b *testing.B) {
+			ips := make([]string, b.N)
+			for i := range ips {
+				ips[i] = net.IPv4(byte(i>>24), byte(i>>16), byte(i>>8), byte(i)).String()
+			}
+
+			hash := NewBoundedLoadHash(hashFunc, DefaultReplicas, 0)
+			hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
+			for _, h := range hosts {
+				hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+			}
+			hash.SetLoad(map[string]float64{"a.svc.local": 200, "b.svc.local": 100, "c.svc.local": 100})
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, _ = hash.Get(ips[i])
+			}
+		})
+	}
+}
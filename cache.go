@@ -16,6 +16,16 @@ limitations under the License.
 
 package gubernator
 
+import "time"
+
+// The cache implementations selectable via `DaemonConfig.CacheType`
+const (
+	// CacheTypeLRU evicts the least recently used rate limit, see LRUCache
+	CacheTypeLRU = "lru"
+	// CacheTypeTinyLFU evicts rare rate limits before frequently used ones, see TinyLFUCache
+	CacheTypeTinyLFU = "tinylfu"
+)
+
 type Cache interface {
 	Add(item *CacheItem) bool
 	UpdateExpiration(key string, expireAt int64) bool
@@ -26,6 +36,25 @@ type Cache interface {
 	Close() error
 }
 
+// cacheByteLimiter is implemented by caches which can limit the estimated bytes used by their items
+type cacheByteLimiter interface {
+	SetMaxBytes(maxBytes int64)
+}
+
+// setCacheMaxBytes limits the bytes used by the cache if `maxBytes` is set and the cache supports it
+func setCacheMaxBytes(cache Cache, maxBytes int64) {
+	if l, ok := cache.(cacheByteLimiter); ok && maxBytes > 0 {
+		l.SetMaxBytes(maxBytes)
+	}
+}
+
+// cacheSweeper is implemented by caches which can incrementally remove their expired items
+type cacheSweeper interface {
+	// Sweep removes the items expired at now until the budget is spent and returns the number of
+	// items removed
+	Sweep(now time.Time, budget time.Duration) int
+}
+
 type CacheItem struct {
 	Algorithm Algorithm
 	Key       string
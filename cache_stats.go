@@ -0,0 +1,114 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheStats are the counts of accesses to a single cache since it was created
+type CacheStats struct {
+	// Items found in the cache
+	Hits int64
+	// Items not found in the cache, including items found expired
+	Misses int64
+	// Cache misses found in the Store
+	StoreHits int64
+	// Cache misses not found in the Store
+	StoreMisses int64
+	// Items removed from the cache after they expired
+	Expired int64
+	// Items evicted to make room for other items before they expired. The
+	// rate limit of an evicted item is reset the next time it is accessed.
+	Evicted int64
+}
+
+// cacheStatsProvider is implemented by caches which count their accesses
+type cacheStatsProvider interface {
+	Stats() CacheStats
+}
+
+// cacheStoreCounter is implemented by caches which count how often a cache miss was found in the Store
+type cacheStoreCounter interface {
+	countStoreAccess(found bool)
+}
+
+// The label used for rate limit names once the name limit was reached
+const cacheNameOther = "_other"
+
+var metricCacheWorkerAccess = prometheus.NewDesc(
+	"gubernator_worker_cache_access_count",
+	"The count of cache accesses by each worker. Label \"type\" = hit|miss|store_hit|store_miss.",
+	[]string{"worker", "type"}, nil,
+)
+var metricCacheWorkerRemoved = prometheus.NewDesc(
+	"gubernator_worker_cache_removed_count",
+	"The count of items removed from the cache of each worker. Label \"reason\" = expired|evicted, "+
+		"where evicted items were removed before they expired.",
+	[]string{"worker", "reason"}, nil,
+)
+var metricCacheNameAccess = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gubernator_name_cache_access_count",
+	Help: "The count of cache accesses by rate limit name. Label \"type\" = hit|miss|store_hit|store_miss.",
+}, []string{"name", "type"})
+
+// cacheNames limits the number of rate limit names metricCacheNameAccess reports. As with the
+// metrics, the limit is shared by all instances in the process.
+var cacheNames = &cacheNameLimiter{}
+
+type cacheNameLimiter struct {
+	mutex sync.RWMutex
+	limit int
+	names map[string]struct{}
+}
+
+// setLimit sets the max number of distinct names reported, additional names are reported as
+// cacheNameOther. A limit of 0 disables the metric.
+func (l *cacheNameLimiter) setLimit(limit int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limit = limit
+	if l.names == nil {
+		l.names = make(map[string]struct{})
+	}
+}
+
+// inc counts an access of type `accessType` for the rate limit name, if enabled
+func (l *cacheNameLimiter) inc(name, accessType string) {
+	l.mutex.RLock()
+	limit := l.limit
+	_, seen := l.names[name]
+	l.mutex.RUnlock()
+	if limit == 0 {
+		return
+	}
+
+	if !seen {
+		l.mutex.Lock()
+		if _, seen = l.names[name]; !seen && len(l.names) < l.limit {
+			l.names[name] = struct{}{}
+			seen = true
+		}
+		l.mutex.Unlock()
+	}
+	if !seen {
+		name = cacheNameOther
+	}
+	metricCacheNameAccess.WithLabelValues(name, accessType).Inc()
+}
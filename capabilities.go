@@ -0,0 +1,80 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"slices"
+)
+
+// PeerProtocolVersion is the version of the protocol peers use to communicate with each other.
+// Peers which run a version without the capabilities handshake report version 0.
+const PeerProtocolVersion = 1
+
+// Capabilities of the peer protocol which peers negotiate with GetPeerCapabilities when they
+// connect. During a rolling upgrade a capability is only used with the peers which support it.
+const (
+	// The peer accepts requests on PeerStream
+	CapabilityPeerStream = "peer_stream"
+	// The peer sends its address with GetPeerRateLimits, such that the owner of a GLOBAL
+	// rate limit may only broadcast updates to interested peers
+	CapabilityGlobalInterest = "global_interest"
+	// The peer merges GLOBAL_CRDT counters sent with UpdatePeerCounters
+	CapabilityGlobalCRDT = "global_crdt"
+	// The peer reports the load it observed with GetPeerLoad
+	CapabilityPeerLoad = "peer_load"
+	// The peer reports the keys it moves to other peers with GetPeerLoad, such that the
+	// peers move the same keys
+	CapabilityPeerLoadShed = "peer_load_shed"
+	// The peer accepts the rate limits a draining peer hands off with HandoffPeerRateLimits
+	CapabilityHandoff = "handoff"
+	// The peer accepts requests compressed with gzip, snappy or zstd, see PeerCompression
+	CapabilityCompression = "compression"
+)
+
+// capabilities are the capabilities of the peer protocol this instance supports
+var capabilities = []string{
+	CapabilityPeerStream,
+	CapabilityGlobalInterest,
+	CapabilityGlobalCRDT,
+	CapabilityPeerLoad,
+	CapabilityHandoff,
+	CapabilityCompression,
+	CapabilityPeerLoadShed,
+}
+
+// Has returns true if the handshake with the peer completed and the peer supports the capability
+func (c *PeerCapabilities) Has(capability string) bool {
+	return c != nil && c.Negotiated && slices.Contains(c.Capabilities, capability)
+}
+
+// commonCapabilities returns the capabilities this instance and every peer supports
+func commonCapabilities(peers []*PeerCapabilities) []string {
+	var common []string
+	for _, capability := range capabilities {
+		supported := true
+		for _, peer := range peers {
+			if !peer.Has(capability) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			common = append(common, capability)
+		}
+	}
+	return common
+}
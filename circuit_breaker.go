@@ -0,0 +1,281 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The states of a circuit breaker as reported by HealthCheck
+const (
+	// Requests are sent to the peer
+	CircuitClosed = "closed"
+	// A single request is sent to the peer to probe whether it recovered
+	CircuitHalfOpen = "half_open"
+	// Requests to the peer fail without being sent
+	CircuitOpen = "open"
+)
+
+const (
+	// The number of latencies of each peer kept to calculate the p99 latency
+	peerLatencySamples = 256
+	// The min number of latencies observed before the timeout of a peer adapts
+	peerLatencyMinSamples = 64
+	// How often the p99 latency is calculated, in observed latencies
+	peerLatencyInterval = 16
+	// The timeout of a peer is this multiple of its p99 latency
+	peerTimeoutMultiplier = 4
+	// The adaptive timeout of a peer is never less than this
+	peerTimeoutMin = 50 * clock.Millisecond
+)
+
+// errCircuitOpen is returned instead of sending a request to a peer while its circuit breaker is open
+var errCircuitOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+
+var (
+	metricCircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gubernator_circuit_breaker_state",
+		Help: "The state of the circuit breaker of each peer.  0 is closed, 1 is half open and 2 is open.",
+	}, []string{"peerAddr"})
+	metricCircuitBreakerRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gubernator_circuit_breaker_rejected_count",
+		Help: "The count of requests to a peer which failed without being sent because its circuit breaker was open.",
+	}, []string{"peerAddr"})
+	metricPeerTimeout = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gubernator_peer_timeout",
+		Help: "The adaptive timeout of requests to each peer in seconds.",
+	}, []string{"peerAddr"})
+)
+
+// deletePeerMetrics stops reporting the circuit breaker and timeout of a peer which left the cluster
+func deletePeerMetrics(peerAddr string) {
+	metricCircuitBreakerState.DeleteLabelValues(peerAddr)
+	metricCircuitBreakerRejected.DeleteLabelValues(peerAddr)
+	metricPeerTimeout.DeleteLabelValues(peerAddr)
+}
+
+// circuitBreaker fails requests to a peer without sending them while too many of the requests
+// to the peer fail or are slow. Once open for CircuitBreakerOpenWait, a single request is sent
+// to probe whether the peer recovered, which closes the breaker if it succeeds.
+type circuitBreaker struct {
+	conf     BehaviorConfig
+	peerAddr string
+
+	mutex       sync.Mutex
+	state       string    // GUARDED_BY(mutex)
+	windowStart time.Time // GUARDED_BY(mutex)
+	requests    int       // GUARDED_BY(mutex)
+	failures    int       // GUARDED_BY(mutex)
+	openedAt    time.Time // GUARDED_BY(mutex)
+	probing     bool      // GUARDED_BY(mutex)
+}
+
+func newCircuitBreaker(conf BehaviorConfig, peerAddr string) *circuitBreaker {
+	setter.SetDefault(&conf.CircuitBreakerErrorRate, 0.5)
+	setter.SetDefault(&conf.CircuitBreakerMinRequests, 20)
+	setter.SetDefault(&conf.CircuitBreakerWindow, 10*clock.Second)
+	setter.SetDefault(&conf.CircuitBreakerOpenWait, 5*clock.Second)
+	setter.SetDefault(&conf.CircuitBreakerSlowCall, conf.BatchTimeout/2)
+
+	cb := &circuitBreaker{
+		conf:        conf,
+		peerAddr:    peerAddr,
+		windowStart: clock.Now(),
+	}
+	cb.setState(CircuitClosed)
+	return cb
+}
+
+// allow returns errCircuitOpen if the request must not be sent to the peer, and whether the
+// request probes if the peer recovered
+func (cb *circuitBreaker) allow() (probe bool, err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if clock.Since(cb.openedAt) < cb.conf.CircuitBreakerOpenWait {
+			break
+		}
+		cb.setState(CircuitHalfOpen)
+		cb.probing = true
+		return true, nil
+	case CircuitHalfOpen:
+		if cb.probing {
+			break
+		}
+		cb.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+
+	metricCircuitBreakerRejected.WithLabelValues(cb.peerAddr).Inc()
+	return false, errCircuitOpen
+}
+
+// record counts the outcome of a request sent to the peer. Requests canceled by the caller
+// say nothing about the peer, and are not counted.
+func (cb *circuitBreaker) record(probe bool, latency time.Duration, err error, canceled bool) {
+	failed := err != nil || (cb.conf.CircuitBreakerSlowCall > 0 && latency > cb.conf.CircuitBreakerSlowCall)
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		// Only the probe decides whether the peer recovered
+		if !probe {
+			return
+		}
+		cb.probing = false
+		if canceled {
+			return
+		}
+		if failed {
+			cb.open()
+			return
+		}
+		cb.setState(CircuitClosed)
+		cb.windowStart = clock.Now()
+		cb.requests, cb.failures = 0, 0
+		return
+	case CircuitOpen:
+		// The request was sent before the breaker opened
+		return
+	}
+
+	if canceled {
+		return
+	}
+	if now := clock.Now(); now.Sub(cb.windowStart) > cb.conf.CircuitBreakerWindow {
+		cb.windowStart = now
+		cb.requests, cb.failures = 0, 0
+	}
+	cb.requests++
+	if failed {
+		cb.failures++
+	}
+	if cb.requests >= cb.conf.CircuitBreakerMinRequests &&
+		float64(cb.failures)/float64(cb.requests) >= cb.conf.CircuitBreakerErrorRate {
+		cb.open()
+	}
+}
+
+// isOpen returns true if requests to the peer fail without being sent, and it's not yet
+// time to probe whether the peer recovered
+func (cb *circuitBreaker) isOpen() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.state == CircuitOpen && clock.Since(cb.openedAt) < cb.conf.CircuitBreakerOpenWait
+}
+
+// status returns the state of the breaker and the fraction of the requests which failed
+// within the current window
+func (cb *circuitBreaker) status() (string, float64) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.requests == 0 {
+		return cb.state, 0
+	}
+	return cb.state, float64(cb.failures) / float64(cb.requests)
+}
+
+// open opens the breaker. GUARDED_BY(mutex)
+func (cb *circuitBreaker) open() {
+	cb.setState(CircuitOpen)
+	cb.openedAt = clock.Now()
+}
+
+// setState sets the state of the breaker. GUARDED_BY(mutex)
+func (cb *circuitBreaker) setState(state string) {
+	cb.state = state
+	switch state {
+	case CircuitClosed:
+		metricCircuitBreakerState.WithLabelValues(cb.peerAddr).Set(0)
+	case CircuitHalfOpen:
+		metricCircuitBreakerState.WithLabelValues(cb.peerAddr).Set(1)
+	case CircuitOpen:
+		metricCircuitBreakerState.WithLabelValues(cb.peerAddr).Set(2)
+	}
+}
+
+// latencyTracker adapts the timeout of the requests to a peer to a multiple of the p99 latency
+// observed for the peer, bounded by BatchTimeout
+type latencyTracker struct {
+	max      time.Duration
+	peerAddr string
+	timeout  atomic.Int64
+
+	mutex   sync.Mutex
+	samples [peerLatencySamples]time.Duration // GUARDED_BY(mutex)
+	count   int                               // GUARDED_BY(mutex)
+}
+
+func newLatencyTracker(conf BehaviorConfig, peerAddr string) *latencyTracker {
+	setter.SetDefault(&conf.BatchTimeout, 500*clock.Millisecond)
+	lt := &latencyTracker{
+		max:      conf.BatchTimeout,
+		peerAddr: peerAddr,
+	}
+	lt.timeout.Store(int64(lt.max))
+	metricPeerTimeout.WithLabelValues(peerAddr).Set(lt.max.Seconds())
+	return lt
+}
+
+// observe records the latency of a request to the peer. Requests which timed out are observed
+// too, such that the timeout grows when the peer becomes slower.
+func (lt *latencyTracker) observe(latency time.Duration) {
+	lt.mutex.Lock()
+	lt.samples[lt.count%peerLatencySamples] = latency
+	lt.count++
+	if lt.count < peerLatencyMinSamples || lt.count%peerLatencyInterval != 0 {
+		lt.mutex.Unlock()
+		return
+	}
+	n := lt.count
+	if n > peerLatencySamples {
+		n = peerLatencySamples
+	}
+	samples := slices.Clone(lt.samples[:n])
+	lt.mutex.Unlock()
+
+	slices.Sort(samples)
+	timeout := samples[len(samples)*99/100] * peerTimeoutMultiplier
+	if timeout < peerTimeoutMin {
+		timeout = peerTimeoutMin
+	}
+	if timeout > lt.max {
+		timeout = lt.max
+	}
+	lt.timeout.Store(int64(timeout))
+	metricPeerTimeout.WithLabelValues(lt.peerAddr).Set(timeout.Seconds())
+}
+
+// get returns the timeout of the next request to the peer
+func (lt *latencyTracker) get() time.Duration {
+	return time.Duration(lt.timeout.Load())
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestPeerMetricsOnSetPeers(t *testing.T) {
+	s, err := NewV1Instance(Config{
+		GRPCServers: []*grpc.Server{grpc.NewServer()},
+		Behaviors: BehaviorConfig{
+			PeerCircuitBreaker:  true,
+			PeerAdaptiveTimeout: true,
+		},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	const peer, other = "127.0.0.1:9298", "127.0.0.1:9299"
+	reported := func(t *testing.T) {
+		t.Helper()
+		for _, m := range []prometheus.Collector{metricCircuitBreakerState, metricPeerTimeout} {
+			assert.Contains(t, peerAddrs(t, m), peer)
+		}
+	}
+
+	s.SetPeers([]PeerInfo{{GRPCAddress: peer}, {GRPCAddress: other}})
+	reported(t)
+
+	// A peer whose weight changed is replaced by a new PeerClient for the same address, which
+	// keeps reporting its metrics
+	s.SetPeers([]PeerInfo{{GRPCAddress: peer, Weight: 2}, {GRPCAddress: other}})
+	reported(t)
+
+	// The metrics of a peer which left are no longer reported
+	s.SetPeers([]PeerInfo{{GRPCAddress: other}})
+	for _, m := range []prometheus.Collector{metricCircuitBreakerState, metricPeerTimeout} {
+		assert.NotContains(t, peerAddrs(t, m), peer)
+		assert.Contains(t, peerAddrs(t, m), other)
+	}
+}
+
+// peerAddrs returns the `peerAddr` labels of the metrics reported by the collector
+func peerAddrs(t *testing.T, collector prometheus.Collector) []string {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	var addrs []string
+	for m := range ch {
+		met := new(dto.Metric)
+		require.NoError(t, m.Write(met))
+		addrs = append(addrs, met.GetLabel()[0].GetValue())
+	}
+	return addrs
+}
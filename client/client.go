@@ -0,0 +1,469 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client provides a high level gubernator client which discovers the
+// peers in a cluster and sends each rate limit request directly to the peer
+// that owns it, avoiding the extra hop a request pays when it is received by
+// a peer that is not the owner.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Config for a Client
+type Config struct {
+	// (Required) One or more `address:port` of gubernator instances used to discover
+	// the rest of the cluster via the GetPeers() RPC.
+	Endpoints []string
+
+	// (Optional) The TLS config used when connecting to gubernator peers
+	TLS *tls.Config
+
+	// (Optional) The peer picker used to decide which peer owns a rate limit. This MUST be
+	// configured identically to the `GUBER_PEER_PICKER` used by the cluster.
+	// Defaults to the same ReplicatedConsistentHash used by the gubernator server.
+	Picker guber.PeerPicker
+
+	// (Optional) Only peers in this data center are considered owners of rate limits.
+	// Should be the same data center as the peers this client connects to.
+	DataCenter string
+
+	// (Optional) How long to wait for a response from a peer before retrying. Defaults to 500ms
+	Timeout time.Duration
+
+	// (Optional) The number of times a request is retried on another peer when the owning
+	// peer fails to respond. Defaults to 2
+	Retries int
+
+	// (Optional) How often the peer list is refreshed from the cluster. Defaults to 30s
+	RefreshInterval time.Duration
+
+	// (Optional) DisableRefresh disables fetching the peer list from the cluster. Use this when
+	// peers are provided via Client.SetPeers() from one of the discovery pools.
+	DisableRefresh bool
+
+	// (Optional) How long to wait before sending a batch of checks to a peer. Defaults to 500µs
+	BatchWait time.Duration
+
+	// (Optional) The max number of checks batched into a single request to a peer. Defaults to 1,000
+	BatchLimit int
+
+	// (Optional) DisableBatching sends every check to the owning peer as soon as it is requested.
+	DisableBatching bool
+
+	// (Optional) A Logger which implements the declared logger interface (typically *logrus.Entry)
+	Logger guber.FieldLogger
+}
+
+func (c *Config) SetDefaults() error {
+	if len(c.Endpoints) == 0 {
+		return errors.New("at least one endpoint is required")
+	}
+
+	setter.SetDefault(&c.Picker, guber.NewReplicatedConsistentHash(nil, guber.DefaultReplicas))
+	setter.SetDefault(&c.Timeout, time.Millisecond*500)
+	setter.SetDefault(&c.Retries, 2)
+	setter.SetDefault(&c.RefreshInterval, time.Second*30)
+	setter.SetDefault(&c.BatchWait, time.Microsecond*500)
+	setter.SetDefault(&c.BatchLimit, 1_000)
+	setter.SetDefault(&c.Logger, logrus.WithField("category", "gubernator-client"))
+
+	// Make a copy of the TLS config in case our caller decides to make changes
+	if c.TLS != nil {
+		c.TLS = c.TLS.Clone()
+	}
+	return nil
+}
+
+// Client sends rate limit requests directly to the peer which owns the rate limit.
+// Checks are batched per peer, retried on another peer if the owner fails to respond,
+// and sent to any known peer if the owner cannot be determined.
+type Client struct {
+	conf Config
+	log  guber.FieldLogger
+	wg   syncutil.WaitGroup
+
+	mutex     sync.RWMutex
+	picker    guber.PeerPicker
+	peers     map[string]*peer
+	endpoints []*peer
+}
+
+// New creates a new Client and fetches the list of peers from the cluster.
+func New(conf Config) (*Client, error) {
+	if err := conf.SetDefaults(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conf:   conf,
+		log:    conf.Logger,
+		picker: conf.Picker.New(),
+		peers:  make(map[string]*peer),
+	}
+
+	for _, addr := range conf.Endpoints {
+		p, err := c.newPeer(guber.PeerInfo{GRPCAddress: addr})
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.endpoints = append(c.endpoints, p)
+	}
+
+	if conf.DisableRefresh {
+		return c, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Timeout)
+	defer cancel()
+	if err := c.Refresh(ctx); err != nil {
+		c.log.WithError(err).Warn("while fetching the initial peer list; requests will be sent to endpoints")
+	}
+
+	var interval = time.NewTicker(conf.RefreshInterval)
+	c.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-interval.C:
+			ctx, cancel := context.WithTimeout(context.Background(), conf.Timeout)
+			if err := c.Refresh(ctx); err != nil {
+				c.log.WithError(err).Warn("while refreshing the peer list")
+			}
+			cancel()
+		case <-done:
+			interval.Stop()
+			return false
+		}
+		return true
+	})
+	return c, nil
+}
+
+// Refresh fetches the list of peers from the first endpoint or peer that responds.
+func (c *Client) Refresh(ctx context.Context) error {
+	var lastErr error
+	for _, p := range c.candidates() {
+		resp, err := p.client.GetPeers(ctx, &guber.GetPeersReq{})
+		if err != nil {
+			lastErr = errors.Wrapf(err, "while fetching peers from '%s'", p.info.GRPCAddress)
+			continue
+		}
+
+		var peers []guber.PeerInfo
+		for _, info := range resp.Peers {
+			peers = append(peers, guber.PeerInfo{
+				GRPCAddress: info.GrpcAddress,
+				HTTPAddress: info.HttpAddress,
+				DataCenter:  info.DataCenter,
+				Weight:      int(info.Weight),
+			})
+		}
+		c.SetPeers(peers)
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no endpoints or peers available")
+	}
+	return lastErr
+}
+
+// SetPeers replaces the peers the client routes requests to. It has the same signature as
+// `gubernator.UpdateFunc` so it can be used as the `OnUpdate` of any of the discovery pools.
+func (c *Client) SetPeers(peerInfo []guber.PeerInfo) {
+	picker := c.conf.Picker.New()
+	peers := make(map[string]*peer)
+
+	c.mutex.RLock()
+	existing := c.peers
+	c.mutex.RUnlock()
+
+	for _, info := range peerInfo {
+		// Peers in other data centers never own rate limits for our data center
+		if info.DataCenter != c.conf.DataCenter {
+			continue
+		}
+		// The owner flag is only meaningful to the gubernator instance itself
+		info.IsOwner = false
+
+		// The picker reads the weight from the stand-in PeerClient, so a peer whose weight changed is replaced
+		p, ok := existing[info.GRPCAddress]
+		if !ok || p.info.Weight != info.Weight {
+			var err error
+			p, err = c.newPeer(info)
+			if err != nil {
+				c.log.WithError(err).Errorf("while connecting to peer '%s'", info.GRPCAddress)
+				continue
+			}
+		}
+		picker.Add(p.picker)
+		peers[info.GRPCAddress] = p
+	}
+
+	c.mutex.Lock()
+	c.picker = picker
+	c.peers = peers
+	c.mutex.Unlock()
+
+	// Shutdown any old peers we no longer need
+	for addr, p := range existing {
+		if peers[addr] != p {
+			p.close()
+		}
+	}
+	c.log.WithField("peers", peerInfo).Debug("peers updated")
+}
+
+// Peers returns the peers the client currently routes requests to.
+func (c *Client) Peers() []guber.PeerInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var results []guber.PeerInfo
+	for _, p := range c.peers {
+		results = append(results, p.info)
+	}
+	return results
+}
+
+// GetRateLimits sends each rate limit request to the peer which owns it and returns the
+// responses in the same order as the requests.
+func (c *Client) GetRateLimits(ctx context.Context, r *guber.GetRateLimitsReq) (*guber.GetRateLimitsResp, error) {
+	resp := guber.GetRateLimitsResp{
+		Responses: make([]*guber.RateLimitResp, len(r.Requests)),
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range r.Requests {
+		if req.UniqueKey == "" {
+			resp.Responses[i] = &guber.RateLimitResp{Error: "field 'unique_key' cannot be empty"}
+			continue
+		}
+		if req.Name == "" {
+			resp.Responses[i] = &guber.RateLimitResp{Error: "field 'namespace' cannot be empty"}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, req *guber.RateLimitReq) {
+			defer wg.Done()
+			resp.Responses[i] = c.check(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// check sends a single rate limit request to the owning peer, retrying on
+// another peer if the owner fails to respond.
+func (c *Client) check(ctx context.Context, req *guber.RateLimitReq) *guber.RateLimitResp {
+	var rl *guber.RateLimitResp
+	// GLOBAL rate limits are answered by any peer, so spread them across the cluster
+	toOwner := !guber.HasBehavior(req.Behavior, guber.Behavior_GLOBAL)
+
+	err := c.route(ctx, req.HashKey(), toOwner, func(ctx context.Context, p *peer) (err error) {
+		rl, err = c.send(ctx, p, req)
+		return err
+	})
+	if err != nil {
+		return &guber.RateLimitResp{Error: err.Error()}
+	}
+	return rl
+}
+
+// LeaseTokens sends a single token lease request to the peer which owns the rate limit. Most
+// users should use a Leaser, which manages the leases of each rate limit, instead.
+func (c *Client) LeaseTokens(ctx context.Context, req *guber.LeaseReq) (*guber.LeaseResp, error) {
+	if req.RateLimit == nil {
+		return nil, errors.New("field 'rate_limit' cannot be empty")
+	}
+
+	var resp *guber.LeaseResp
+	err := c.route(ctx, req.RateLimit.HashKey(), true, func(ctx context.Context, p *peer) error {
+		ctx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+		defer cancel()
+
+		r, err := p.client.LeaseTokens(ctx, &guber.LeaseTokensReq{
+			Requests: []*guber.LeaseReq{req},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "while leasing tokens from '%s'", p.info.GRPCAddress)
+		}
+		if len(r.Responses) != 1 {
+			return fmt.Errorf("peer '%s' responded with incorrect lease list size", p.info.GRPCAddress)
+		}
+		resp = r.Responses[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// route calls `send` with the peer which owns the key, or with any peer if `toOwner` is false.
+// If the peer fails to respond, `send` is retried with another peer.
+func (c *Client) route(ctx context.Context, key string, toOwner bool, send func(context.Context, *peer) error) error {
+	var tried []*peer
+	var err error
+
+	for attempt := 0; attempt <= c.conf.Retries; attempt++ {
+		var p *peer
+		if attempt == 0 && toOwner {
+			p = c.owner(key)
+		}
+		if p == nil {
+			p = c.anyPeer(tried)
+		}
+		if p == nil {
+			break
+		}
+		tried = append(tried, p)
+
+		err = send(ctx, p)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil || !isRetryable(err) {
+			break
+		}
+		c.log.WithError(err).
+			WithField("peer", p.info.GRPCAddress).
+			WithField("key", key).
+			Debug("retrying request on another peer")
+	}
+
+	if err == nil {
+		err = errors.New("no peers available")
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, p *peer, req *guber.RateLimitReq) (*guber.RateLimitResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+	defer cancel()
+
+	if c.conf.DisableBatching || guber.HasBehavior(req.Behavior, guber.Behavior_NO_BATCHING) {
+		resp, err := p.client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while sending rate limit to '%s'", p.info.GRPCAddress)
+		}
+		if len(resp.Responses) != 1 {
+			return nil, fmt.Errorf("peer '%s' responded with incorrect rate limit list size", p.info.GRPCAddress)
+		}
+		return resp.Responses[0], nil
+	}
+	return p.batch(ctx, req)
+}
+
+// owner returns the peer which owns the provided key, or nil if no peers are known.
+func (c *Client) owner(key string) *peer {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	pc, err := c.picker.Get(key)
+	if err != nil {
+		return nil
+	}
+	return c.peers[pc.Info().GRPCAddress]
+}
+
+// anyPeer returns a random peer or endpoint which is not in the excluded list.
+func (c *Client) anyPeer(exclude []*peer) *peer {
+	var candidates []*peer
+	for _, p := range c.candidates() {
+		if !containsPeer(exclude, p) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// candidates returns the known peers followed by the configured endpoints.
+func (c *Client) candidates() []*peer {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	results := make([]*peer, 0, len(c.peers)+len(c.endpoints))
+	for _, p := range c.peers {
+		results = append(results, p)
+	}
+	return append(results, c.endpoints...)
+}
+
+// Close stops refreshing the peer list and closes all peer connections.
+func (c *Client) Close() {
+	c.wg.Stop()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, p := range c.peers {
+		p.close()
+	}
+	for _, p := range c.endpoints {
+		p.close()
+	}
+	c.peers = make(map[string]*peer)
+	c.endpoints = nil
+}
+
+func containsPeer(peers []*peer, p *peer) bool {
+	for _, i := range peers {
+		if i == p || i.info.GRPCAddress == p.info.GRPCAddress {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryable returns true if the error indicates the peer did not
+// handle the request and another peer should be tried.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errPeerClosed) {
+		return true
+	}
+	switch status.Code(errors.Cause(err)) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Aborted:
+		return true
+	}
+	return false
+}
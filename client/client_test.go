@@ -0,0 +1,148 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/gubernator-io/gubernator/v2/client"
+	"github.com/gubernator-io/gubernator/v2/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	err := cluster.StartWith([]guber.PeerInfo{
+		{GRPCAddress: "127.0.0.1:9790", HTTPAddress: "127.0.0.1:9780"},
+		{GRPCAddress: "127.0.0.1:9791", HTTPAddress: "127.0.0.1:9781"},
+		{GRPCAddress: "127.0.0.1:9792", HTTPAddress: "127.0.0.1:9782"},
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	cluster.Stop()
+	os.Exit(code)
+}
+
+func TestClientDiscoversPeers(t *testing.T) {
+	c, err := client.New(client.Config{
+		Endpoints: []string{cluster.PeerAt(0).GRPCAddress},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.ElementsMatch(t, cluster.GetPeers(), c.Peers())
+}
+
+func TestClientRoutesToOwner(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf client.Config
+	}{
+		{name: "Batching", conf: client.Config{}},
+		{name: "No batching", conf: client.Config{DisableBatching: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.conf.Endpoints = []string{cluster.PeerAt(0).GRPCAddress}
+			c, err := client.New(tc.conf)
+			require.NoError(t, err)
+			defer c.Close()
+
+			var req guber.GetRateLimitsReq
+			for i := 0; i < 20; i++ {
+				req.Requests = append(req.Requests, &guber.RateLimitReq{
+					Name:      "test_client_routes_to_owner",
+					UniqueKey: fmt.Sprintf("%s:account:%d", tc.name, i),
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      1,
+				})
+			}
+
+			resp, err := c.GetRateLimits(context.Background(), &req)
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, len(req.Requests))
+
+			for _, rl := range resp.Responses {
+				assert.Empty(t, rl.Error)
+				assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+				assert.Equal(t, int64(9), rl.Remaining)
+				// Responses forwarded to the owner by a non-owning peer include
+				// the owner metadata; requests handled by the owner do not.
+				assert.Empty(t, rl.Metadata["owner"])
+			}
+		})
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	c, err := client.New(client.Config{
+		Endpoints:      []string{cluster.PeerAt(0).GRPCAddress},
+		DisableRefresh: true,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Include a peer which is not running, requests owned by this
+	// peer must fall back to one of the running peers.
+	peers := append([]guber.PeerInfo{{GRPCAddress: "127.0.0.1:1"}}, cluster.GetPeers()...)
+	c.SetPeers(peers)
+	require.Len(t, c.Peers(), len(peers))
+
+	var req guber.GetRateLimitsReq
+	for i := 0; i < 20; i++ {
+		req.Requests = append(req.Requests, &guber.RateLimitReq{
+			Name:      "test_client_fallback",
+			UniqueKey: fmt.Sprintf("account:%d", i),
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+		})
+	}
+
+	resp, err := c.GetRateLimits(context.Background(), &req)
+	require.NoError(t, err)
+	for _, rl := range resp.Responses {
+		assert.Empty(t, rl.Error)
+		assert.Equal(t, int64(9), rl.Remaining)
+	}
+}
+
+func TestClientInvalidRequest(t *testing.T) {
+	c, err := client.New(client.Config{
+		Endpoints: []string{cluster.PeerAt(0).GRPCAddress},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	resp, err := c.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{{Name: "test_client_invalid_request"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "field 'unique_key' cannot be empty", resp.Responses[0].Error)
+
+	_, err = client.New(client.Config{})
+	assert.EqualError(t, err, "at least one endpoint is required")
+}
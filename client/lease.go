@@ -0,0 +1,309 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/mailgun/holster/v4/syncutil"
+	"google.golang.org/protobuf/proto"
+)
+
+// LeaserConfig for a Leaser
+type LeaserConfig struct {
+	// (Required) The client used to lease tokens from the owner of each rate limit
+	Client *Client
+
+	// (Optional) The fraction of the rate limit leased from the owner at a time. Defaults to 0.05 (5%)
+	Fraction float64
+
+	// (Optional) How long a lease is valid. Unused tokens are returned to the owner
+	// when the lease expires. Defaults to 1s
+	Duration time.Duration
+
+	// (Optional) Leases are considered expired this long before the owner expires them, such
+	// that unused tokens are returned before the owner stops accepting them. Also controls
+	// how often expired leases are returned. Defaults to 100ms
+	ExpireMargin time.Duration
+
+	// (Optional) A Logger which implements the declared logger interface (typically *logrus.Entry)
+	Logger guber.FieldLogger
+}
+
+func (c *LeaserConfig) SetDefaults() error {
+	if c.Client == nil {
+		return errors.New("LeaserConfig.Client is required")
+	}
+
+	setter.SetDefault(&c.Fraction, 0.05)
+	setter.SetDefault(&c.Duration, time.Second)
+	setter.SetDefault(&c.ExpireMargin, time.Millisecond*100)
+	setter.SetDefault(&c.Logger, c.Client.log)
+
+	if c.Fraction <= 0 || c.Fraction > 1 {
+		return errors.New("LeaserConfig.Fraction must be greater than 0 and at most 1")
+	}
+	if c.ExpireMargin >= c.Duration {
+		return errors.New("LeaserConfig.ExpireMargin must be less than LeaserConfig.Duration")
+	}
+	return nil
+}
+
+// Leaser leases blocks of tokens from the owner of each rate limit and spends them
+// locally, such that most checks of a hot rate limit never leave the process. Unused
+// tokens are returned to the owner when the lease expires.
+//
+// Because tokens are spent locally, the responses of a Leaser are approximate; `Remaining`
+// is the number of tokens left in the lease plus the number of tokens the rate limit had
+// remaining when the lease was granted. Behaviors which only the owner can apply, such as
+// DRAIN_OVER_LIMIT, are not supported and GLOBAL rate limits cannot be leased.
+type Leaser struct {
+	conf LeaserConfig
+	log  guber.FieldLogger
+	wg   syncutil.WaitGroup
+
+	mutex  sync.Mutex
+	leases map[string]*lease
+}
+
+type lease struct {
+	mutex     sync.Mutex
+	id        string
+	remaining int64
+	expireAt  clock.Time
+	status    *guber.RateLimitResp
+	removed   bool
+	// The rate limit the lease was granted from, used to return unused tokens.
+	rateLimit *guber.RateLimitReq
+}
+
+// valid returns true if the lease has not expired. GUARDED_BY(mutex)
+func (l *lease) valid(now clock.Time) bool {
+	return l.id != "" && now.Before(l.expireAt)
+}
+
+// NewLeaser creates a new Leaser which returns the unused tokens of expired leases in the background.
+func NewLeaser(conf LeaserConfig) (*Leaser, error) {
+	if err := conf.SetDefaults(); err != nil {
+		return nil, err
+	}
+
+	le := &Leaser{
+		conf:   conf,
+		log:    conf.Logger,
+		leases: make(map[string]*lease),
+	}
+
+	var interval = clock.NewTicker(conf.ExpireMargin)
+	le.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-interval.C():
+			le.returnExpired(false)
+		case <-done:
+			interval.Stop()
+			return false
+		}
+		return true
+	})
+	return le, nil
+}
+
+// GetRateLimit spends the hits of the request from the lease of the rate limit. If the lease has
+// expired or has too few tokens remaining, a new lease is requested from the owner. If the owner
+// cannot be reached, the request is sent to the owner as a regular rate limit check.
+func (le *Leaser) GetRateLimit(ctx context.Context, req *guber.RateLimitReq) (*guber.RateLimitResp, error) {
+	if req.UniqueKey == "" {
+		return nil, errors.New("field 'unique_key' cannot be empty")
+	}
+	if req.Name == "" {
+		return nil, errors.New("field 'namespace' cannot be empty")
+	}
+	if guber.HasBehavior(req.Behavior, guber.Behavior_GLOBAL) {
+		return nil, errors.New("tokens cannot be leased from a rate limit with GLOBAL behavior")
+	}
+
+	l := le.lock(req.HashKey())
+	defer l.mutex.Unlock()
+
+	now := clock.Now()
+	if l.valid(now) && l.remaining >= req.Hits {
+		return l.spend(req.Hits), nil
+	}
+
+	if err := le.renew(ctx, l, req, now); err != nil {
+		le.log.WithError(err).
+			WithField("key", req.HashKey()).
+			Debug("while renewing lease; sending rate limit to the owner")
+
+		resp, err := le.conf.Client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Responses[0], nil
+	}
+
+	if l.remaining >= req.Hits {
+		return l.spend(req.Hits), nil
+	}
+
+	// The owner does not have enough tokens remaining to satisfy the hits
+	return &guber.RateLimitResp{
+		Status:    guber.Status_OVER_LIMIT,
+		Limit:     l.status.Limit,
+		Remaining: l.remaining + l.status.Remaining,
+		ResetTime: l.status.ResetTime,
+	}, nil
+}
+
+// lock returns the locked lease for the key, creating it if it doesn't exist.
+func (le *Leaser) lock(key string) *lease {
+	for {
+		le.mutex.Lock()
+		l, ok := le.leases[key]
+		if !ok {
+			l = &lease{}
+			le.leases[key] = l
+		}
+		le.mutex.Unlock()
+
+		l.mutex.Lock()
+		// The lease was returned while we waited for the lock
+		if l.removed {
+			l.mutex.Unlock()
+			continue
+		}
+		return l
+	}
+}
+
+// spend removes the hits from the lease. GUARDED_BY(mutex)
+func (l *lease) spend(hits int64) *guber.RateLimitResp {
+	l.remaining -= hits
+	return &guber.RateLimitResp{
+		Status:    guber.Status_UNDER_LIMIT,
+		Limit:     l.status.Limit,
+		Remaining: l.remaining + l.status.Remaining,
+		ResetTime: l.status.ResetTime,
+	}
+}
+
+// renew returns the unused tokens of the current lease and leases enough tokens from the
+// owner to satisfy the hits of the request. GUARDED_BY(l.mutex)
+func (le *Leaser) renew(ctx context.Context, l *lease, req *guber.RateLimitReq, now clock.Time) error {
+	rateLimit := proto.Clone(req).(*guber.RateLimitReq)
+	createdAt := now.UnixNano() / 1_000_000
+	rateLimit.CreatedAt = &createdAt
+	rateLimit.Hits = 0
+
+	tokens := int64(math.Ceil(float64(req.Limit) * le.conf.Fraction))
+	if tokens < req.Hits {
+		tokens = req.Hits
+	}
+
+	lr := &guber.LeaseReq{
+		RateLimit: rateLimit,
+		Tokens:    tokens,
+		Duration:  le.conf.Duration.Milliseconds(),
+	}
+	if l.id != "" {
+		lr.ReturnLeaseId = l.id
+		lr.Returned = l.remaining
+	}
+
+	// The previous lease is kept until the request succeeds, such that a failed renewal
+	// returns the unused tokens again. The owner forgets a lease once it returned its tokens,
+	// so they are never returned twice.
+	resp, err := le.conf.Client.LeaseTokens(ctx, lr)
+	if err != nil {
+		return err
+	}
+
+	l.id = resp.LeaseId
+	l.remaining = resp.Tokens
+	l.expireAt = time.UnixMilli(resp.ExpireAt).Add(-le.conf.ExpireMargin)
+	l.status = resp.RateLimit
+	l.rateLimit = rateLimit
+	return nil
+}
+
+// returnExpired returns the unused tokens of leases which have expired to the owner and
+// forgets them. If `all` is true every lease is returned regardless of expiration.
+func (le *Leaser) returnExpired(all bool) {
+	le.mutex.Lock()
+	leases := make(map[string]*lease, len(le.leases))
+	for key, l := range le.leases {
+		leases[key] = l
+	}
+	le.mutex.Unlock()
+
+	now := clock.Now()
+	for key, l := range leases {
+		l.mutex.Lock()
+		if l.valid(now) && !all {
+			l.mutex.Unlock()
+			continue
+		}
+
+		if l.id != "" && l.remaining > 0 {
+			le.release(l, now)
+		}
+
+		l.removed = true
+		le.mutex.Lock()
+		delete(le.leases, key)
+		le.mutex.Unlock()
+		l.mutex.Unlock()
+	}
+}
+
+// release returns the unused tokens of the lease to the owner. GUARDED_BY(l.mutex)
+func (le *Leaser) release(l *lease, now clock.Time) {
+	rateLimit := proto.Clone(l.rateLimit).(*guber.RateLimitReq)
+	createdAt := now.UnixNano() / 1_000_000
+	rateLimit.CreatedAt = &createdAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), le.conf.Client.conf.Timeout)
+	defer cancel()
+
+	_, err := le.conf.Client.LeaseTokens(ctx, &guber.LeaseReq{
+		RateLimit:     rateLimit,
+		ReturnLeaseId: l.id,
+		Returned:      l.remaining,
+	})
+	if err != nil {
+		le.log.WithError(err).
+			WithField("key", rateLimit.HashKey()).
+			Warn("while returning unused leased tokens")
+	}
+	l.id, l.remaining = "", 0
+}
+
+// Close stops returning expired leases in the background and returns the
+// unused tokens of all leases to their owners.
+func (le *Leaser) Close() {
+	le.wg.Stop()
+	le.returnExpired(true)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/gubernator-io/gubernator/v2/client"
+	"github.com/gubernator-io/gubernator/v2/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaser(t *testing.T) {
+	c, err := client.New(client.Config{
+		Endpoints: []string{cluster.PeerAt(0).GRPCAddress},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	newReq := func(key string, limit, hits int64) *guber.RateLimitReq {
+		return &guber.RateLimitReq{
+			Name:      "test_leaser",
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     limit,
+			Hits:      hits,
+		}
+	}
+	// ownerRemaining returns the remaining tokens as seen by the owner of the rate limit
+	ownerRemaining := func(t *testing.T, key string, limit int64) int64 {
+		t.Helper()
+		resp, err := c.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{newReq(key, limit, 0)},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0].Remaining
+	}
+
+	t.Run("Spends leased tokens locally", func(t *testing.T) {
+		local := guber.RandomString(10)
+		le, err := client.NewLeaser(client.LeaserConfig{Client: c})
+		require.NoError(t, err)
+
+		// 5% of 100 is leased at a time
+		for i := int64(1); i <= 5; i++ {
+			resp, err := le.GetRateLimit(context.Background(), newReq(local, 100, 1))
+			require.NoError(t, err)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Status)
+			assert.Equal(t, 100-i, resp.Remaining)
+			assert.Equal(t, int64(95), ownerRemaining(t, local, 100))
+		}
+
+		// The lease is exhausted, a new lease is requested from the owner
+		resp, err := le.GetRateLimit(context.Background(), newReq(local, 100, 1))
+		require.NoError(t, err)
+		assert.Equal(t, int64(94), resp.Remaining)
+		assert.Equal(t, int64(90), ownerRemaining(t, local, 100))
+
+		// Close returns the unused tokens
+		le.Close()
+		assert.Equal(t, int64(94), ownerRemaining(t, local, 100))
+	})
+
+	t.Run("Over the limit", func(t *testing.T) {
+		over := guber.RandomString(10)
+		le, err := client.NewLeaser(client.LeaserConfig{Client: c, Fraction: 1})
+		require.NoError(t, err)
+		defer le.Close()
+
+		for i := 0; i < 3; i++ {
+			resp, err := le.GetRateLimit(context.Background(), newReq(over, 3, 1))
+			require.NoError(t, err)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Status)
+		}
+
+		resp, err := le.GetRateLimit(context.Background(), newReq(over, 3, 1))
+		require.NoError(t, err)
+		assert.Equal(t, guber.Status_OVER_LIMIT, resp.Status)
+		assert.Equal(t, int64(0), resp.Remaining)
+	})
+
+	t.Run("Returns tokens when the lease expires", func(t *testing.T) {
+		expire := guber.RandomString(10)
+		le, err := client.NewLeaser(client.LeaserConfig{
+			Client:       c,
+			Duration:     time.Millisecond * 200,
+			ExpireMargin: time.Millisecond * 50,
+		})
+		require.NoError(t, err)
+		defer le.Close()
+
+		_, err = le.GetRateLimit(context.Background(), newReq(expire, 100, 1))
+		require.NoError(t, err)
+		assert.Equal(t, int64(95), ownerRemaining(t, expire, 100))
+
+		assert.Eventually(t, func() bool {
+			return ownerRemaining(t, expire, 100) == 99
+		}, time.Second, time.Millisecond*10)
+	})
+
+	t.Run("Keeps the lease when renewing fails", func(t *testing.T) {
+		failed := guber.RandomString(10)
+		le, err := client.NewLeaser(client.LeaserConfig{Client: c})
+		require.NoError(t, err)
+
+		_, err = le.GetRateLimit(context.Background(), newReq(failed, 100, 1))
+		require.NoError(t, err)
+		assert.Equal(t, int64(95), ownerRemaining(t, failed, 100))
+
+		// More hits than the lease has left renew the lease, which fails
+		invalid := newReq(failed, 100, 10)
+		invalid.Algorithm = guber.Algorithm(99)
+		resp, err := le.GetRateLimit(context.Background(), invalid)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Error, "Invalid rate limit algorithm")
+
+		// The unused tokens of the lease are still returned
+		le.Close()
+		assert.Equal(t, int64(99), ownerRemaining(t, failed, 100))
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := client.NewLeaser(client.LeaserConfig{})
+		assert.EqualError(t, err, "LeaserConfig.Client is required")
+
+		_, err = client.NewLeaser(client.LeaserConfig{Client: c, Fraction: 2})
+		assert.EqualError(t, err, "LeaserConfig.Fraction must be greater than 0 and at most 1")
+	})
+}
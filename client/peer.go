@@ -0,0 +1,199 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/syncutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var errPeerClosed = errors.New("peer connection is closing")
+
+// peer holds the connection to a single gubernator instance and batches
+// checks sent to that instance.
+type peer struct {
+	info   guber.PeerInfo
+	conf   Config
+	conn   *grpc.ClientConn
+	client guber.V1Client
+	// picker is a stand-in PeerClient added to the PeerPicker such that keys
+	// are hashed exactly as they are by the gubernator server.
+	picker *guber.PeerClient
+	queue  chan *request
+	wg     syncutil.WaitGroup
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type request struct {
+	ctx  context.Context
+	req  *guber.RateLimitReq
+	resp chan response
+}
+
+type response struct {
+	rl  *guber.RateLimitResp
+	err error
+}
+
+func (c *Client) newPeer(info guber.PeerInfo) (*peer, error) {
+	var opts []grpc.DialOption
+	if c.conf.TLS != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(c.conf.TLS)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	conn, err := grpc.NewClient(info.GRPCAddress, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial peer '%s'", info.GRPCAddress)
+	}
+
+	// The PeerClient is never used to make requests; NewClient() does not
+	// connect until the first request is made.
+	pc, err := guber.NewPeerClient(guber.PeerConfig{
+		TLS:      c.conf.TLS,
+		Info:     info,
+		Log:      c.log,
+		Behavior: guber.BehaviorConfig{DisableBatching: true},
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "while creating picker entry for '%s'", info.GRPCAddress)
+	}
+
+	p := &peer{
+		info:   info,
+		conf:   c.conf,
+		conn:   conn,
+		client: guber.NewV1Client(conn),
+		picker: pc,
+		queue:  make(chan *request, c.conf.BatchLimit),
+		closed: make(chan struct{}),
+	}
+
+	if !c.conf.DisableBatching {
+		p.runBatch()
+	}
+	return p, nil
+}
+
+// batch queues the request to be sent with the next batch of requests to this peer.
+func (p *peer) batch(ctx context.Context, r *guber.RateLimitReq) (*guber.RateLimitResp, error) {
+	req := request{
+		ctx:  ctx,
+		req:  r,
+		resp: make(chan response, 1),
+	}
+
+	select {
+	case p.queue <- &req:
+	case <-p.closed:
+		return nil, errPeerClosed
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "context error while enqueuing request")
+	}
+
+	select {
+	case resp := <-req.resp:
+		return resp.rl, resp.err
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "context error while waiting for response")
+	}
+}
+
+// runBatch collects queued requests and sends them as a single request when either
+// BatchWait has elapsed or the queue reaches BatchLimit.
+func (p *peer) runBatch() {
+	var interval = guber.NewInterval(p.conf.BatchWait)
+	var queue []*request
+
+	p.wg.Until(func(done chan struct{}) bool {
+		select {
+		case r := <-p.queue:
+			queue = append(queue, r)
+			if len(queue) >= p.conf.BatchLimit {
+				go p.sendBatch(queue)
+				queue = nil
+				return true
+			}
+
+			// If this is our first enqueued item since last
+			// sendBatch, reset interval timer.
+			if len(queue) == 1 {
+				interval.Next()
+			}
+
+		case <-interval.C:
+			if len(queue) > 0 {
+				go p.sendBatch(queue)
+				queue = nil
+			}
+
+		case <-done:
+			interval.Stop()
+			for _, r := range queue {
+				r.resp <- response{err: errPeerClosed}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+func (p *peer) sendBatch(queue []*request) {
+	var req guber.GetRateLimitsReq
+	for _, r := range queue {
+		req.Requests = append(req.Requests, r.req)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.conf.Timeout)
+	resp, err := p.client.GetRateLimits(ctx, &req)
+	cancel()
+
+	if err == nil && len(resp.Responses) != len(queue) {
+		err = errors.New("server responded with incorrect rate limit list size")
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "while sending batch to '%s'", p.info.GRPCAddress)
+		for _, r := range queue {
+			r.resp <- response{err: err}
+		}
+		return
+	}
+
+	for i, r := range queue {
+		r.resp <- response{rl: resp.Responses[i]}
+	}
+}
+
+func (p *peer) close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		p.wg.Stop()
+		_ = p.picker.Shutdown(context.Background())
+		_ = p.conn.Close()
+	})
+}
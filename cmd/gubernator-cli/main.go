@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -46,6 +48,11 @@ var (
 	checksPerRequest        uint64
 	reqRate                 float64
 	quiet                   bool
+	hotKeys                 bool
+	hotKeysLimit            int
+	peers                   bool
+	topology                bool
+	owner                   string
 )
 
 func main() {
@@ -57,6 +64,11 @@ func main() {
 	flag.Uint64Var(&checksPerRequest, "checks", 1, "Rate checks per request (default 1)")
 	flag.Float64Var(&reqRate, "rate", 0, "Request rate overall, 0 = no rate limit")
 	flag.BoolVar(&quiet, "q", false, "Quiet logging")
+	flag.BoolVar(&hotKeys, "hot-keys", false, "Print the hot keys of each peer in the cluster and exit")
+	flag.IntVar(&hotKeysLimit, "top", 10, "The number of hot keys of each type printed by -hot-keys (default 10)")
+	flag.BoolVar(&peers, "peers", false, "Print the weight and ownership of each peer in the cluster and exit")
+	flag.BoolVar(&topology, "topology", false, "Print the peers known by the instance, the state of their connections and whether they agree on the peers, and exit")
+	flag.StringVar(&owner, "owner", "", "With -topology, also print the peer which owns the rate limit given as 'name/unique_key'")
 	flag.Parse()
 
 	if quiet {
@@ -84,6 +96,7 @@ func main() {
 	tracing.EndScope(startCtx, nil)
 
 	var client guber.V1Client
+	var conf guber.DaemonConfig
 	err = tracing.CallScope(ctx, func(ctx context.Context) error {
 		// Print startup message.
 		cmdLine := strings.Join(os.Args[1:], " ")
@@ -93,7 +106,7 @@ func main() {
 		if err != nil {
 			return fmt.Errorf("while opening config file: %s", err)
 		}
-		conf, err := guber.SetupDaemonConfig(log, configFileReader)
+		conf, err = guber.SetupDaemonConfig(log, configFileReader)
 		if err != nil {
 			return err
 		}
@@ -116,6 +129,21 @@ func main() {
 
 	checkErr(err)
 
+	if hotKeys {
+		checkErr(printHotKeys(ctx, client, conf))
+		return
+	}
+
+	if peers {
+		checkErr(printPeers(ctx, client))
+		return
+	}
+
+	if topology {
+		checkErr(printTopology(ctx, client))
+		return
+	}
+
 	// Generate a selection of rate limits with random limits.
 	var rateLimits []*guber.RateLimitReq
 
@@ -178,6 +206,110 @@ func randInt(min, max int) int {
 	return rand.Intn(max-min) + min
 }
 
+// printHotKeys prints the hot keys of every peer known by the instance `client` is connected to
+func printHotKeys(ctx context.Context, client guber.V1Client, conf guber.DaemonConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	peers, err := client.GetPeers(ctx, &guber.GetPeersReq{})
+	if err != nil {
+		return errors.Wrap(err, "while calling GetPeers()")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, peer := range peers.Peers {
+		peerClient := client
+		if !peer.IsOwner {
+			peerClient, err = guber.DialV1Server(peer.GrpcAddress, conf.ClientTLS())
+			if err != nil {
+				return errors.Wrapf(err, "while connecting to peer '%s'", peer.GrpcAddress)
+			}
+		}
+
+		resp, err := peerClient.GetHotKeys(ctx, &guber.GetHotKeysReq{Limit: int32(hotKeysLimit)})
+		if err != nil {
+			return errors.Wrapf(err, "while calling GetHotKeys() on peer '%s'", peer.GrpcAddress)
+		}
+
+		fmt.Fprintf(w, "PEER %s %s\n", peer.GrpcAddress, peer.DataCenter)
+		fmt.Fprintln(w, "TYPE\tNAME\tKEY\tCOUNT\tERROR")
+		for _, k := range resp.Checks {
+			fmt.Fprintf(w, "checks\t%s\t%s\t%d\t%d\n", k.Name, k.UniqueKey, k.Count, k.Error)
+		}
+		for _, k := range resp.OverLimit {
+			fmt.Fprintf(w, "over_limit\t%s\t%s\t%d\t%d\n", k.Name, k.UniqueKey, k.Count, k.Error)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// printPeers prints the fraction of the rate limits each peer owns according to the instance `client` is connected to
+func printPeers(ctx context.Context, client guber.V1Client) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.GetPeers(ctx, &guber.GetPeersReq{})
+	if err != nil {
+		return errors.Wrap(err, "while calling GetPeers()")
+	}
+
+	sort.Slice(resp.Peers, func(i, j int) bool {
+		if resp.Peers[i].DataCenter != resp.Peers[j].DataCenter {
+			return resp.Peers[i].DataCenter < resp.Peers[j].DataCenter
+		}
+		return resp.Peers[i].GrpcAddress < resp.Peers[j].GrpcAddress
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATA CENTER\tPEER\tWEIGHT\tOWNERSHIP")
+	for _, peer := range resp.Peers {
+		// Peers which do not advertise a weight have a weight of 1
+		weight := peer.Weight
+		setter.SetDefault(&weight, 1)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f%%\n", peer.DataCenter, peer.GrpcAddress, weight, peer.Ownership*100)
+	}
+	return w.Flush()
+}
+
+// printTopology prints the peers known by the instance `client` is connected to and the state of their connections
+func printTopology(ctx context.Context, client guber.V1Client) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var req guber.GetTopologyReq
+	if owner != "" {
+		var ok bool
+		req.Name, req.UniqueKey, ok = strings.Cut(owner, "/")
+		if !ok {
+			return errors.Errorf("-owner '%s' is invalid; expected 'name/unique_key'", owner)
+		}
+	}
+
+	resp, err := client.GetTopology(ctx, &req)
+	if err != nil {
+		return errors.Wrap(err, "while calling GetTopology()")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATA CENTER\tPEER\tRING HASH\tBATCH QUEUE\tERRORS")
+	for _, p := range append(resp.LocalPeers, resp.RegionPeers...) {
+		ringHash := p.RingHash
+		if p.RingHashError != "" {
+			ringHash = "error: " + p.RingHashError
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", p.Peer.DataCenter, p.Peer.GrpcAddress, ringHash,
+			p.BatchQueueLength, strings.Join(p.LastErrors, "; "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "RING HASH\t%s\n", resp.RingHash)
+	fmt.Fprintf(w, "RING AGREEMENT\t%t\n", resp.RingAgreement)
+	if resp.KeyOwner != nil {
+		fmt.Fprintf(w, "OWNER OF '%s'\t%s\n", owner, resp.KeyOwner.GrpcAddress)
+	}
+	return w.Flush()
+}
+
 func sendRequest(ctx context.Context, client guber.V1Client, req *guber.GetRateLimitsReq) {
 	ctx = tracing.StartScope(ctx)
 	defer tracing.EndScope(ctx, nil)
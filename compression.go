@@ -0,0 +1,202 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// The codecs which may compress the requests peers send to each other. The responses are
+// compressed with the codec of the request.
+const (
+	CompressionNone   = ""
+	CompressionGzip   = gzip.Name
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+func init() {
+	registerCompressors()
+}
+
+// registerCompressors registers the compressors of the codecs with gRPC. The compressors are
+// registered globally, so a compressor the application or another package already registered
+// under the same name is kept rather than replaced.
+func registerCompressors() {
+	for _, c := range []encoding.Compressor{&snappyCompressor{}, &zstdCompressor{}} {
+		if encoding.GetCompressor(c.Name()) == nil {
+			encoding.RegisterCompressor(c)
+		}
+	}
+}
+
+// compressUnary compresses the requests to the peer once the peer negotiated it supports compression
+func (c *PeerClient) compressUnary(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if c.HasCapability(CapabilityCompression) {
+		opts = append(opts, grpc.UseCompressor(c.conf.Behavior.PeerCompression))
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// compressStream compresses the messages on streams opened once the peer negotiated it supports compression
+func (c *PeerClient) compressStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if c.HasCapability(CapabilityCompression) {
+		opts = append(opts, grpc.UseCompressor(c.conf.Behavior.PeerCompression))
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// snappyCompressor compresses gRPC messages with the snappy framing format
+type snappyCompressor struct {
+	writers sync.Pool
+	readers sync.Pool
+}
+
+func (c *snappyCompressor) Name() string {
+	return CompressionSnappy
+}
+
+func (c *snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	sw, ok := c.writers.Get().(*s2.Writer)
+	if !ok {
+		sw = s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1))
+	} else {
+		sw.Reset(w)
+	}
+	return &snappyWriter{Writer: sw, pool: &c.writers}, nil
+}
+
+func (c *snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	sr, ok := c.readers.Get().(*s2.Reader)
+	if !ok {
+		sr = s2.NewReader(r)
+	} else {
+		sr.Reset(r)
+	}
+	return &snappyReader{Reader: sr, pool: &c.readers}, nil
+}
+
+type snappyWriter struct {
+	*s2.Writer
+	pool *sync.Pool
+}
+
+func (w *snappyWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+type snappyReader struct {
+	*s2.Reader
+	pool *sync.Pool
+}
+
+func (r *snappyReader) Read(p []byte) (int, error) {
+	if r.Reader == nil {
+		return 0, io.EOF
+	}
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		// The message was read completely, so the reader may be reused
+		r.pool.Put(r.Reader)
+		r.Reader = nil
+	}
+	return n, err
+}
+
+// zstdCompressor compresses gRPC messages with zstd at its fastest level, which compresses
+// the repetitive names and keys of rate limits better than snappy at a similar cost
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (c *zstdCompressor) Name() string {
+	return CompressionZstd
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	enc, ok := c.encoders.Get().(*zstd.Encoder)
+	if !ok {
+		var err error
+		enc, err = zstd.NewWriter(w,
+			zstd.WithEncoderLevel(zstd.SpeedFastest),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		enc.Reset(w)
+	}
+	return &zstdWriter{Encoder: enc, pool: &c.encoders}, nil
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, ok := c.decoders.Get().(*zstd.Decoder)
+	if !ok {
+		var err error
+		dec, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+	} else if err := dec.Reset(r); err != nil {
+		c.decoders.Put(dec)
+		return nil, err
+	}
+	return &zstdReader{Decoder: dec, pool: &c.decoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.Decoder == nil {
+		return 0, io.EOF
+	}
+	n, err := r.Decoder.Read(p)
+	if err == io.EOF {
+		// The message was read completely, so the decoder may be reused
+		r.pool.Put(r.Decoder)
+		r.Decoder = nil
+	}
+	return n, err
+}
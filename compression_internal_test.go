@@ -0,0 +1,40 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/encoding"
+)
+
+// appCompressor is a compressor the application registered under the name of one of our codecs
+type appCompressor struct {
+	encoding.Compressor
+}
+
+func TestRegisterCompressors(t *testing.T) {
+	ours := encoding.GetCompressor(CompressionSnappy)
+	defer encoding.RegisterCompressor(ours)
+
+	app := &appCompressor{Compressor: ours}
+	encoding.RegisterCompressor(app)
+	registerCompressors()
+	assert.Same(t, app, encoding.GetCompressor(CompressionSnappy))
+	assert.IsType(t, &zstdCompressor{}, encoding.GetCompressor(CompressionZstd))
+}
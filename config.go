@@ -54,6 +54,37 @@ type BehaviorConfig struct {
 	BatchLimit int
 	// DisableBatching disables batching behavior for all ratelimits.
 	DisableBatching bool
+	// PeerStreaming sends forwarded rate limits, global hits and global updates to each peer over a
+	// single long-lived stream instead of a request each. Peers which don't support the stream are
+	// sent a request each.
+	PeerStreaming bool
+	// The max number of requests in flight on the stream to each peer. Defaults to 100
+	PeerStreamWindow int
+	// The codec which compresses the requests to peers, either CompressionGzip, CompressionSnappy or
+	// CompressionZstd. Requests to peers which don't support compression are not compressed. Defaults
+	// to CompressionNone
+	PeerCompression string
+	// PeerCircuitBreaker fails the requests to a peer without sending them while too many of the
+	// requests to the peer fail or are slow, instead of waiting for each request to time out.
+	PeerCircuitBreaker bool
+	// The fraction of the requests to a peer within CircuitBreakerWindow which must fail or be
+	// slower than CircuitBreakerSlowCall to open the circuit breaker. Defaults to 0.5
+	CircuitBreakerErrorRate float64
+	// The min number of requests to a peer within CircuitBreakerWindow before the circuit breaker
+	// may open. Defaults to 20
+	CircuitBreakerMinRequests int
+	// How long the requests to a peer are counted by the circuit breaker. Defaults to 10 seconds
+	CircuitBreakerWindow time.Duration
+	// How long the circuit breaker of a peer stays open before a single request probes whether the
+	// peer recovered. Defaults to 5 seconds
+	CircuitBreakerOpenWait time.Duration
+	// Requests to a peer slower than this are counted as failed by the circuit breaker. Defaults to
+	// half of BatchTimeout
+	CircuitBreakerSlowCall time.Duration
+	// PeerAdaptiveTimeout bounds the timeout of the requests to a peer by a multiple of the p99 latency
+	// observed for the peer, such that the requests to a peer which stopped responding fail long
+	// before BatchTimeout.
+	PeerAdaptiveTimeout bool
 
 	// How long a non-owning peer should wait before syncing hits to the owning peer
 	GlobalSyncWait time.Duration
@@ -66,6 +97,30 @@ type BehaviorConfig struct {
 
 	// Number of concurrent requests that will be made to peers. Defaults to 100
 	GlobalPeerRequestsConcurrency int
+	// How long a peer remains interested in updates of a global rate limit after it last sent hits or
+	// checks for the rate limit to the owner. When set, the owner only broadcasts updates to interested
+	// peers, and every peer must use the same value. Defaults to 0, which broadcasts every update to
+	// every peer
+	GlobalInterestTTL time.Duration
+	// How often every GLOBAL_CRDT counter is gossiped to the other peers, not only the counters
+	// which changed, such that a peer which joined or restarted learns the hits counted before.
+	// Rounded down to a multiple of GlobalSyncWait. Defaults to 30 seconds
+	GlobalCRDTFullSyncInterval time.Duration
+
+	// How long the owning peer remembers the response to a request with a `request_id`. Defaults to 1 minute
+	RequestIDWindow time.Duration
+	// The max number of `request_id` responses remembered. Defaults to 50,000
+	RequestIDCacheSize int
+
+	// How often peers share the load they observed on each peer, when the LocalPicker is
+	// a BoundedLoadHash. Defaults to 10 seconds
+	PeerLoadInterval time.Duration
+
+	// How long a draining instance must receive no forwarded requests before the peers are
+	// considered to have stopped routing requests to it, and it hands off its rate limits. Set
+	// it longer than peer discovery takes to remove an instance from every peer. Defaults to
+	// 1 second
+	DrainIdleWait time.Duration
 }
 
 // Config for a gubernator instance
@@ -81,6 +136,10 @@ type Config struct {
 	// (Optional) The cache implementation
 	CacheFactory func(maxSize int) Cache
 
+	// (Optional) Collects the metrics of the caches created by CacheFactory, labeled with the
+	// name of the worker or shard which owns each cache
+	CacheCollector *LRUCacheCollector
+
 	// (Optional) A persistent store implementation. Allows the implementor the ability to store the rate limits this
 	// instance of gubernator owns. It's up to the implementor to decide what rate limits to persist.
 	// For instance an implementor might only persist rate limits that have an expiration of
@@ -124,6 +183,41 @@ type Config struct {
 	// (Optional) The total size of the cache used to store rate limits. Defaults to 50,000
 	CacheSize int
 
+	// (Optional) The max estimated bytes used by the keys and values of the cache, split evenly
+	// between the workers. Only applies to caches which support byte limits like LRUCache and
+	// TinyLFUCache. Both CacheSize and CacheMaxBytes are enforced. Defaults to 0, which is unlimited
+	CacheMaxBytes int64
+
+	// (Optional) The engine used to apply rate limits to the cache, either EngineWorkerPool
+	// or EngineShardedMutex. Defaults to EngineWorkerPool
+	Engine string
+
+	// (Optional) The max number of rate limit names the cache metrics are broken down by, additional
+	// names are reported as "_other". As the metrics are, the limit is shared by all instances in the
+	// process. Defaults to 0, which disables the metrics by name
+	CacheNameMetricsLimit int
+
+	// (Optional) How often each worker removes expired items from its cache. Defaults
+	// to 1 second, a negative value disables the sweeper
+	CacheSweepInterval time.Duration
+
+	// (Optional) How long each worker may spend removing expired items before it resumes
+	// handling requests; the next sweep continues where the previous one stopped. Defaults to 1ms
+	CacheSweepBudget time.Duration
+
+	// (Optional) The number of rate limits tracked to find the rate limits which are checked and over
+	// the limit most often. The rate limits are spread over 16 shards which each track this many
+	// rate limits. Defaults to 128, a negative value disables tracking
+	HotKeysCapacity int
+
+	// (Optional) How long until the counts of the tracked rate limits are halved, such that recent
+	// checks weigh more than older checks. Defaults to 1 minute
+	HotKeysHalfLife time.Duration
+
+	// (Optional) The max number of hot keys of each type reported by the `gubernator_hot_key_count`
+	// metric. Defaults to 10
+	HotKeysMetricsLimit int
+
 	// (Optional) EventChannel receives hit events
 	EventChannel chan<- HitEvent
 }
@@ -137,18 +231,37 @@ func (c *Config) SetDefaults() error {
 	setter.SetDefault(&c.Behaviors.BatchTimeout, time.Millisecond*500)
 	setter.SetDefault(&c.Behaviors.BatchLimit, maxBatchSize)
 	setter.SetDefault(&c.Behaviors.BatchWait, time.Microsecond*500)
+	setter.SetDefault(&c.Behaviors.PeerStreamWindow, 100)
+	setter.SetDefault(&c.Behaviors.CircuitBreakerErrorRate, 0.5)
+	setter.SetDefault(&c.Behaviors.CircuitBreakerMinRequests, 20)
+	setter.SetDefault(&c.Behaviors.CircuitBreakerWindow, 10*time.Second)
+	setter.SetDefault(&c.Behaviors.CircuitBreakerOpenWait, 5*time.Second)
+	setter.SetDefault(&c.Behaviors.CircuitBreakerSlowCall, c.Behaviors.BatchTimeout/2)
 
 	setter.SetDefault(&c.Behaviors.GlobalTimeout, time.Millisecond*500)
 	setter.SetDefault(&c.Behaviors.GlobalBatchLimit, maxBatchSize)
 	setter.SetDefault(&c.Behaviors.GlobalSyncWait, time.Millisecond*100)
 
 	setter.SetDefault(&c.Behaviors.GlobalPeerRequestsConcurrency, 100)
+	setter.SetDefault(&c.Behaviors.GlobalCRDTFullSyncInterval, 30*time.Second)
+
+	setter.SetDefault(&c.Behaviors.RequestIDWindow, time.Minute)
+	setter.SetDefault(&c.Behaviors.RequestIDCacheSize, 50_000)
+
+	setter.SetDefault(&c.Behaviors.PeerLoadInterval, 10*time.Second)
+	setter.SetDefault(&c.Behaviors.DrainIdleWait, time.Second)
 
-	setter.SetDefault(&c.LocalPicker, NewReplicatedConsistentHash(nil, defaultReplicas))
+	setter.SetDefault(&c.LocalPicker, NewReplicatedConsistentHash(nil, DefaultReplicas))
 	setter.SetDefault(&c.RegionPicker, NewRegionPicker(nil))
 
 	setter.SetDefault(&c.CacheSize, 50_000)
 	setter.SetDefault(&c.Workers, runtime.NumCPU())
+	setter.SetDefault(&c.Engine, EngineWorkerPool)
+	setter.SetDefault(&c.CacheSweepInterval, time.Second)
+	setter.SetDefault(&c.CacheSweepBudget, time.Millisecond)
+	setter.SetDefault(&c.HotKeysCapacity, 128)
+	setter.SetDefault(&c.HotKeysHalfLife, time.Minute)
+	setter.SetDefault(&c.HotKeysMetricsLimit, 10)
 	setter.SetDefault(&c.InstanceID, GetInstanceID())
 	setter.SetDefault(&c.Logger, logrus.New().WithFields(logrus.Fields{
 		"instance": c.InstanceID,
@@ -161,6 +274,11 @@ func (c *Config) SetDefaults() error {
 		}
 	}
 
+	if c.Engine != EngineWorkerPool && c.Engine != EngineShardedMutex {
+		return fmt.Errorf("Engine '%s' is invalid; choices are ['%s', '%s']",
+			c.Engine, EngineWorkerPool, EngineShardedMutex)
+	}
+
 	if c.Behaviors.BatchLimit > maxBatchSize {
 		return fmt.Errorf("Behaviors.BatchLimit cannot exceed '%d'", maxBatchSize)
 	}
@@ -182,13 +300,33 @@ type PeerInfo struct {
 	GRPCAddress string `json:"grpc-address"`
 	// (Optional) Is true if PeerInfo is for this instance of gubernator
 	IsOwner bool `json:"is-owner,omitempty"`
+	// (Optional) The share of the rate limits this peer owns relative to the other peers,
+	// such that a peer with a weight of 2 owns twice as many rate limits as a peer with
+	// a weight of 1. Defaults to 1, must not be greater than MaxPeerWeight
+	Weight int `json:"weight,omitempty"`
 }
 
+// MaxPeerWeight is the largest weight of a peer. The pickers place a peer on their ring or
+// table in proportion to its weight, which bounds the size of the ring.
+const MaxPeerWeight = 100
+
 // HashKey returns the hash key used to identify this peer in the Picker.
 func (p PeerInfo) HashKey() string {
 	return p.GRPCAddress
 }
 
+// weight returns the weight of the peer, which defaults to 1 if not set and is capped
+// at MaxPeerWeight
+func (p PeerInfo) weight() int {
+	if p.Weight <= 0 {
+		return 1
+	}
+	if p.Weight > MaxPeerWeight {
+		return MaxPeerWeight
+	}
+	return p.Weight
+}
+
 type UpdateFunc func([]PeerInfo)
 
 var DebugEnabled = false
@@ -207,10 +345,20 @@ type DaemonConfig struct {
 	// provide client certificate but you want to enforce mTLS in other RPCs (like in K8s)
 	HTTPStatusListenAddress string
 
+	// (Optional) The `address:port` that will accept Redis protocol (RESP) connections. Clients of
+	// the redis-cell module can rate limit using `CL.THROTTLE` against this address. Disabled if empty
+	RESPListenAddress string
+
 	// (Optional) Defines the max age connection from client in seconds.
 	// Default is infinity
 	GRPCMaxConnectionAgeSeconds int
 
+	// (Optional) How long Close() may drain the instance before it exits. While draining, the
+	// instance has left peer discovery, HealthCheck reports it is not ready, forwarded requests
+	// are served until the peers stop sending them and the rate limits it owns are handed off to
+	// their next owners. Defaults to 0, which exits without draining
+	DrainTimeout time.Duration
+
 	// (Optional) The `address:port` that is advertised to other Gubernator peers.
 	// Defaults to `GRPCListenAddress`
 	AdvertiseAddress string
@@ -218,10 +366,38 @@ type DaemonConfig struct {
 	// (Optional) The number of items in the cache. Defaults to 50,000
 	CacheSize int
 
+	// (Optional) The max estimated bytes used by the keys and values of the cache. Defaults to unlimited
+	CacheMaxBytes int64
+
+	// (Optional) How often each worker removes expired items from its cache. Defaults to 1 second
+	CacheSweepInterval time.Duration
+
+	// (Optional) How long each worker may spend removing expired items per sweep. Defaults to 1ms
+	CacheSweepBudget time.Duration
+
+	// (Optional) The max number of rate limit names the cache metrics are broken down by. Defaults to 0, disabled
+	CacheNameMetricsLimit int
+
+	// (Optional) The cache implementation, either CacheTypeLRU or CacheTypeTinyLFU. Defaults to CacheTypeLRU
+	CacheType string
+
 	// (Optional) The number of go routine workers used to process concurrent rate limit requests
 	// Defaults to the number of CPUs returned by runtime.NumCPU()
 	Workers int
 
+	// (Optional) The engine used to apply rate limits to the cache
+	//  Valid options are [worker-pool, sharded-mutex] (Defaults to 'worker-pool')
+	Engine string
+
+	// (Optional) The number of rate limits tracked to find hot keys. Defaults to 128, negative disables
+	HotKeysCapacity int
+
+	// (Optional) How long until the counts of hot keys are halved. Defaults to 1 minute
+	HotKeysHalfLife time.Duration
+
+	// (Optional) The max number of hot keys of each type reported as metrics. Defaults to 10
+	HotKeysMetricsLimit int
+
 	// (Optional) Configure how behaviours behave
 	Behaviors BehaviorConfig
 
@@ -229,8 +405,12 @@ type DaemonConfig struct {
 	// use with multi-region support
 	DataCenter string
 
+	// (Optional) The share of the rate limits this instance owns relative to the other peers,
+	// advertised to the other peers by the member-list and etcd peer discovery. Defaults to 1
+	PeerWeight int
+
 	// (Optional) Which pool to use when discovering other Gubernator peers
-	//  Valid options are [etcd, k8s, member-list] (Defaults to 'member-list')
+	//  Valid options are [etcd, k8s, member-list, dns, file, none] (Defaults to 'member-list')
 	PeerDiscoveryType string
 
 	// (Optional) Etcd configuration used for peer discovery
@@ -245,6 +425,9 @@ type DaemonConfig struct {
 	// (Optional) Member list configuration used for peer discovery
 	MemberListPoolConf MemberListPoolConfig
 
+	// (Optional) File configuration used for peer discovery
+	FilePoolConf FilePoolConfig
+
 	// (Optional) The PeerPicker as selected by `GUBER_PEER_PICKER`
 	Picker PeerPicker
 
@@ -334,14 +517,39 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile io.Reader) (DaemonConfi
 		fmt.Sprintf("%s:1050", LocalHost()))
 	setter.SetDefault(&conf.InstanceID, GetInstanceID())
 	setter.SetDefault(&conf.HTTPStatusListenAddress, os.Getenv("GUBER_STATUS_HTTP_ADDRESS"), "")
+	setter.SetDefault(&conf.RESPListenAddress, os.Getenv("GUBER_RESP_ADDRESS"), "")
 	setter.SetDefault(&conf.GRPCMaxConnectionAgeSeconds, getEnvInteger(log, "GUBER_GRPC_MAX_CONN_AGE_SEC"), 0)
+	setter.SetDefault(&conf.DrainTimeout, getEnvDuration(log, "GUBER_DRAIN_TIMEOUT"))
+	setter.SetDefault(&conf.Behaviors.DrainIdleWait, getEnvDuration(log, "GUBER_DRAIN_IDLE_WAIT"))
 	setter.SetDefault(&conf.CacheSize, getEnvInteger(log, "GUBER_CACHE_SIZE"), 50_000)
+	setter.SetDefault(&conf.CacheMaxBytes, int64(getEnvInteger(log, "GUBER_CACHE_MAX_BYTES")))
+	setter.SetDefault(&conf.CacheSweepInterval, getEnvDuration(log, "GUBER_CACHE_SWEEP_INTERVAL"))
+	setter.SetDefault(&conf.CacheSweepBudget, getEnvDuration(log, "GUBER_CACHE_SWEEP_BUDGET"))
+	setter.SetDefault(&conf.CacheNameMetricsLimit, getEnvInteger(log, "GUBER_CACHE_NAME_METRICS_LIMIT"))
+	setter.SetDefault(&conf.CacheType, os.Getenv("GUBER_CACHE_TYPE"), CacheTypeLRU)
+	if conf.CacheType != CacheTypeLRU && conf.CacheType != CacheTypeTinyLFU {
+		return conf, errors.Errorf("'GUBER_CACHE_TYPE=%s' is invalid; choices are ['%s', '%s']",
+			conf.CacheType, CacheTypeLRU, CacheTypeTinyLFU)
+	}
 	setter.SetDefault(&conf.Workers, getEnvInteger(log, "GUBER_WORKER_COUNT"), 0)
+	setter.SetDefault(&conf.HotKeysCapacity, getEnvInteger(log, "GUBER_HOT_KEYS_CAPACITY"))
+	setter.SetDefault(&conf.HotKeysHalfLife, getEnvDuration(log, "GUBER_HOT_KEYS_HALF_LIFE"))
+	setter.SetDefault(&conf.HotKeysMetricsLimit, getEnvInteger(log, "GUBER_HOT_KEYS_METRICS_LIMIT"))
+	setter.SetDefault(&conf.Engine, os.Getenv("GUBER_ENGINE"), EngineWorkerPool)
+	if conf.Engine != EngineWorkerPool && conf.Engine != EngineShardedMutex {
+		return conf, errors.Errorf("'GUBER_ENGINE=%s' is invalid; choices are ['%s', '%s']",
+			conf.Engine, EngineWorkerPool, EngineShardedMutex)
+	}
 	setter.SetDefault(&conf.AdvertiseAddress, os.Getenv("GUBER_ADVERTISE_ADDRESS"), conf.GRPCListenAddress)
 	setter.SetDefault(&conf.DataCenter, os.Getenv("GUBER_DATA_CENTER"), "")
+	setter.SetDefault(&conf.PeerWeight, getEnvInteger(log, "GUBER_PEER_WEIGHT"))
+	if conf.PeerWeight < 0 || conf.PeerWeight > MaxPeerWeight {
+		return conf, errors.Errorf("'GUBER_PEER_WEIGHT=%d' is invalid; must be between 0 and %d",
+			conf.PeerWeight, MaxPeerWeight)
+	}
 	setter.SetDefault(&conf.MetricFlags, getEnvMetricFlags(log, "GUBER_METRIC_FLAGS"))
 
-	choices := []string{"member-list", "k8s", "etcd", "dns", "none"}
+	choices := []string{"member-list", "k8s", "etcd", "dns", "file", "none"}
 	setter.SetDefault(&conf.PeerDiscoveryType, os.Getenv("GUBER_PEER_DISCOVERY_TYPE"), "member-list")
 	if !slice.ContainsString(conf.PeerDiscoveryType, choices, nil) {
 		return conf, fmt.Errorf("GUBER_PEER_DISCOVERY_TYPE is invalid; choices are [%s]`", strings.Join(choices, ","))
@@ -365,11 +573,34 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile io.Reader) (DaemonConfi
 	setter.SetDefault(&conf.Behaviors.BatchLimit, getEnvInteger(log, "GUBER_BATCH_LIMIT"))
 	setter.SetDefault(&conf.Behaviors.BatchWait, getEnvDuration(log, "GUBER_BATCH_WAIT"))
 	setter.SetDefault(&conf.Behaviors.DisableBatching, getEnvBool(log, "GUBER_DISABLE_BATCHING"))
+	setter.SetDefault(&conf.Behaviors.PeerStreaming, getEnvBool(log, "GUBER_PEER_STREAMING"))
+	setter.SetDefault(&conf.Behaviors.PeerStreamWindow, getEnvInteger(log, "GUBER_PEER_STREAM_WINDOW"))
+	setter.SetDefault(&conf.Behaviors.PeerCompression, os.Getenv("GUBER_PEER_COMPRESSION"))
+	switch conf.Behaviors.PeerCompression {
+	case CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd:
+	default:
+		return conf, errors.Errorf("'GUBER_PEER_COMPRESSION=%s' is invalid; choices are ['%s', '%s', '%s']",
+			conf.Behaviors.PeerCompression, CompressionGzip, CompressionSnappy, CompressionZstd)
+	}
+	setter.SetDefault(&conf.Behaviors.PeerCircuitBreaker, getEnvBool(log, "GUBER_PEER_CIRCUIT_BREAKER"))
+	setter.SetDefault(&conf.Behaviors.CircuitBreakerErrorRate, getEnvFloat(log, "GUBER_CIRCUIT_BREAKER_ERROR_RATE"))
+	setter.SetDefault(&conf.Behaviors.CircuitBreakerMinRequests, getEnvInteger(log, "GUBER_CIRCUIT_BREAKER_MIN_REQUESTS"))
+	setter.SetDefault(&conf.Behaviors.CircuitBreakerWindow, getEnvDuration(log, "GUBER_CIRCUIT_BREAKER_WINDOW"))
+	setter.SetDefault(&conf.Behaviors.CircuitBreakerOpenWait, getEnvDuration(log, "GUBER_CIRCUIT_BREAKER_OPEN_WAIT"))
+	setter.SetDefault(&conf.Behaviors.CircuitBreakerSlowCall, getEnvDuration(log, "GUBER_CIRCUIT_BREAKER_SLOW_CALL"))
+	setter.SetDefault(&conf.Behaviors.PeerAdaptiveTimeout, getEnvBool(log, "GUBER_PEER_ADAPTIVE_TIMEOUT"))
 
 	setter.SetDefault(&conf.Behaviors.GlobalTimeout, getEnvDuration(log, "GUBER_GLOBAL_TIMEOUT"))
 	setter.SetDefault(&conf.Behaviors.GlobalBatchLimit, getEnvInteger(log, "GUBER_GLOBAL_BATCH_LIMIT"))
 	setter.SetDefault(&conf.Behaviors.GlobalSyncWait, getEnvDuration(log, "GUBER_GLOBAL_SYNC_WAIT"))
 	setter.SetDefault(&conf.Behaviors.ForceGlobal, getEnvBool(log, "GUBER_FORCE_GLOBAL"))
+	setter.SetDefault(&conf.Behaviors.GlobalInterestTTL, getEnvDuration(log, "GUBER_GLOBAL_INTEREST_TTL"))
+	setter.SetDefault(&conf.Behaviors.GlobalCRDTFullSyncInterval, getEnvDuration(log, "GUBER_GLOBAL_CRDT_FULL_SYNC_INTERVAL"))
+
+	setter.SetDefault(&conf.Behaviors.RequestIDWindow, getEnvDuration(log, "GUBER_REQUEST_ID_WINDOW"))
+	setter.SetDefault(&conf.Behaviors.RequestIDCacheSize, getEnvInteger(log, "GUBER_REQUEST_ID_CACHE_SIZE"))
+
+	setter.SetDefault(&conf.Behaviors.PeerLoadInterval, getEnvDuration(log, "GUBER_PEER_LOAD_INTERVAL"))
 
 	// TLS Config
 	if anyHasPrefix("GUBER_TLS_", os.Environ()) {
@@ -412,12 +643,14 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile io.Reader) (DaemonConfi
 	setter.SetDefault(&conf.EtcdPoolConf.EtcdConfig.Password, os.Getenv("GUBER_ETCD_PASSWORD"))
 	setter.SetDefault(&conf.EtcdPoolConf.Advertise.GRPCAddress, os.Getenv("GUBER_ETCD_ADVERTISE_ADDRESS"), conf.AdvertiseAddress)
 	setter.SetDefault(&conf.EtcdPoolConf.Advertise.DataCenter, os.Getenv("GUBER_ETCD_DATA_CENTER"), conf.DataCenter)
+	setter.SetDefault(&conf.EtcdPoolConf.Advertise.Weight, conf.PeerWeight)
 
 	setter.SetDefault(&conf.MemberListPoolConf.Advertise.GRPCAddress, os.Getenv("GUBER_MEMBERLIST_ADVERTISE_ADDRESS"), conf.AdvertiseAddress)
 	setter.SetDefault(&conf.MemberListPoolConf.MemberListAddress, os.Getenv("GUBER_MEMBERLIST_ADDRESS"), fmt.Sprintf("%s:7946", advAddr))
 	setter.SetDefault(&conf.MemberListPoolConf.MemberListBindAddress, os.Getenv("GUBER_MEMBERLIST_BIND_ADDRESS"))
 	setter.SetDefault(&conf.MemberListPoolConf.KnownNodes, getEnvSlice("GUBER_MEMBERLIST_KNOWN_NODES"), []string{})
 	setter.SetDefault(&conf.MemberListPoolConf.Advertise.DataCenter, conf.DataCenter)
+	setter.SetDefault(&conf.MemberListPoolConf.Advertise.Weight, conf.PeerWeight)
 	setter.SetDefault(&conf.MemberListPoolConf.EncryptionConfig.SecretKeys, getEnvSlice("GUBER_MEMBERLIST_SECRET_KEYS"), []string{})
 	setter.SetDefault(&conf.MemberListPoolConf.EncryptionConfig.GossipVerifyIncoming, getEnvBool(log, "GUBER_MEMBERLIST_GOSSIP_VERIFY_INCOMING"), true)
 	setter.SetDefault(&conf.MemberListPoolConf.EncryptionConfig.GossipVerifyOutgoing, getEnvBool(log, "GUBER_MEMBERLIST_GOSSIP_VERIFY_OUTGOING"), true)
@@ -436,31 +669,53 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile io.Reader) (DaemonConfi
 
 	// DNS Config
 	setter.SetDefault(&conf.DNSPoolConf.FQDN, os.Getenv("GUBER_DNS_FQDN"))
+	setter.SetDefault(&conf.DNSPoolConf.GRPCSRV, os.Getenv("GUBER_DNS_GRPC_SRV"))
+	setter.SetDefault(&conf.DNSPoolConf.HTTPSRV, os.Getenv("GUBER_DNS_HTTP_SRV"))
 	setter.SetDefault(&conf.DNSPoolConf.ResolvConf, os.Getenv("GUBER_RESOLV_CONF"), "/etc/resolv.conf")
 	setter.SetDefault(&conf.DNSPoolConf.OwnAddress, conf.AdvertiseAddress)
 
+	// File Config
+	setter.SetDefault(&conf.FilePoolConf.Path, os.Getenv("GUBER_PEERS_FILE"))
+	setter.SetDefault(&conf.FilePoolConf.PollInterval, getEnvDuration(log, "GUBER_PEERS_FILE_POLL_INTERVAL"))
+	setter.SetDefault(&conf.FilePoolConf.OwnAddress, conf.AdvertiseAddress)
+
 	// PeerPicker Config
 	if pp := os.Getenv("GUBER_PEER_PICKER"); pp != "" {
-		var replicas int
+		var replicas, tableSize int
+		var factor float64
 		var hash string
 
+		setter.SetDefault(&hash, os.Getenv("GUBER_PEER_PICKER_HASH"), "fnv1a")
+		hashFuncs := map[string]HashString64{
+			"fnv1a": fnv1a.HashString64,
+			"fnv1":  fnv1.HashString64,
+		}
+		fn, ok := hashFuncs[hash]
+		if !ok {
+			return conf, errors.Errorf("'GUBER_PEER_PICKER_HASH=%s' is invalid; choices are [%s]",
+				hash, validHash64Keys(hashFuncs))
+		}
+
 		switch pp {
 		case "replicated-hash":
-			setter.SetDefault(&replicas, getEnvInteger(log, "GUBER_REPLICATED_HASH_REPLICAS"), defaultReplicas)
-			conf.Picker = NewReplicatedConsistentHash(nil, replicas)
-			setter.SetDefault(&hash, os.Getenv("GUBER_PEER_PICKER_HASH"), "fnv1a")
-			hashFuncs := map[string]HashString64{
-				"fnv1a": fnv1a.HashString64,
-				"fnv1":  fnv1.HashString64,
-			}
-			fn, ok := hashFuncs[hash]
-			if !ok {
-				return conf, errors.Errorf("'GUBER_PEER_PICKER_HASH=%s' is invalid; choices are [%s]",
-					hash, validHash64Keys(hashFuncs))
-			}
+			setter.SetDefault(&replicas, getEnvInteger(log, "GUBER_REPLICATED_HASH_REPLICAS"), DefaultReplicas)
 			conf.Picker = NewReplicatedConsistentHash(fn, replicas)
+		case "rendezvous-hash":
+			conf.Picker = NewRendezvousHash(fn)
+		case "maglev-hash":
+			setter.SetDefault(&tableSize, getEnvInteger(log, "GUBER_MAGLEV_TABLE_SIZE"), DefaultMaglevTableSize)
+			conf.Picker = NewMaglevHash(fn, tableSize)
+		case "bounded-load-hash":
+			setter.SetDefault(&replicas, getEnvInteger(log, "GUBER_REPLICATED_HASH_REPLICAS"), DefaultReplicas)
+			setter.SetDefault(&factor, getEnvFloat(log, "GUBER_BOUNDED_LOAD_FACTOR"), DefaultBoundedLoadFactor)
+			if factor < 1 {
+				return conf, errors.Errorf("'GUBER_BOUNDED_LOAD_FACTOR=%g' is invalid; must be greater "+
+					"than or equal to 1", factor)
+			}
+			conf.Picker = NewBoundedLoadHash(fn, replicas, factor)
 		default:
-			return conf, errors.Errorf("'GUBER_PEER_PICKER=%s' is invalid; choices are ['replicated-hash', 'consistent-hash']", pp)
+			return conf, errors.Errorf("'GUBER_PEER_PICKER=%s' is invalid; choices are "+
+				"['replicated-hash', 'rendezvous-hash', 'maglev-hash', 'bounded-load-hash']", pp)
 		}
 	}
 
@@ -484,6 +739,11 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile io.Reader) (DaemonConfi
 		log.Debug("DNS peer pool config found")
 	}
 
+	if conf.PeerDiscoveryType == "file" && conf.FilePoolConf.Path == "" {
+		return conf, errors.New("when using `file` for peer discovery, you MUST provide the path " +
+			"of the file which lists the peers via `GUBER_PEERS_FILE`")
+	}
+
 	// If env contains any TLS configuration
 	if anyHasPrefix("GUBER_ETCD_TLS_", os.Environ()) {
 		if err := setupEtcdTLS(conf.EtcdPoolConf.EtcdConfig); err != nil {
@@ -631,6 +891,19 @@ func getEnvInteger(log logrus.FieldLogger, name string) int {
 	return int(i)
 }
 
+func getEnvFloat(log logrus.FieldLogger, name string) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.WithError(err).Errorf("while parsing '%s' as a float", name)
+		return 0
+	}
+	return f
+}
+
 func getEnvDuration(log logrus.FieldLogger, name string) time.Duration {
 	v := os.Getenv(name)
 	if v == "" {
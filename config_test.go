@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -44,3 +45,99 @@ func TestDefaultInstanceId(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, instanceConfig.InstanceID)
 }
+
+func TestPeerPicker(t *testing.T) {
+	os.Clearenv()
+	s := `
+GUBER_PEER_PICKER=maglev-hash
+GUBER_MAGLEV_TABLE_SIZE=1000`
+	daemonConfig, err := SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.IsType(t, &MaglevHash{}, daemonConfig.Picker)
+	require.Equal(t, 1009, daemonConfig.Picker.(*MaglevHash).tableSize)
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_PICKER=rendezvous-hash`
+	daemonConfig, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.IsType(t, &RendezvousHash{}, daemonConfig.Picker)
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_PICKER=bounded-load-hash
+GUBER_BOUNDED_LOAD_FACTOR=1.5
+GUBER_PEER_LOAD_INTERVAL=30s`
+	daemonConfig, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.IsType(t, &BoundedLoadHash{}, daemonConfig.Picker)
+	require.Equal(t, 1.5, daemonConfig.Picker.(*BoundedLoadHash).factor)
+	require.Equal(t, 30*time.Second, daemonConfig.Behaviors.PeerLoadInterval)
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_PICKER=bounded-load-hash
+GUBER_BOUNDED_LOAD_FACTOR=0.5`
+	_, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.ErrorContains(t, err, "'GUBER_BOUNDED_LOAD_FACTOR=0.5' is invalid")
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_PICKER=jump-hash`
+	_, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.ErrorContains(t, err, "'GUBER_PEER_PICKER=jump-hash' is invalid")
+}
+
+func TestPeerWeight(t *testing.T) {
+	os.Clearenv()
+	s := `
+GUBER_PEER_WEIGHT=3`
+	daemonConfig, err := SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.Equal(t, 3, daemonConfig.MemberListPoolConf.Advertise.Weight)
+	require.Equal(t, 3, daemonConfig.EtcdPoolConf.Advertise.Weight)
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_WEIGHT=-1`
+	_, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.ErrorContains(t, err, "'GUBER_PEER_WEIGHT=-1' is invalid")
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_WEIGHT=101`
+	_, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.ErrorContains(t, err, "'GUBER_PEER_WEIGHT=101' is invalid")
+}
+
+func TestPeersFile(t *testing.T) {
+	os.Clearenv()
+	s := `
+GUBER_PEER_DISCOVERY_TYPE=file
+GUBER_ADVERTISE_ADDRESS=10.10.10.10:1051
+GUBER_PEERS_FILE=/etc/gubernator/peers.yaml
+GUBER_PEERS_FILE_POLL_INTERVAL=1s`
+	daemonConfig, err := SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.Equal(t, "/etc/gubernator/peers.yaml", daemonConfig.FilePoolConf.Path)
+	require.Equal(t, time.Second, daemonConfig.FilePoolConf.PollInterval)
+	require.Equal(t, "10.10.10.10:1051", daemonConfig.FilePoolConf.OwnAddress)
+
+	os.Clearenv()
+	s = `
+GUBER_PEER_DISCOVERY_TYPE=file`
+	_, err = SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.ErrorContains(t, err, "`GUBER_PEERS_FILE`")
+}
+
+func TestDNSSRV(t *testing.T) {
+	os.Clearenv()
+	s := `
+GUBER_PEER_DISCOVERY_TYPE=dns
+GUBER_DNS_GRPC_SRV=_grpc._tcp.gubernator.service.consul
+GUBER_DNS_HTTP_SRV=_http._tcp.gubernator.service.consul`
+	daemonConfig, err := SetupDaemonConfig(logrus.StandardLogger(), strings.NewReader(s))
+	require.NoError(t, err)
+	require.Equal(t, "_grpc._tcp.gubernator.service.consul", daemonConfig.DNSPoolConf.GRPCSRV)
+	require.Equal(t, "_http._tcp.gubernator.service.consul", daemonConfig.DNSPoolConf.HTTPSRV)
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/mailgun/errors"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// crdtManager applies rate limits with GLOBAL_CRDT behavior. Each rate limit is a G-Counter
+// of the hits counted by each peer during the current window, and the counters which changed
+// are periodically gossiped to every other peer, which merge them with their own counters.
+// Every GlobalCRDTFullSyncInterval all counters are gossiped, such that a peer which joined
+// or restarted during a window learns the hits counted before, even for the rate limits
+// which receive no more hits.
+//
+// At most `Config.CacheSize` counters are kept. Once exceeded, the least recently used
+// counter is removed, and the hits other peers counted for it are forgotten until they are
+// gossiped again.
+type crdtManager struct {
+	mutex sync.Mutex
+	// The counters by key, ordered from most to least recently used
+	counters map[string]*list.Element // GUARDED_BY(mutex)
+	ll       *list.List               // GUARDED_BY(mutex)
+	maxSize  int
+	// The keys of the counters which changed since the last gossip
+	dirty map[string]struct{} // GUARDED_BY(mutex)
+	// The gossips since all counters were last gossiped, and after how many gossips all counters
+	// are gossiped again. Counting gossips keeps the gossip loop from reading the clock.
+	gossips      int // GUARDED_BY(mutex)
+	fullSyncEach int
+	// The latest creation time of the requests applied, in Epoch milliseconds. Counters of windows
+	// which ended before it are removed, without the gossip loop reading the clock.
+	latest int64 // GUARDED_BY(mutex)
+	// Identifies the hits counted by this instance. Unique to each run of the instance, such that
+	// an instance which restarts during a window doesn't count its hits from zero again.
+	id                   string
+	wg                   syncutil.WaitGroup
+	conf                 BehaviorConfig
+	log                  FieldLogger
+	instance             *V1Instance
+	metricGossipDuration prometheus.Summary
+	metricCounterCount   prometheus.Gauge
+}
+
+// crdtCounter is a G-Counter of the hits counted by each peer during a window
+type crdtCounter struct {
+	key       string
+	windowEnd int64
+	hits      map[string]int64
+}
+
+func newCRDTManager(conf BehaviorConfig, instance *V1Instance) *crdtManager {
+	cm := crdtManager{
+		counters:     make(map[string]*list.Element),
+		ll:           list.New(),
+		maxSize:      instance.conf.CacheSize,
+		dirty:        make(map[string]struct{}),
+		fullSyncEach: max(int(conf.GlobalCRDTFullSyncInterval/conf.GlobalSyncWait), 1),
+		id:           instance.conf.InstanceID + "-" + generateID(),
+		conf:         conf,
+		log:          instance.log,
+		instance:     instance,
+		metricGossipDuration: prometheus.NewSummary(prometheus.SummaryOpts{
+			Name:       "gubernator_crdt_gossip_duration",
+			Help:       "The duration of GLOBAL_CRDT gossips to peers in seconds.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.99: 0.001},
+		}),
+		metricCounterCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gubernator_crdt_counter_count",
+			Help: "The count of GLOBAL_CRDT rate limits with hits counted during their current window.",
+		}),
+	}
+	cm.runGossip()
+	return &cm
+}
+
+// total returns the hits counted by all peers
+func (c *crdtCounter) total() int64 {
+	var total int64
+	for _, hits := range c.hits {
+		total += hits
+	}
+	return total
+}
+
+// GetRateLimit applies the hits of the request to the counter of this instance, and returns
+// the status of the rate limit given the hits counted by all peers.
+func (cm *crdtManager) GetRateLimit(r *RateLimitReq) (*RateLimitResp, error) {
+	if r.Algorithm != Algorithm_TOKEN_BUCKET {
+		return nil, errors.New("GLOBAL_CRDT behavior only supports the TOKEN_BUCKET algorithm")
+	}
+	if HasBehavior(r.Behavior, Behavior_RESET_REMAINING) {
+		return nil, errors.New("GLOBAL_CRDT behavior does not support RESET_REMAINING")
+	}
+	if r.Hits < 0 {
+		return nil, errors.New("GLOBAL_CRDT behavior does not support negative hits")
+	}
+	windowEnd, err := crdtWindowEnd(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.HashKey()
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.latest = max(cm.latest, *r.CreatedAt)
+
+	// A counter from a later window is kept, as the clock of the peer which sent it may be
+	// slightly ahead of ours.
+	counter := cm.counter(key, windowEnd)
+
+	resp := &RateLimitResp{
+		Status:    Status_UNDER_LIMIT,
+		Limit:     r.Limit,
+		Remaining: max(r.Limit-counter.total(), 0),
+		ResetTime: counter.windowEnd,
+	}
+
+	switch {
+	case r.Hits == 0:
+		// Client is only interested in retrieving the current status
+	case r.Hits > resp.Remaining:
+		metricOverLimitCounter.Add(1)
+		resp.Status = Status_OVER_LIMIT
+		if HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT) && resp.Remaining > 0 {
+			counter.hits[cm.id] += resp.Remaining
+			resp.Remaining = 0
+			cm.dirty[key] = struct{}{}
+		}
+	default:
+		counter.hits[cm.id] += r.Hits
+		resp.Remaining -= r.Hits
+		cm.dirty[key] = struct{}{}
+	}
+	return resp, nil
+}
+
+// counter returns the counter of `key`, which is replaced by a new counter if it counts a
+// window which ends before `windowEnd`. The least recently used counter is removed once
+// there are more than `maxSize` counters.
+func (cm *crdtManager) counter(key string, windowEnd int64) *crdtCounter {
+	if ele, ok := cm.counters[key]; ok {
+		cm.ll.MoveToFront(ele)
+		counter := ele.Value.(*crdtCounter)
+		if counter.windowEnd < windowEnd {
+			counter.windowEnd = windowEnd
+			counter.hits = make(map[string]int64)
+		}
+		return counter
+	}
+
+	counter := &crdtCounter{key: key, windowEnd: windowEnd, hits: make(map[string]int64)}
+	cm.counters[key] = cm.ll.PushFront(counter)
+	if cm.maxSize > 0 && cm.ll.Len() > cm.maxSize {
+		cm.removeElement(cm.ll.Back())
+	}
+	return counter
+}
+
+// removeElement removes the counter, which is no longer gossiped even if it changed
+func (cm *crdtManager) removeElement(ele *list.Element) {
+	counter := cm.ll.Remove(ele).(*crdtCounter)
+	delete(cm.counters, counter.key)
+	delete(cm.dirty, counter.key)
+}
+
+// crdtWindowEnd returns the end of the window the request is counted in, in Epoch milliseconds.
+// Every peer calculates the same window without coordinating.
+func crdtWindowEnd(r *RateLimitReq) (int64, error) {
+	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		return GregorianExpiration(clock.Now(), r.Duration)
+	}
+	if r.Duration <= 0 {
+		return 0, errors.New("GLOBAL_CRDT behavior requires a 'duration' greater than 0")
+	}
+	createdAt := *r.CreatedAt
+	return createdAt - createdAt%r.Duration + r.Duration, nil
+}
+
+// merge merges the counters gossiped by another peer with our counters, keeping the highest
+// count of each peer. Counters of an earlier window than our own are ignored.
+func (cm *crdtManager) merge(counters []*PeerCounter) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	for _, pc := range counters {
+		counter := cm.counter(pc.Key, pc.WindowEnd)
+		if counter.windowEnd > pc.WindowEnd {
+			continue
+		}
+		for id, hits := range pc.Hits {
+			if hits > counter.hits[id] {
+				counter.hits[id] = hits
+			}
+		}
+	}
+}
+
+// runGossip gossips the counters which changed to every other peer in a forever loop,
+// at a periodic frequency determined by GlobalSyncWait.
+func (cm *crdtManager) runGossip() {
+	ticker := clock.NewTicker(cm.conf.GlobalSyncWait)
+	cm.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-ticker.C():
+			cm.gossip(context.Background())
+			return true
+		case <-done:
+			ticker.Stop()
+			return false
+		}
+	})
+}
+
+// gossip sends the counters which changed since the last gossip to every other peer, or all
+// counters once GlobalCRDTFullSyncInterval elapsed, and removes the counters of windows which
+// ended.
+func (cm *crdtManager) gossip(ctx context.Context) {
+	var counters []*PeerCounter
+
+	cm.mutex.Lock()
+	for _, ele := range cm.counters {
+		if ele.Value.(*crdtCounter).windowEnd < cm.latest {
+			cm.removeElement(ele)
+		}
+	}
+	keys := cm.dirty
+	if cm.gossips++; cm.gossips >= cm.fullSyncEach {
+		cm.gossips = 0
+		keys = make(map[string]struct{}, len(cm.counters))
+		for key := range cm.counters {
+			keys[key] = struct{}{}
+		}
+	}
+	for key := range keys {
+		counter := cm.counters[key].Value.(*crdtCounter)
+		pc := &PeerCounter{
+			Key:       key,
+			WindowEnd: counter.windowEnd,
+			Hits:      make(map[string]int64, len(counter.hits)),
+		}
+		// Include the hits of the other peers, such that peers which missed an earlier
+		// gossip from those peers still converge.
+		for id, hits := range counter.hits {
+			pc.Hits[id] = hits
+		}
+		counters = append(counters, pc)
+	}
+	cm.dirty = make(map[string]struct{})
+	cm.metricCounterCount.Set(float64(len(cm.counters)))
+	cm.mutex.Unlock()
+
+	if len(counters) == 0 {
+		return
+	}
+	defer prometheus.NewTimer(cm.metricGossipDuration).ObserveDuration()
+
+	for len(counters) > 0 {
+		n := len(counters)
+		if n > cm.conf.GlobalBatchLimit {
+			n = cm.conf.GlobalBatchLimit
+		}
+		cm.sendPeers(ctx, &UpdatePeerCountersReq{Counters: counters[:n]})
+		counters = counters[n:]
+	}
+}
+
+// sendPeers sends the counters to all other peers
+func (cm *crdtManager) sendPeers(ctx context.Context, req *UpdatePeerCountersReq) {
+	fan := syncutil.NewFanOut(cm.conf.GlobalPeerRequestsConcurrency)
+	for _, peer := range cm.instance.GetPeerList() {
+		// Exclude ourselves from the gossip
+		if peer.Info().IsOwner {
+			continue
+		}
+		// Exclude peers which don't support GLOBAL_CRDT, but not those still negotiating capabilities
+		if caps := peer.Capabilities(); caps.Negotiated && !caps.Has(CapabilityGlobalCRDT) {
+			continue
+		}
+
+		fan.Run(func(in interface{}) error {
+			peer := in.(*PeerClient)
+			ctx, cancel := context.WithTimeout(ctx, cm.conf.GlobalTimeout)
+			_, err := peer.UpdatePeerCounters(ctx, req)
+			cancel()
+
+			if err != nil && !errors.Is(err, context.Canceled) {
+				cm.log.WithError(err).Errorf("while gossiping GLOBAL_CRDT counters to '%s'", peer.Info().GRPCAddress)
+			}
+			return nil
+		}, peer)
+	}
+	fan.Wait()
+}
+
+// Close stops gossiping
+func (cm *crdtManager) Close() {
+	cm.wg.Stop()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/list"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRDTCounters(t *testing.T) {
+	cm := &crdtManager{
+		counters: make(map[string]*list.Element),
+		ll:       list.New(),
+		maxSize:  2,
+		dirty:    make(map[string]struct{}),
+		id:       "a",
+	}
+	hit := func(key string) *RateLimitResp {
+		createdAt := MillisecondNow()
+		resp, err := cm.GetRateLimit(&RateLimitReq{
+			Name:      "test",
+			UniqueKey: key,
+			Algorithm: Algorithm_TOKEN_BUCKET,
+			Behavior:  Behavior_GLOBAL_CRDT,
+			Duration:  Minute,
+			Limit:     10,
+			Hits:      1,
+			CreatedAt: &createdAt,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Bounded", func(t *testing.T) {
+		assert.Equal(t, int64(9), hit("1").Remaining)
+		assert.Equal(t, int64(9), hit("2").Remaining)
+		assert.Equal(t, int64(8), hit("1").Remaining)
+
+		// The least recently used counter is removed, along with its pending gossip
+		assert.Equal(t, int64(9), hit("3").Remaining)
+		assert.Len(t, cm.counters, 2)
+		assert.Equal(t, 2, cm.ll.Len())
+		assert.NotContains(t, cm.counters, "test_2")
+		assert.NotContains(t, cm.dirty, "test_2")
+		assert.Equal(t, int64(7), hit("1").Remaining)
+
+		// Counters merged from other peers are bounded too
+		cm.merge([]*PeerCounter{{Key: "test_4", WindowEnd: hit("3").ResetTime, Hits: map[string]int64{"b": 5}}})
+		assert.Len(t, cm.counters, 2)
+		assert.NotContains(t, cm.counters, "test_1")
+		assert.Equal(t, int64(4), hit("4").Remaining)
+	})
+}
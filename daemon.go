@@ -48,6 +48,7 @@ import (
 type Daemon struct {
 	GRPCListeners []net.Listener
 	HTTPListener  net.Listener
+	RESPListener  net.Listener
 	V1Server      *V1Instance
 	InstanceID    string
 	PeerInfo      PeerInfo
@@ -58,6 +59,7 @@ type Daemon struct {
 	conf          DaemonConfig
 	httpSrv       *http.Server
 	httpSrvNoMTLS *http.Server
+	respSrv       *respServer
 	grpcSrvs      []*grpc.Server
 	wg            syncutil.WaitGroup
 	statsHandler  *GRPCStatsHandler
@@ -94,15 +96,19 @@ func (s *Daemon) Start(ctx context.Context) error {
 
 	s.promRegister = prometheus.NewRegistry()
 
-	// The LRU cache for storing rate limits.
+	// The cache for storing rate limits.
 	cacheCollector := NewLRUCacheCollector()
 	if err := s.promRegister.Register(cacheCollector); err != nil {
 		return errors.Wrap(err, "during call to promRegister.Register()")
 	}
 
 	cacheFactory := func(maxSize int) Cache {
-		cache := NewLRUCache(maxSize)
-		cacheCollector.AddCache(cache)
+		var cache Cache
+		if s.conf.CacheType == CacheTypeTinyLFU {
+			cache = NewTinyLFUCache(maxSize)
+		} else {
+			cache = NewLRUCache(maxSize)
+		}
 		return cache
 	}
 
@@ -149,19 +155,28 @@ func (s *Daemon) Start(ctx context.Context) error {
 
 	// Registers a new gubernator instance with the GRPC server
 	s.instanceConf = Config{
-		PeerTraceGRPC: s.conf.TraceLevel >= tracing.DebugLevel,
-		PeerTLS:       s.conf.ClientTLS(),
-		DataCenter:    s.conf.DataCenter,
-		LocalPicker:   s.conf.Picker,
-		GRPCServers:   s.grpcSrvs,
-		Logger:        s.log,
-		CacheFactory:  cacheFactory,
-		Behaviors:     s.conf.Behaviors,
-		CacheSize:     s.conf.CacheSize,
-		Workers:       s.conf.Workers,
-		InstanceID:    s.conf.InstanceID,
-		EventChannel:  s.conf.EventChannel,
-		AdvertiseAddr: s.conf.AdvertiseAddress,
+		PeerTraceGRPC:         s.conf.TraceLevel >= tracing.DebugLevel,
+		PeerTLS:               s.conf.ClientTLS(),
+		DataCenter:            s.conf.DataCenter,
+		LocalPicker:           s.conf.Picker,
+		GRPCServers:           s.grpcSrvs,
+		Logger:                s.log,
+		CacheFactory:          cacheFactory,
+		CacheCollector:        cacheCollector,
+		Behaviors:             s.conf.Behaviors,
+		CacheSize:             s.conf.CacheSize,
+		CacheMaxBytes:         s.conf.CacheMaxBytes,
+		CacheSweepInterval:    s.conf.CacheSweepInterval,
+		CacheSweepBudget:      s.conf.CacheSweepBudget,
+		CacheNameMetricsLimit: s.conf.CacheNameMetricsLimit,
+		Workers:               s.conf.Workers,
+		Engine:                s.conf.Engine,
+		HotKeysCapacity:       s.conf.HotKeysCapacity,
+		HotKeysHalfLife:       s.conf.HotKeysHalfLife,
+		HotKeysMetricsLimit:   s.conf.HotKeysMetricsLimit,
+		InstanceID:            s.conf.InstanceID,
+		EventChannel:          s.conf.EventChannel,
+		AdvertiseAddr:         s.conf.AdvertiseAddress,
 	}
 
 	s.V1Server, err = NewV1Instance(s.instanceConf)
@@ -237,6 +252,13 @@ func (s *Daemon) Start(ctx context.Context) error {
 		if err != nil {
 			return errors.Wrap(err, "while creating the DNS pool")
 		}
+	case "file":
+		s.conf.FilePoolConf.OnUpdate = s.V1Server.SetPeers
+		s.conf.FilePoolConf.Logger = s.log
+		s.pool, err = NewFilePool(s.conf.FilePoolConf)
+		if err != nil {
+			return errors.Wrap(err, "while creating the file pool")
+		}
 	case "member-list":
 		s.conf.MemberListPoolConf.OnUpdate = s.V1Server.SetPeers
 		s.conf.MemberListPoolConf.Logger = s.log
@@ -364,7 +386,24 @@ func (s *Daemon) Start(ctx context.Context) error {
 		})
 	}
 
-	// Validate we can reach the GRPC and HTTP endpoints before returning
+	if s.conf.RESPListenAddress != "" {
+		s.RESPListener, err = net.Listen("tcp", s.conf.RESPListenAddress)
+		if err != nil {
+			return errors.Wrap(err, "while starting RESP listener")
+		}
+		if s.conf.ServerTLS() != nil {
+			s.RESPListener = tls.NewListener(s.RESPListener, s.conf.ServerTLS().Clone())
+		}
+		s.respSrv = newRESPServer(s.RESPListener, s.V1Server, s.log)
+		respAddr := s.RESPListener.Addr().String()
+		addrs = append(addrs, respAddr)
+		s.wg.Go(func() {
+			s.log.Infof("RESP Listening on %s ...", respAddr)
+			s.respSrv.Serve()
+		})
+	}
+
+	// Validate we can reach the GRPC, HTTP and RESP endpoints before returning
 	for _, l := range s.GRPCListeners {
 		addrs = append(addrs, l.Addr().String())
 	}
@@ -385,12 +424,25 @@ func (s *Daemon) Close() {
 		s.pool.Close()
 	}
 
+	if s.conf.DrainTimeout > 0 {
+		s.log.Infof("Draining for up to %s ...", s.conf.DrainTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), s.conf.DrainTimeout)
+		if err := s.V1Server.Drain(ctx); err != nil {
+			s.log.WithError(err).Error("while draining")
+		}
+		cancel()
+	}
+
 	s.log.Infof("HTTP Gateway close for %s ...", s.conf.HTTPListenAddress)
 	_ = s.httpSrv.Shutdown(context.Background())
 	if s.httpSrvNoMTLS != nil {
 		s.log.Infof("HTTP Status Gateway close for %s ...", s.conf.HTTPStatusListenAddress)
 		_ = s.httpSrvNoMTLS.Shutdown(context.Background())
 	}
+	if s.respSrv != nil {
+		s.log.Infof("RESP close for %s ...", s.conf.RESPListenAddress)
+		s.respSrv.Close()
+	}
 	for i, srv := range s.grpcSrvs {
 		s.log.Infof("GRPC close for %s ...", s.GRPCListeners[i].Addr())
 		srv.GracefulStop()
@@ -402,6 +454,7 @@ func (s *Daemon) Close() {
 	s.gwCancel()
 	s.httpSrv = nil
 	s.httpSrvNoMTLS = nil
+	s.respSrv = nil
 	s.grpcSrvs = nil
 }
 
@@ -418,6 +471,12 @@ func (s *Daemon) SetPeers(in []PeerInfo) {
 	s.V1Server.SetPeers(peers)
 }
 
+// ResizeWorkers changes the number of workers of this daemon without a restart,
+// see V1Instance.ResizeWorkers()
+func (s *Daemon) ResizeWorkers(ctx context.Context, n int) error {
+	return s.V1Server.ResizeWorkers(ctx, n)
+}
+
 // Config returns the current config for this Daemon
 func (s *Daemon) Config() DaemonConfig {
 	return s.conf
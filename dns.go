@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/mailgun/holster/v4/setter"
@@ -111,10 +112,68 @@ func (r *DNSResolver) lookupHost(host string, dnsType uint16, delay uint32) ([]n
 	return result, delay, nil
 }
 
+// lookupSRV returns the SRV records of the name, and the addresses of their targets which the
+// DNS server included in the additional section of the response
+func (r *DNSResolver) lookupSRV(name string, delay uint32) ([]*dns.SRV, map[string][]net.IP, uint32, error) {
+	m1 := new(dns.Msg)
+	m1.Id = dns.Id()
+	m1.RecursionDesired = true
+	m1.Question = []dns.Question{{Name: dns.Fqdn(name), Qtype: dns.TypeSRV, Qclass: dns.ClassINET}}
+	// The SRV records of a large pool along with their addresses don't fit in 512 bytes
+	m1.SetEdns0(4096, false)
+
+	server := r.Servers[r.random.Intn(len(r.Servers))]
+	in, err := dns.Exchange(m1, server)
+	if err == nil && in.Truncated {
+		// The response didn't fit in a UDP packet, so ask again over TCP for all the records
+		client := &dns.Client{Net: "tcp"}
+		in, _, err = client.Exchange(m1, server)
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if in.Rcode != dns.RcodeSuccess {
+		return nil, nil, 0, errors.New(dns.RcodeToString[in.Rcode])
+	}
+
+	var records []*dns.SRV
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.SRV); ok {
+			records = append(records, t)
+			delay = min(delay, record.Header().Ttl)
+		}
+	}
+	if len(records) == 0 {
+		return nil, nil, 0, errors.New("not useful")
+	}
+
+	ips := make(map[string][]net.IP)
+	for _, record := range in.Extra {
+		switch t := record.(type) {
+		case *dns.A:
+			ips[t.Hdr.Name] = append(ips[t.Hdr.Name], t.A)
+		case *dns.AAAA:
+			ips[t.Hdr.Name] = append(ips[t.Hdr.Name], t.AAAA)
+		}
+	}
+	return records, ips, delay, nil
+}
+
 type DNSPoolConfig struct {
-	// (Required) The FQDN that should resolve to gubernator instance ip addresses
+	// (Required) The FQDN that should resolve to gubernator instance ip addresses.
+	// Not required when GRPCSRV is set.
 	FQDN string
 
+	// (Optional) The name of the SRV records which list the GRPC ports of the gubernator instances,
+	// such as `_grpc._tcp.gubernator.service.consul`. When set, the peers are discovered from the
+	// SRV records instead of the A and AAAA records of FQDN.
+	GRPCSRV string
+
+	// (Optional) The name of the SRV records which list the HTTP ports of the gubernator instances.
+	// When GRPCSRV is set without HTTPSRV, peers are assumed to listen for HTTP on port 1050.
+	HTTPSRV string
+
 	// (Required) Filesystem path to "/etc/resolv.conf", override for testing
 	ResolvConf string
 
@@ -141,6 +200,10 @@ func NewDNSPool(conf DNSPoolConfig) (*DNSPool, error) {
 		return nil, errors.New("Advertise.GRPCAddress is required")
 	}
 
+	if conf.FQDN == "" && conf.GRPCSRV == "" {
+		return nil, errors.New("FQDN or GRPCSRV is required")
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	pool := &DNSPool{
 		log:    conf.Logger,
@@ -152,19 +215,96 @@ func NewDNSPool(conf DNSPoolConfig) (*DNSPool, error) {
 	return pool, nil
 }
 
-func peer(ip string, self string, ipv6 bool) PeerInfo {
-
-	if ipv6 {
-		ip = "[" + ip + "]"
-	}
-	grpc := ip + ":1051"
+func peer(ip string, self string) PeerInfo {
+	grpc := net.JoinHostPort(ip, "1051")
 	return PeerInfo{
 		DataCenter:  "",
-		HTTPAddress: ip + ":1050",
+		HTTPAddress: net.JoinHostPort(ip, "1050"),
 		GRPCAddress: grpc,
 		IsOwner:     grpc == self,
 	}
+}
+
+// srvPeers returns a peer for each address of the targets of the GRPC SRV records with the
+// lowest priority, as the targets with a higher priority are backups. The weights of the
+// records become the weights of the peers, see srvWeights. The HTTP port of a peer is taken from the HTTP SRV record
+// of the same target, or defaults to 1050 if httpSRV is nil.
+func srvPeers(grpcSRV, httpSRV []*dns.SRV, ips map[string][]net.IP, self string) []PeerInfo {
+	if len(grpcSRV) == 0 {
+		return nil
+	}
+
+	priority := grpcSRV[0].Priority
+	for _, record := range grpcSRV {
+		if record.Priority < priority {
+			priority = record.Priority
+		}
+	}
+	var records []*dns.SRV
+	for _, record := range grpcSRV {
+		if record.Priority == priority {
+			records = append(records, record)
+		}
+	}
+	weights := srvWeights(records)
+
+	httpPorts := make(map[string]uint16, len(httpSRV))
+	for _, record := range httpSRV {
+		httpPorts[dns.Fqdn(record.Target)] = record.Port
+	}
 
+	var peers []PeerInfo
+	for i, record := range records {
+		target := dns.Fqdn(record.Target)
+		for _, ip := range ips[target] {
+			p := PeerInfo{
+				GRPCAddress: net.JoinHostPort(ip.String(), strconv.Itoa(int(record.Port))),
+				Weight:      weights[i],
+			}
+			if httpSRV == nil {
+				p.HTTPAddress = net.JoinHostPort(ip.String(), "1050")
+			} else if port, ok := httpPorts[target]; ok {
+				p.HTTPAddress = net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+			}
+			p.IsOwner = p.GRPCAddress == self
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// srvWeights returns the peer weight of each SRV record. SRV weights range from 0 to 65535,
+// while peer weights range from 1 to MaxPeerWeight. A weight of 0 is the smallest weight, as
+// targets with a weight of 0 are rarely picked, so it becomes 1. The weights are divided by
+// their greatest common divisor such that they stay small, and if the largest is still above
+// MaxPeerWeight, they are scaled such that the largest becomes MaxPeerWeight. Weights which
+// round down to 0 become 1.
+func srvWeights(records []*dns.SRV) []int {
+	var divisor, largest uint32
+	for _, record := range records {
+		divisor = gcd(divisor, max(uint32(record.Weight), 1))
+	}
+	weights := make([]int, len(records))
+	for i, record := range records {
+		w := max(uint32(record.Weight), 1) / divisor
+		largest = max(largest, w)
+		weights[i] = int(w)
+	}
+	if largest <= MaxPeerWeight {
+		return weights
+	}
+	for i, w := range weights {
+		scaled := (uint32(w)*MaxPeerWeight + largest/2) / largest
+		weights[i] = int(max(scaled, 1))
+	}
+	return weights
+}
+
+func gcd(a, b uint32) uint32 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
 }
 
 func min(a uint32, b uint32) uint32 {
@@ -182,6 +322,8 @@ func (p *DNSPool) task() {
 		if err != nil {
 			p.log.Warn("No resolver: ", err)
 
+		} else if p.conf.GRPCSRV != "" {
+			delay = p.updateFromSRV(resolver, delay)
 		} else {
 			ipv4, delay4, err4 := resolver.lookupHost(p.conf.FQDN, dns.TypeA, delay)
 			ipv6, delay6, err6 := resolver.lookupHost(p.conf.FQDN, dns.TypeAAAA, delay)
@@ -189,13 +331,13 @@ func (p *DNSPool) task() {
 			if err4 == nil {
 				delay = min(delay, delay4)
 				for _, ip := range ipv4 {
-					update = append(update, peer(ip.String(), p.conf.OwnAddress, false))
+					update = append(update, peer(ip.String(), p.conf.OwnAddress))
 				}
 			}
 			if err6 == nil {
 				delay = min(delay, delay6)
 				for _, ip := range ipv6 {
-					update = append(update, peer(ip.String(), p.conf.OwnAddress, false))
+					update = append(update, peer(ip.String(), p.conf.OwnAddress))
 				}
 			}
 			if len(update) > 0 {
@@ -213,6 +355,64 @@ func (p *DNSPool) task() {
 	}
 }
 
+// updateFromSRV looks up the SRV records of the peers and calls OnUpdate, returning how long
+// until the records should be looked up again
+func (p *DNSPool) updateFromSRV(resolver *DNSResolver, delay uint32) uint32 {
+	grpcSRV, ips, delay, err := resolver.lookupSRV(p.conf.GRPCSRV, delay)
+	if err != nil {
+		p.log.Error("Looking up GRPC SRV records: ", err)
+		return 300
+	}
+
+	var httpSRV []*dns.SRV
+	if p.conf.HTTPSRV != "" {
+		var httpIPs map[string][]net.IP
+		var httpDelay uint32
+		httpSRV, httpIPs, httpDelay, err = resolver.lookupSRV(p.conf.HTTPSRV, delay)
+		if err != nil {
+			p.log.Warn("Looking up HTTP SRV records: ", err)
+			// Peers are still reachable via GRPC, so keep them without an HTTP address
+			httpSRV = []*dns.SRV{}
+		} else {
+			delay = httpDelay
+		}
+		for target, addrs := range httpIPs {
+			if _, ok := ips[target]; !ok {
+				ips[target] = addrs
+			}
+		}
+	}
+
+	// Resolve the targets the DNS server did not include the addresses of
+	for _, record := range grpcSRV {
+		target := dns.Fqdn(record.Target)
+		if _, ok := ips[target]; ok {
+			continue
+		}
+		ipv4, delay4, err4 := resolver.lookupHost(target, dns.TypeA, delay)
+		if err4 == nil {
+			delay = min(delay, delay4)
+			ips[target] = append(ips[target], ipv4...)
+		}
+		ipv6, delay6, err6 := resolver.lookupHost(target, dns.TypeAAAA, delay)
+		if err6 == nil {
+			delay = min(delay, delay6)
+			ips[target] = append(ips[target], ipv6...)
+		}
+		if err4 != nil && err6 != nil {
+			p.log.Warn("Looking up SRV target '", target, "': ", err4, err6)
+		}
+	}
+
+	update := srvPeers(grpcSRV, httpSRV, ips, p.conf.OwnAddress)
+	if len(update) > 0 {
+		p.conf.OnUpdate(update)
+	} else {
+		p.log.Error("No peers found in the SRV records of ", p.conf.GRPCSRV)
+	}
+	return delay
+}
+
 func (p *DNSPool) Close() {
 	p.cancel()
 }
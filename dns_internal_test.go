@@ -0,0 +1,158 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRVPeers(t *testing.T) {
+	srv := func(target string, priority, weight, port uint16) *dns.SRV {
+		return &dns.SRV{Target: target, Priority: priority, Weight: weight, Port: port}
+	}
+	ips := map[string][]net.IP{
+		"a.gubernator.":      {net.ParseIP("10.0.0.1")},
+		"b.gubernator.":      {net.ParseIP("10.0.0.2")},
+		"c.gubernator.":      {net.ParseIP("10.0.0.3")},
+		"backup.gubernator.": {net.ParseIP("10.0.0.4")},
+		"ipv6.gubernator.":   {net.ParseIP("fd00::1")},
+	}
+
+	t.Run("Ports and weights per target", func(t *testing.T) {
+		peers := srvPeers(
+			[]*dns.SRV{
+				srv("a.gubernator.", 10, 20, 9051),
+				srv("b.gubernator", 10, 40, 9052),
+				srv("backup.gubernator.", 20, 10, 9053),
+			},
+			[]*dns.SRV{
+				srv("a.gubernator.", 10, 20, 9050),
+				srv("b.gubernator.", 10, 40, 9060),
+			},
+			ips, "10.0.0.2:9052")
+
+		assert.Equal(t, []PeerInfo{
+			{GRPCAddress: "10.0.0.1:9051", HTTPAddress: "10.0.0.1:9050", Weight: 1},
+			{GRPCAddress: "10.0.0.2:9052", HTTPAddress: "10.0.0.2:9060", Weight: 2, IsOwner: true},
+		}, peers)
+	})
+
+	t.Run("Default HTTP port", func(t *testing.T) {
+		peers := srvPeers(
+			[]*dns.SRV{
+				srv("c.gubernator.", 0, 0, 1051),
+				srv("ipv6.gubernator.", 0, 0, 1051),
+			},
+			nil, ips, "")
+
+		assert.Equal(t, []PeerInfo{
+			{GRPCAddress: "10.0.0.3:1051", HTTPAddress: "10.0.0.3:1050", Weight: 1},
+			{GRPCAddress: "[fd00::1]:1051", HTTPAddress: "[fd00::1]:1050", Weight: 1},
+		}, peers)
+	})
+
+	t.Run("Missing HTTP record", func(t *testing.T) {
+		peers := srvPeers(
+			[]*dns.SRV{srv("a.gubernator.", 0, 1, 1051)},
+			[]*dns.SRV{}, ips, "")
+
+		assert.Equal(t, []PeerInfo{{GRPCAddress: "10.0.0.1:1051", Weight: 1}}, peers)
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			weights  []uint16
+			expected []int
+		}{
+			{name: "Zero is the smallest weight", weights: []uint16{0, 3}, expected: []int{1, 3}},
+			{name: "Zero and largest", weights: []uint16{0, 65535}, expected: []int{1, 100}},
+			{name: "Largest", weights: []uint16{65535, 65535}, expected: []int{1, 1}},
+			{name: "Coprime", weights: []uint16{100, 101}, expected: []int{99, 100}},
+			{name: "Coprime and large", weights: []uint16{65534, 65535}, expected: []int{100, 100}},
+			{name: "Ratio", weights: []uint16{1001, 3000, 60000}, expected: []int{2, 5, 100}},
+			{name: "Common divisor", weights: []uint16{1000, 3000, 60000}, expected: []int{1, 3, 60}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				var records []*dns.SRV
+				for i, w := range tt.weights {
+					records = append(records, srv("a.gubernator.", 0, w, uint16(1051+i)))
+				}
+				var weights []int
+				for _, p := range srvPeers(records, nil, ips, "") {
+					assert.LessOrEqual(t, p.Weight, MaxPeerWeight)
+					weights = append(weights, p.Weight)
+				}
+				assert.Equal(t, tt.expected, weights)
+			})
+		}
+	})
+
+	t.Run("Unresolved target", func(t *testing.T) {
+		assert.Empty(t, srvPeers([]*dns.SRV{srv("unknown.gubernator.", 0, 1, 1051)}, nil, ips, ""))
+	})
+}
+
+func TestLookupSRVTruncated(t *testing.T) {
+	// The server truncates responses over UDP, and answers with every record over TCP
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if _, ok := w.LocalAddr().(*net.UDPAddr); ok {
+			assert.NotNil(t, req.IsEdns0())
+			resp.Truncated = true
+		} else {
+			for i := 0; i < 100; i++ {
+				target := RandomString(20) + ".gubernator."
+				resp.Answer = append(resp.Answer, &dns.SRV{
+					Hdr:    dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 30},
+					Target: target,
+					Port:   1051,
+				})
+				resp.Extra = append(resp.Extra, &dns.A{
+					Hdr: dns.RR_Header{Name: target, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+					A:   net.IPv4(10, 0, 0, byte(i)),
+				})
+			}
+		}
+		_ = w.WriteMsg(resp)
+	})
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	udp := &dns.Server{PacketConn: pc, Handler: handler}
+	tcp := &dns.Server{Listener: listener, Handler: handler}
+	go func() { _ = udp.ActivateAndServe() }()
+	go func() { _ = tcp.ActivateAndServe() }()
+	defer func() { _ = udp.Shutdown() }()
+	defer func() { _ = tcp.Shutdown() }()
+
+	r := &DNSResolver{Servers: []string{pc.LocalAddr().String()}, random: rand.New(rand.NewSource(1))}
+	records, ips, delay, err := r.lookupSRV("_grpc._tcp.gubernator", 300)
+	require.NoError(t, err)
+	assert.Len(t, records, 100)
+	assert.Len(t, ips, 100)
+	assert.Equal(t, uint32(30), delay)
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/errors"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// How often a draining instance checks whether the peers stopped routing requests to it
+	drainPollInterval = 100 * clock.Millisecond
+)
+
+var metricHandoffCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gubernator_handoff_count",
+	Help: "The count of rate limits a draining instance handed off to their next owners.  Label \"type\" may be \"sent\" for rate limits sent to the next owner, \"skipped\" for rate limits whose next owner doesn't support the handoff, or \"failed\" for rate limits which could not be sent.",
+}, []string{"type"})
+
+// Drain prepares the instance to leave the cluster. Once draining, HealthCheck reports the
+// instance is not ready and the requests forwarded by peers are served until the peers stop
+// routing requests to this instance. Then the rate limits this instance owns are handed off to
+// the peers which own them once this instance has left. Drain returns when the rate limits
+// are handed off or the context is done.
+func (s *V1Instance) Drain(ctx context.Context) error {
+	s.draining.Store(true)
+	s.lastPeerRequest.Store(clock.Now().UnixNano())
+
+	s.waitForPeersIdle(ctx)
+	return s.handoff(ctx)
+}
+
+// waitForPeersIdle waits until no forwarded requests were received for DrainIdleWait. If the
+// context has a deadline, half the time left is reserved for the handoff.
+func (s *V1Instance) waitForPeersIdle(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, clock.Until(deadline)/2)
+		defer cancel()
+	}
+
+	ticker := clock.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if clock.Since(time.Unix(0, s.lastPeerRequest.Load())) >= s.conf.Behaviors.DrainIdleWait {
+			return
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			s.log.Warn("peers are still routing requests to this instance; handing off rate limits")
+			return
+		}
+	}
+}
+
+// handoff sends the rate limits this instance owns to the peers which own them once this
+// instance has left. The next owner merges them with the rate limits it already has, such as
+// rate limits it received requests for since the peers stopped routing requests to this
+// instance.
+func (s *V1Instance) handoff(ctx context.Context) error {
+	s.peerMutex.RLock()
+	picker := s.conf.LocalPicker
+	next := picker.New()
+	for _, peer := range picker.Peers() {
+		if !peer.Info().IsOwner {
+			next.Add(peer)
+		}
+	}
+	s.peerMutex.RUnlock()
+
+	if len(next.Peers()) == 0 {
+		return nil
+	}
+
+	items, err := s.workerPool.Items(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error in workerPool.Items")
+	}
+
+	batches := make(map[*PeerClient][]*HandoffRateLimit)
+	for _, item := range items {
+		if item.IsExpired() {
+			continue
+		}
+		rl := newHandoffRateLimit(item)
+		if rl == nil {
+			continue
+		}
+
+		s.peerMutex.RLock()
+		owner, err := picker.Get(item.Key)
+		s.peerMutex.RUnlock()
+		if err != nil || !owner.Info().IsOwner {
+			continue
+		}
+
+		peer, err := next.Get(item.Key)
+		if err != nil {
+			continue
+		}
+		if !peer.HasCapability(CapabilityHandoff) {
+			metricHandoffCounter.WithLabelValues("skipped").Inc()
+			continue
+		}
+		batches[peer] = append(batches[peer], rl)
+	}
+
+	var wg sync.WaitGroup
+	for peer, batch := range batches {
+		wg.Add(1)
+		go func(peer *PeerClient, batch []*HandoffRateLimit) {
+			defer wg.Done()
+			for len(batch) != 0 {
+				n := len(batch)
+				if n > maxBatchSize {
+					n = maxBatchSize
+				}
+				_, err := peer.HandoffPeerRateLimits(ctx, &HandoffPeerRateLimitsReq{RateLimits: batch[:n]})
+				if err != nil {
+					s.log.WithError(err).
+						WithField("peer", peer.Info().GRPCAddress).
+						Error("while handing off rate limits")
+					metricHandoffCounter.WithLabelValues("failed").Add(float64(len(batch)))
+					return
+				}
+				metricHandoffCounter.WithLabelValues("sent").Add(float64(n))
+				batch = batch[n:]
+			}
+		}(peer, batch)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// HandoffPeerRateLimits is called by a draining peer to hand off the rate limits it owned to this peer
+func (s *V1Instance) HandoffPeerRateLimits(ctx context.Context, r *HandoffPeerRateLimitsReq) (*HandoffPeerRateLimitsResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.HandoffPeerRateLimits")).ObserveDuration()
+	resp := &HandoffPeerRateLimitsResp{}
+	for _, rl := range r.RateLimits {
+		handed := newHandoffCacheItem(rl)
+		if handed == nil {
+			continue
+		}
+
+		item, ok, err := s.workerPool.GetCacheItem(ctx, rl.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error in workerPool.GetCacheItem")
+		}
+		if ok && !item.IsExpired() {
+			// Both peers counted hits for the rate limit, so count the hits of both
+			item = mergeHandoffCacheItem(item, handed)
+			if item == nil {
+				continue
+			}
+			resp.Merged++
+		} else {
+			item = handed
+			resp.Added++
+		}
+		if err := s.workerPool.AddCacheItem(ctx, rl.Key, item); err != nil {
+			return nil, errors.Wrap(err, "Error in workerPool.AddCacheItem")
+		}
+	}
+	return resp, nil
+}
+
+// newHandoffCacheItem returns the cache item of a handed off rate limit, or nil if the
+// algorithm is unknown
+func newHandoffCacheItem(rl *HandoffRateLimit) *CacheItem {
+	item := &CacheItem{
+		Algorithm: rl.Algorithm,
+		Key:       rl.Key,
+		ExpireAt:  rl.ExpireAt,
+	}
+	switch rl.Algorithm {
+	case Algorithm_LEAKY_BUCKET:
+		item.Value = &LeakyBucketItem{
+			Limit:     rl.Limit,
+			Duration:  rl.Duration,
+			Remaining: rl.Remaining,
+			UpdatedAt: rl.UpdatedAt,
+			Burst:     rl.Burst,
+		}
+	case Algorithm_TOKEN_BUCKET:
+		item.Value = &TokenBucketItem{
+			Status:    rl.Status,
+			Limit:     rl.Limit,
+			Duration:  rl.Duration,
+			Remaining: int64(rl.Remaining),
+			CreatedAt: rl.UpdatedAt,
+		}
+	default:
+		return nil
+	}
+	return item
+}
+
+// mergeHandoffCacheItem returns a copy of the existing item which also counts the hits of the
+// handed off item, such that the merged rate limit allows no more hits than the limit across
+// both peers. The window of the existing item is kept. Returns nil if the hits of the handed off
+// item don't count in the window of the existing item, or if the items have a different
+// algorithm, limit or duration, in which case the existing item was created by a request with
+// the current config of the rate limit. Either way the existing item is kept as is.
+func mergeHandoffCacheItem(existing, handed *CacheItem) *CacheItem {
+	if existing.Algorithm != handed.Algorithm || handed.IsExpired() {
+		return nil
+	}
+	merged := &CacheItem{
+		Algorithm: existing.Algorithm,
+		Key:       existing.Key,
+		ExpireAt:  existing.ExpireAt,
+		InvalidAt: existing.InvalidAt,
+	}
+	switch v := existing.Value.(type) {
+	case *LeakyBucketItem:
+		h, ok := handed.Value.(*LeakyBucketItem)
+		if !ok || h.Limit != v.Limit || h.Duration != v.Duration {
+			return nil
+		}
+		b := *v
+		b.Remaining = max(b.Remaining-(float64(h.Burst)-h.Remaining), 0)
+		merged.Value = &b
+	case *TokenBucketItem:
+		h, ok := handed.Value.(*TokenBucketItem)
+		// The handed off window ended before the existing window started
+		if !ok || h.Limit != v.Limit || h.Duration != v.Duration || handed.ExpireAt <= v.CreatedAt {
+			return nil
+		}
+		b := *v
+		b.Remaining = max(b.Remaining-(h.Limit-h.Remaining), 0)
+		if h.Status == Status_OVER_LIMIT {
+			b.Status = h.Status
+		}
+		merged.Value = &b
+	default:
+		return nil
+	}
+	return merged
+}
+
+// newHandoffRateLimit returns the rate limit of the cache item to hand off, or nil if the
+// cache item is not a rate limit
+func newHandoffRateLimit(item *CacheItem) *HandoffRateLimit {
+	rl := &HandoffRateLimit{
+		Key:       item.Key,
+		Algorithm: item.Algorithm,
+		ExpireAt:  item.ExpireAt,
+	}
+	switch v := item.Value.(type) {
+	case *LeakyBucketItem:
+		rl.Limit = v.Limit
+		rl.Duration = v.Duration
+		rl.Remaining = v.Remaining
+		rl.Burst = v.Burst
+		rl.UpdatedAt = v.UpdatedAt
+	case *TokenBucketItem:
+		rl.Status = v.Status
+		rl.Limit = v.Limit
+		rl.Duration = v.Duration
+		rl.Remaining = float64(v.Remaining)
+		rl.UpdatedAt = v.CreatedAt
+	default:
+		return nil
+	}
+	return rl
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"io"
+)
+
+const (
+	// EngineWorkerPool applies rate limits using a pool of worker goroutines, each owning a
+	// partition of the cache. Requests are handed to the owning worker over channels.
+	EngineWorkerPool = "worker-pool"
+	// EngineShardedMutex applies rate limits in the calling goroutine while holding the
+	// lock of the cache shard which owns the rate limit.
+	EngineShardedMutex = "sharded-mutex"
+)
+
+// notAppliedError is returned by a CacheEngine when a request had no effect on the cache, such
+// as when the context expired before the request reached the cache.
+type notAppliedError struct {
+	error
+}
+
+func (e notAppliedError) Unwrap() error {
+	return e.error
+}
+
+// CacheEngine applies rate limits to the cache. Implementations partition the cache such
+// that operations on the same key are never applied concurrently, as the Cache
+// implementations and the algorithms are not thread-safe.
+type CacheEngine interface {
+	io.Closer
+
+	// GetRateLimit applies the rate limit request to the cache
+	GetRateLimit(ctx context.Context, req *RateLimitReq, reqState RateLimitReqState) (*RateLimitResp, error)
+
+	// LeaseTokens returns the unused tokens of a previous lease and leases new tokens from
+	// the rate limit in a single operation. Returns a notAppliedError if the unused tokens were
+	// not returned.
+	LeaseTokens(ctx context.Context, req *LeaseReq, returned int64) (rl *RateLimitResp, granted int64, err error)
+
+	// AddCacheItem adds an item to the cache
+	AddCacheItem(ctx context.Context, key string, item *CacheItem) error
+
+	// GetCacheItem gets an item from the cache
+	GetCacheItem(ctx context.Context, key string) (item *CacheItem, found bool, err error)
+
+	// Load loads the items returned by Config.Loader into the cache
+	Load(ctx context.Context) error
+
+	// Store saves all items in the cache with Config.Loader
+	Store(ctx context.Context) error
+
+	// Items returns all items in the cache
+	Items(ctx context.Context) ([]*CacheItem, error)
+
+	// Resize changes the number of workers or shards which partition the cache to `n`
+	// without losing the items in the cache.
+	Resize(ctx context.Context, n int) error
+}
+
+// NewCacheEngine returns the CacheEngine selected by `Config.Engine`
+func NewCacheEngine(conf *Config) CacheEngine {
+	if conf.Engine == EngineShardedMutex {
+		return NewShardedCache(conf)
+	}
+	return NewWorkerPool(conf)
+}
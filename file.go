@@ -0,0 +1,179 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"slices"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+type FilePoolConfig struct {
+	// (Required) The path of a JSON or YAML file with the list of peers, such as
+	//  - grpc-address: 10.0.0.1:1051
+	//    http-address: 10.0.0.1:1050
+	//    data-center: us-east-1
+	//    weight: 2
+	Path string
+
+	// (Required) Own GRPC address
+	OwnAddress string
+
+	// (Required) Called when the list of gubernators in the pool updates
+	OnUpdate UpdateFunc
+
+	// (Optional) How often the file is read to find changes to the list of peers. Defaults to 5s
+	PollInterval clock.Duration
+
+	Logger FieldLogger
+}
+
+// FilePool discovers peers from a file, which is read again every PollInterval such that
+// peers may be added or removed by editing the file. When the file becomes invalid the
+// last valid list of peers is kept.
+type FilePool struct {
+	log    FieldLogger
+	conf   FilePoolConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	data   []byte
+	peers  []PeerInfo
+}
+
+func NewFilePool(conf FilePoolConfig) (*FilePool, error) {
+	setter.SetDefault(&conf.Logger, logrus.WithField("category", "gubernator"))
+	setter.SetDefault(&conf.PollInterval, 5*clock.Second)
+
+	if conf.Path == "" {
+		return nil, errors.New("Path is required")
+	}
+	if conf.OwnAddress == "" {
+		return nil, errors.New("OwnAddress is required")
+	}
+	if conf.OnUpdate == nil {
+		return nil, errors.New("OnUpdate is required")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &FilePool{
+		log:    conf.Logger,
+		conf:   conf,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	// Refuse to start with a missing or invalid file, later errors keep the last valid peers
+	if err := pool.load(); err != nil {
+		cancel()
+		return nil, err
+	}
+	go pool.task()
+	return pool, nil
+}
+
+func (p *FilePool) task() {
+	ticker := clock.NewTicker(p.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C():
+			if err := p.load(); err != nil {
+				p.log.WithError(err).Error("while reading the peers file; keeping the last valid peers")
+			}
+		}
+	}
+}
+
+// load reads the file and calls OnUpdate if the list of peers changed
+func (p *FilePool) load() error {
+	data, err := os.ReadFile(p.conf.Path)
+	if err != nil {
+		return errors.Wrap(err, "while reading the peers file")
+	}
+	if p.data != nil && bytes.Equal(data, p.data) {
+		return nil
+	}
+
+	peers, err := parsePeersFile(data, p.conf.OwnAddress)
+	if err != nil {
+		return errors.Wrapf(err, "peers file '%s' is invalid", p.conf.Path)
+	}
+	p.data = data
+	if p.peers != nil && slices.Equal(peers, p.peers) {
+		return nil
+	}
+	p.peers = peers
+
+	if !slices.ContainsFunc(peers, func(peer PeerInfo) bool { return peer.IsOwner }) {
+		p.log.Warnf("peers file '%s' does not include this instance '%s'", p.conf.Path, p.conf.OwnAddress)
+	}
+	p.log.Debugf("peers file '%s' lists %d peers", p.conf.Path, len(peers))
+	p.conf.OnUpdate(slices.Clone(peers))
+	return nil
+}
+
+func (p *FilePool) Close() {
+	p.cancel()
+}
+
+// parsePeersFile parses and validates a JSON or YAML list of peers
+func parsePeersFile(data []byte, ownAddress string) ([]PeerInfo, error) {
+	var peers []PeerInfo
+	if err := yaml.UnmarshalStrict(data, &peers); err != nil {
+		return nil, err
+	}
+	if len(peers) == 0 {
+		return nil, errors.New("no peers listed")
+	}
+
+	seen := make(map[string]struct{}, len(peers))
+	for i := range peers {
+		peer := &peers[i]
+		if peer.GRPCAddress == "" {
+			return nil, errors.Errorf("peer %d has no grpc-address", i)
+		}
+		if _, _, err := net.SplitHostPort(peer.GRPCAddress); err != nil {
+			return nil, errors.Wrapf(err, "grpc-address '%s' is invalid; expected format is `address:port`",
+				peer.GRPCAddress)
+		}
+		if peer.HTTPAddress != "" {
+			if _, _, err := net.SplitHostPort(peer.HTTPAddress); err != nil {
+				return nil, errors.Wrapf(err, "http-address '%s' is invalid; expected format is `address:port`",
+					peer.HTTPAddress)
+			}
+		}
+		if peer.Weight < 0 || peer.Weight > MaxPeerWeight {
+			return nil, errors.Errorf("weight '%d' of peer '%s' is invalid; must be between 0 and %d",
+				peer.Weight, peer.GRPCAddress, MaxPeerWeight)
+		}
+		if _, ok := seen[peer.GRPCAddress]; ok {
+			return nil, errors.Errorf("peer '%s' is listed more than once", peer.GRPCAddress)
+		}
+		seen[peer.GRPCAddress] = struct{}{}
+		peer.IsOwner = peer.GRPCAddress == ownAddress
+	}
+	return peers, nil
+}
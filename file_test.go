@@ -0,0 +1,167 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- grpc-address: 10.0.0.1:1051
+  http-address: 10.0.0.1:1050
+- grpc-address: 10.0.0.2:1051
+  http-address: 10.0.0.2:1050
+  data-center: datacenter1
+  weight: 2
+`), 0o600))
+
+	updates := make(chan []guber.PeerInfo, 10)
+	pool, err := guber.NewFilePool(guber.FilePoolConfig{
+		Path:         path,
+		OwnAddress:   "10.0.0.1:1051",
+		PollInterval: 10 * clock.Millisecond,
+		OnUpdate:     func(peers []guber.PeerInfo) { updates <- peers },
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, []guber.PeerInfo{
+		{GRPCAddress: "10.0.0.1:1051", HTTPAddress: "10.0.0.1:1050", IsOwner: true},
+		{GRPCAddress: "10.0.0.2:1051", HTTPAddress: "10.0.0.2:1050", DataCenter: "datacenter1", Weight: 2},
+	}, nextUpdate(t, updates))
+
+	// JSON is valid YAML
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"grpc-address": "10.0.0.1:1051", "http-address": "10.0.0.1:1050"},
+		{"grpc-address": "10.0.0.3:1051"}
+	]`), 0o600))
+	assert.Equal(t, []guber.PeerInfo{
+		{GRPCAddress: "10.0.0.1:1051", HTTPAddress: "10.0.0.1:1050", IsOwner: true},
+		{GRPCAddress: "10.0.0.3:1051"},
+	}, nextUpdate(t, updates))
+
+	// The same peers in a different format is not an update
+	require.NoError(t, os.WriteFile(path, []byte(`
+- {grpc-address: 10.0.0.1:1051, http-address: 10.0.0.1:1050}
+- {grpc-address: 10.0.0.3:1051}
+`), 0o600))
+	time.Sleep(50 * time.Millisecond)
+
+	// An invalid file keeps the last valid peers
+	require.NoError(t, os.WriteFile(path, []byte(`
+- grpc-address: 10.0.0.1:1051
+- grpc-address: 10.0.0.1:1051
+`), 0o600))
+	select {
+	case peers := <-updates:
+		t.Fatalf("unexpected update %v", peers)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+- grpc-address: 10.0.0.4:1051
+`), 0o600))
+	assert.Equal(t, []guber.PeerInfo{{GRPCAddress: "10.0.0.4:1051"}}, nextUpdate(t, updates))
+}
+
+func TestFilePoolInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "Empty",
+			data: ``,
+			err:  "no peers listed",
+		},
+		{
+			name: "NotAList",
+			data: `grpc-address: 10.0.0.1:1051`,
+			err:  "cannot unmarshal object",
+		},
+		{
+			name: "UnknownField",
+			data: `[{grpc-address: 10.0.0.1:1051, grpc: 10.0.0.1:1051}]`,
+			err:  `unknown field "grpc"`,
+		},
+		{
+			name: "MissingGRPCAddress",
+			data: `[{http-address: 10.0.0.1:1050}]`,
+			err:  "peer 0 has no grpc-address",
+		},
+		{
+			name: "InvalidGRPCAddress",
+			data: `[{grpc-address: 10.0.0.1}]`,
+			err:  "grpc-address '10.0.0.1' is invalid",
+		},
+		{
+			name: "InvalidHTTPAddress",
+			data: `[{grpc-address: 10.0.0.1:1051, http-address: 10.0.0.1}]`,
+			err:  "http-address '10.0.0.1' is invalid",
+		},
+		{
+			name: "NegativeWeight",
+			data: `[{grpc-address: 10.0.0.1:1051, weight: -1}]`,
+			err:  "weight '-1' of peer '10.0.0.1:1051' is invalid",
+		},
+		{
+			name: "WeightTooLarge",
+			data: `[{grpc-address: 10.0.0.1:1051, weight: 101}]`,
+			err:  "weight '101' of peer '10.0.0.1:1051' is invalid",
+		},
+		{
+			name: "Duplicate",
+			data: `[{grpc-address: 10.0.0.1:1051}, {grpc-address: 10.0.0.1:1051}]`,
+			err:  "peer '10.0.0.1:1051' is listed more than once",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peers.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+
+			_, err := guber.NewFilePool(guber.FilePoolConfig{
+				Path:       path,
+				OwnAddress: "10.0.0.1:1051",
+				OnUpdate:   func([]guber.PeerInfo) {},
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func nextUpdate(t *testing.T, updates chan []guber.PeerInfo) []guber.PeerInfo {
+	t.Helper()
+	select {
+	case peers := <-updates:
+		return peers
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the peers to update")
+		return nil
+	}
+}
@@ -21,9 +21,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"net/http"
 	"os"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -43,7 +45,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/maps"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	json "google.golang.org/protobuf/encoding/protojson"
 )
 
@@ -1340,6 +1344,320 @@ func TestGlobalResetRemaining(t *testing.T) {
 	assert.NotEqual(t, 100, resp.Responses[0].Remaining)
 }
 
+func TestGlobalInterest(t *testing.T) {
+	name := t.Name()
+	key := guber.RandomString(10)
+
+	// Spawn daemons which only broadcast updates to the peers interested in them
+	var daemons []*guber.Daemon
+	var peers []guber.PeerInfo
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		d, err := guber.SpawnDaemon(ctx, guber.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9390+i),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9380+i),
+			AdvertiseAddress:  fmt.Sprintf("127.0.0.1:%d", 9390+i),
+			Behaviors: guber.BehaviorConfig{
+				GlobalSyncWait:    clock.Millisecond * 50,
+				GlobalInterestTTL: clock.Minute,
+			},
+		})
+		cancel()
+		require.NoError(t, err)
+		defer d.Close()
+		daemons = append(daemons, d)
+		peers = append(peers, guber.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress})
+	}
+	for _, d := range daemons {
+		var infos []guber.PeerInfo
+		for _, p := range peers {
+			p.IsOwner = p.GRPCAddress == d.Config().GRPCListenAddress
+			infos = append(infos, p)
+		}
+		d.SetPeers(infos)
+	}
+
+	sendHit := func(d *guber.Daemon, hits int64) *guber.RateLimitResp {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_GLOBAL,
+					Duration:  guber.Minute * 3,
+					Hits:      hits,
+					Limit:     5,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "", resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	// Find the owner of the rate limit. If daemons[0] doesn't own it, it is the first non-owner, which
+	// is interested in the rate limit first anyway. The owner doesn't report itself as the owner.
+	var owner *guber.Daemon
+	var nonOwners []*guber.Daemon
+	ownerAddr := sendHit(daemons[0], 0).Metadata["owner"]
+	if ownerAddr == "" {
+		ownerAddr = daemons[0].Config().GRPCListenAddress
+	}
+	for _, d := range daemons {
+		if d.Config().GRPCListenAddress == ownerAddr {
+			owner = d
+		} else {
+			nonOwners = append(nonOwners, d)
+		}
+	}
+	require.NotNil(t, owner)
+	require.Len(t, nonOwners, 2)
+
+	peerUpdates := func(typ string) float64 {
+		metricsURL := fmt.Sprintf("http://%s/metrics", owner.Config().HTTPListenAddress)
+		m, err := getMetricRequest(metricsURL, fmt.Sprintf(`gubernator_broadcast_peer_update_count{type=%q}`, typ))
+		require.NoError(t, err)
+		if m == nil {
+			return 0
+		}
+		return float64(m.Value)
+	}
+
+	// Only the peer which sent the hit receives the update
+	assert.Equal(t, int64(4), sendHit(nonOwners[0], 1).Remaining)
+	require.NoError(t, waitForBroadcast(clock.Second*3, owner, 1))
+	skipped := peerUpdates("skipped")
+	assert.GreaterOrEqual(t, skipped, float64(1))
+	assert.GreaterOrEqual(t, peerUpdates("sent"), float64(1))
+
+	// The other peer answers from its own state until its check registers its interest with the owner
+	assert.Equal(t, int64(5), sendHit(nonOwners[1], 0).Remaining)
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.Equal(t, int64(4), sendHit(nonOwners[1], 0).Remaining)
+	})
+
+	// Both peers are now interested, so no updates are skipped
+	sendHit(nonOwners[0], 1)
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.Equal(t, int64(3), sendHit(nonOwners[1], 0).Remaining)
+	})
+	assert.Equal(t, skipped, peerUpdates("skipped"))
+}
+
+func TestGlobalCRDT(t *testing.T) {
+	// Start at the beginning of a window, such that the window doesn't end during the test
+	now := clock.Now().Truncate(clock.Minute)
+	defer clock.Freeze(now).Unfreeze()
+	name := t.Name()
+	key := guber.RandomString(10)
+
+	// Peers only gossip to the peers in their own data center
+	var daemons []*guber.Daemon
+	for i := 0; i < cluster.NumOfDaemons(); i++ {
+		if cluster.PeerAt(i).DataCenter == cluster.DataCenterNone {
+			daemons = append(daemons, cluster.DaemonAt(i))
+		}
+	}
+
+	sendHit := func(client guber.V1Client, hits int64) *guber.RateLimitResp {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_GLOBAL_CRDT,
+					Duration:  guber.Minute,
+					Hits:      hits,
+					Limit:     100,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "", resp.Responses[0].GetError())
+		return resp.Responses[0]
+	}
+
+	// waitForRemaining waits until every peer has received the hits of every other peer
+	waitForRemaining := func(remaining int64) {
+		for _, d := range daemons {
+			assert.Eventually(t, func() bool {
+				return sendHit(d.MustClient(), 0).Remaining == remaining
+			}, clock.Second*10, clock.Millisecond*10, d.GRPCListeners[0].Addr().String())
+		}
+	}
+
+	// Every peer allows the hits it receives without asking the other peers
+	for _, d := range daemons {
+		resp := sendHit(d.MustClient(), 10)
+		assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Status)
+		assert.Equal(t, now.Add(clock.Minute).UnixMilli(), resp.ResetTime)
+	}
+	waitForRemaining(100 - 10*int64(len(daemons)))
+
+	// Hits sent to every peer concurrently are all counted
+	var wg sync.WaitGroup
+	for _, d := range daemons {
+		wg.Add(1)
+		go func(d *guber.Daemon) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				sendHit(d.MustClient(), 1)
+			}
+		}(d)
+	}
+	wg.Wait()
+	remaining := 100 - 15*int64(len(daemons))
+	waitForRemaining(remaining)
+
+	// A peer which knows the hits of every other peer rejects hits beyond the limit
+	resp := sendHit(daemons[0].MustClient(), remaining+1)
+	assert.Equal(t, guber.Status_OVER_LIMIT, resp.Status)
+	assert.Equal(t, remaining, resp.Remaining)
+	resp = sendHit(daemons[0].MustClient(), remaining)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Status)
+	assert.Equal(t, int64(0), resp.Remaining)
+	waitForRemaining(0)
+	for _, d := range daemons {
+		assert.Equal(t, guber.Status_OVER_LIMIT, sendHit(d.MustClient(), 1).Status)
+	}
+
+	// Every peer resets the rate limit when the window ends
+	clock.Advance(clock.Minute)
+	for _, d := range daemons {
+		resp := sendHit(d.MustClient(), 0)
+		assert.Equal(t, int64(100), resp.Remaining)
+		assert.Equal(t, now.Add(2*clock.Minute).UnixMilli(), resp.ResetTime)
+	}
+	sendHit(daemons[1].MustClient(), 30)
+	waitForRemaining(70)
+}
+
+func TestGlobalCRDTUnsupported(t *testing.T) {
+	client := cluster.DaemonAt(0).MustClient()
+
+	for _, tc := range []struct {
+		name      string
+		algorithm guber.Algorithm
+		behavior  guber.Behavior
+		hits      int64
+		duration  int64
+		err       string
+	}{{
+		name:      "Leaky bucket",
+		algorithm: guber.Algorithm_LEAKY_BUCKET,
+		duration:  guber.Minute,
+		hits:      1,
+		err:       "GLOBAL_CRDT behavior only supports the TOKEN_BUCKET algorithm",
+	}, {
+		name:     "Reset remaining",
+		behavior: guber.Behavior_RESET_REMAINING,
+		duration: guber.Minute,
+		err:      "GLOBAL_CRDT behavior does not support RESET_REMAINING",
+	}, {
+		name:     "Negative hits",
+		duration: guber.Minute,
+		hits:     -1,
+		err:      "GLOBAL_CRDT behavior does not support negative hits",
+	}, {
+		name: "Missing duration",
+		hits: 1,
+		err:  "GLOBAL_CRDT behavior requires a 'duration' greater than 0",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+			defer cancel()
+			resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      t.Name(),
+						UniqueKey: guber.RandomString(10),
+						Algorithm: tc.algorithm,
+						Behavior:  guber.Behavior_GLOBAL_CRDT | tc.behavior,
+						Duration:  tc.duration,
+						Hits:      tc.hits,
+						Limit:     100,
+					},
+				},
+			})
+			require.NoError(t, err)
+			assert.Contains(t, resp.Responses[0].Error, tc.err)
+		})
+	}
+}
+
+func TestGlobalCRDTRestart(t *testing.T) {
+	name := t.Name()
+	key := guber.RandomString(10)
+
+	spawn := func(i int) *guber.Daemon {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		d, err := guber.SpawnDaemon(ctx, guber.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9190+i),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9180+i),
+			AdvertiseAddress:  fmt.Sprintf("127.0.0.1:%d", 9190+i),
+			Behaviors: guber.BehaviorConfig{
+				GlobalSyncWait:             clock.Millisecond * 50,
+				GlobalCRDTFullSyncInterval: clock.Millisecond * 200,
+			},
+		})
+		require.NoError(t, err)
+		d.SetPeers([]guber.PeerInfo{
+			{GRPCAddress: "127.0.0.1:9190", IsOwner: i == 0},
+			{GRPCAddress: "127.0.0.1:9191", IsOwner: i == 1},
+		})
+		return d
+	}
+	a := spawn(0)
+	defer a.Close()
+	b := spawn(1)
+
+	sendHit := func(d *guber.Daemon, hits int64) *guber.RateLimitResp {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_GLOBAL_CRDT,
+					Duration:  guber.Minute * 60 * 24,
+					Hits:      hits,
+					Limit:     100,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "", resp.Responses[0].GetError())
+		return resp.Responses[0]
+	}
+
+	sendHit(a, 10)
+	assert.Eventually(t, func() bool {
+		return sendHit(b, 0).Remaining == 90
+	}, clock.Second*10, clock.Millisecond*10)
+
+	// A restarted peer learns the hits counted before it restarted, although the rate limit
+	// receives no more hits.
+	b.Close()
+	b = spawn(1)
+	defer b.Close()
+	assert.Eventually(t, func() bool {
+		return sendHit(b, 0).Remaining == 90
+	}, clock.Second*10, clock.Millisecond*10)
+}
+
 func TestChangeLimit(t *testing.T) {
 	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
 	require.NoError(t, err)
@@ -1532,6 +1850,213 @@ func TestHealthCheck(t *testing.T) {
 	require.NoError(t, startGubernator())
 }
 
+func TestHealthCheckCapabilities(t *testing.T) {
+	for _, d := range cluster.GetDaemons() {
+		testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+			resp, err := d.MustClient().HealthCheck(context.Background(), &guber.HealthCheckReq{})
+			require.NoError(t, err)
+
+			// Every peer runs the same version, so every capability is negotiated with every peer
+			assert.Equal(t, []string{
+				guber.CapabilityPeerStream,
+				guber.CapabilityGlobalInterest,
+				guber.CapabilityGlobalCRDT,
+				guber.CapabilityPeerLoad,
+				guber.CapabilityHandoff,
+				guber.CapabilityCompression,
+				guber.CapabilityPeerLoadShed,
+			}, resp.Capabilities)
+			assert.Len(t, resp.PeerCapabilities, len(d.Peers()))
+			for _, peer := range resp.PeerCapabilities {
+				assert.True(t, peer.Negotiated, peer.GrpcAddress)
+				assert.Equal(t, uint32(guber.PeerProtocolVersion), peer.ProtocolVersion)
+				assert.Equal(t, resp.Capabilities, peer.Capabilities)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	name := t.Name()
+
+	var daemons []*guber.Daemon
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		d, err := guber.SpawnDaemon(ctx, guber.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9290+i),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9280+i),
+			AdvertiseAddress:  fmt.Sprintf("127.0.0.1:%d", 9290+i),
+			DrainTimeout:      clock.Second * 10,
+			Behaviors: guber.BehaviorConfig{
+				DrainIdleWait: clock.Second * 2,
+			},
+		})
+		cancel()
+		require.NoError(t, err)
+		defer d.Close()
+		daemons = append(daemons, d)
+	}
+	draining, remaining := daemons[0], daemons[1]
+	peers := []guber.PeerInfo{
+		{GRPCAddress: draining.Config().GRPCListenAddress},
+		{GRPCAddress: remaining.Config().GRPCListenAddress},
+	}
+	draining.SetPeers(peers)
+	remaining.SetPeers(peers)
+
+	// Find rate limits owned by the instance which drains
+	findKey := func() string {
+		for {
+			key := guber.RandomString(10)
+			peer, err := draining.V1Server.GetPeer(context.Background(), name+"_"+key)
+			require.NoError(t, err)
+			if peer.Info().IsOwner {
+				return key
+			}
+		}
+	}
+	key, mergedKey := findKey(), findKey()
+
+	sendHit := func(key string, hits int64) *guber.RateLimitResp {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		client, err := guber.DialV1Server(remaining.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Minute * 3,
+					Hits:      hits,
+					Limit:     5,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		require.Equal(t, "", resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	assert.Equal(t, int64(3), sendHit(key, 2).Remaining)
+	assert.Equal(t, int64(2), sendHit(mergedKey, 3).Remaining)
+
+	// The rate limit is only handed off once the handshake with the next owner completed
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		for _, peer := range draining.V1Server.GetPeerList() {
+			if !peer.Info().IsOwner {
+				assert.True(t, peer.HasCapability(guber.CapabilityHandoff))
+			}
+		}
+	})
+
+	closed := make(chan struct{})
+	go func() {
+		draining.Close()
+		close(closed)
+	}()
+
+	// The draining instance reports it is not ready
+	client, err := guber.DialV1Server(draining.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		_, err := client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "this instance is draining")
+	})
+
+	// Requests forwarded to the draining instance are still served
+	assert.Equal(t, int64(2), sendHit(key, 1).Remaining)
+
+	// Once the peers stop routing requests to the draining instance, it hands off the rate
+	// limits to their next owner and exits
+	remaining.SetPeers(peers[1:])
+
+	// A rate limit the next owner received requests for before the handoff is merged with the
+	// handed off rate limit, which counts the hits of both
+	assert.Equal(t, int64(4), sendHit(mergedKey, 1).Remaining)
+	select {
+	case <-closed:
+	case <-time.After(clock.Second * 10):
+		t.Fatal("timed out waiting for the instance to drain")
+	}
+	assert.Equal(t, int64(2), sendHit(key, 0).Remaining)
+	assert.Equal(t, int64(1), sendHit(mergedKey, 0).Remaining)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	name := t.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+	d, err := guber.SpawnDaemon(ctx, guber.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9295",
+		HTTPListenAddress: "127.0.0.1:9285",
+		AdvertiseAddress:  "127.0.0.1:9295",
+		Behaviors: guber.BehaviorConfig{
+			DisableBatching:           true,
+			PeerCircuitBreaker:        true,
+			CircuitBreakerMinRequests: 2,
+			CircuitBreakerOpenWait:    clock.Minute,
+		},
+	})
+	cancel()
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Nothing listens on the address of the other peer, so every request forwarded to it fails
+	d.SetPeers([]guber.PeerInfo{
+		{GRPCAddress: d.Config().GRPCListenAddress},
+		{GRPCAddress: "127.0.0.1:9296"},
+	})
+
+	var key string
+	for {
+		key = guber.RandomString(10)
+		peer, err := d.V1Server.GetPeer(context.Background(), name+"_"+key)
+		require.NoError(t, err)
+		if !peer.Info().IsOwner {
+			break
+		}
+	}
+
+	client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	sendHit := func() *guber.RateLimitResp {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+		defer cancel()
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Minute,
+					Hits:      1,
+					Limit:     10,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		return resp.Responses[0]
+	}
+
+	for i := 0; i < 2; i++ {
+		rl := sendHit()
+		assert.NotEmpty(t, rl.Error)
+		assert.NotContains(t, rl.Error, "circuit breaker is open")
+	}
+
+	// While the breaker is open, requests fail without being forwarded
+	assert.Contains(t, sendHit().Error, "circuit breaker is open")
+
+	_, err = client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit breaker of local peer '127.0.0.1:9296' is open")
+}
+
 func TestLeakyBucketDivBug(t *testing.T) {
 	defer clock.Freeze(clock.Now()).Unfreeze()
 	name := t.Name()
@@ -2170,8 +2695,359 @@ func TestEventChannel(t *testing.T) {
 	})
 }
 
+func TestLeaseTokens(t *testing.T) {
+	name := t.Name()
+	key := guber.RandomString(10)
+	ctx := context.Background()
+
+	// Send requests to a peer that does not own the rate limit such that
+	// lease requests are forwarded to the owner.
+	daemons, err := cluster.ListNonOwningDaemons(name, key)
+	require.NoError(t, err)
+	client, err := guber.DialV1Server(daemons[0].PeerInfo.GRPCAddress, nil)
+	require.NoError(t, err)
+
+	rateLimit := func(algorithm guber.Algorithm) *guber.RateLimitReq {
+		return &guber.RateLimitReq{
+			Name:      name,
+			UniqueKey: key + algorithm.String(),
+			Algorithm: algorithm,
+			// Long enough that the leaky bucket does not leak during the test
+			Duration: guber.Minute * 60,
+			Limit:    100,
+		}
+	}
+	lease := func(t *testing.T, req *guber.LeaseReq) *guber.LeaseResp {
+		t.Helper()
+		resp, err := client.LeaseTokens(ctx, &guber.LeaseTokensReq{
+			Requests: []*guber.LeaseReq{req},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+	remaining := func(t *testing.T, req *guber.RateLimitReq) int64 {
+		t.Helper()
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0].Remaining
+	}
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			now := epochMillis(clock.Now())
+			first := lease(t, &guber.LeaseReq{RateLimit: rateLimit(algorithm), Tokens: 5})
+			assert.NotEmpty(t, first.LeaseId)
+			assert.Equal(t, int64(5), first.Tokens)
+			assert.Equal(t, int64(95), first.RateLimit.Remaining)
+			assert.Greater(t, first.ExpireAt, now)
+			assert.Equal(t, int64(95), remaining(t, rateLimit(algorithm)))
+
+			// Return unused tokens while leasing more
+			second := lease(t, &guber.LeaseReq{
+				RateLimit:     rateLimit(algorithm),
+				Tokens:        10,
+				ReturnLeaseId: first.LeaseId,
+				Returned:      3,
+			})
+			assert.Equal(t, int64(10), second.Tokens)
+			assert.Equal(t, int64(88), second.RateLimit.Remaining)
+
+			// Tokens of a lease can only be returned once
+			resp := lease(t, &guber.LeaseReq{
+				RateLimit:     rateLimit(algorithm),
+				ReturnLeaseId: first.LeaseId,
+				Returned:      2,
+			})
+			assert.Empty(t, resp.LeaseId)
+			assert.Equal(t, int64(0), resp.Tokens)
+			assert.Equal(t, int64(88), resp.RateLimit.Remaining)
+
+			// Cannot return more tokens than were leased
+			resp = lease(t, &guber.LeaseReq{
+				RateLimit:     rateLimit(algorithm),
+				ReturnLeaseId: second.LeaseId,
+				Returned:      50,
+			})
+			assert.Equal(t, int64(98), resp.RateLimit.Remaining)
+
+			// Grants no more than the remaining tokens
+			resp = lease(t, &guber.LeaseReq{RateLimit: rateLimit(algorithm), Tokens: 1000})
+			assert.Equal(t, int64(98), resp.Tokens)
+			assert.Equal(t, int64(0), resp.RateLimit.Remaining)
+			assert.Equal(t, guber.Status_OVER_LIMIT, func() guber.Status {
+				req := rateLimit(algorithm)
+				req.Hits = 1
+				r, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{req},
+				})
+				require.NoError(t, err)
+				return r.Responses[0].Status
+			}())
+
+			resp = lease(t, &guber.LeaseReq{
+				RateLimit:     rateLimit(algorithm),
+				ReturnLeaseId: resp.LeaseId,
+				Returned:      98,
+			})
+			assert.Equal(t, int64(98), resp.RateLimit.Remaining)
+		})
+	}
+
+	t.Run("Expired lease", func(t *testing.T) {
+		req := rateLimit(guber.Algorithm_TOKEN_BUCKET)
+		req.UniqueKey += "expired"
+		resp := lease(t, &guber.LeaseReq{RateLimit: req, Tokens: 10, Duration: 1})
+		assert.Equal(t, int64(10), resp.Tokens)
+		assert.Equal(t, resp.RateLimit.ResetTime-guber.Minute*60+1, resp.ExpireAt)
+		clock.Sleep(clock.Millisecond * 5)
+
+		// Tokens returned after the lease expired are spent
+		resp = lease(t, &guber.LeaseReq{RateLimit: req, ReturnLeaseId: resp.LeaseId, Returned: 10})
+		assert.Equal(t, int64(90), resp.RateLimit.Remaining)
+	})
+
+	t.Run("Failed lease keeps the lease", func(t *testing.T) {
+		req := rateLimit(guber.Algorithm_TOKEN_BUCKET)
+		req.UniqueKey += "failed"
+		first := lease(t, &guber.LeaseReq{RateLimit: req, Tokens: 10})
+		assert.Equal(t, int64(90), first.RateLimit.Remaining)
+
+		invalid := rateLimit(guber.Algorithm_TOKEN_BUCKET)
+		invalid.UniqueKey = req.UniqueKey
+		invalid.Algorithm = guber.Algorithm(99)
+		resp, err := client.LeaseTokens(ctx, &guber.LeaseTokensReq{
+			Requests: []*guber.LeaseReq{{RateLimit: invalid, ReturnLeaseId: first.LeaseId, Returned: 10}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Responses[0].Error, "Invalid rate limit algorithm")
+
+		// The tokens which were not credited back may be returned again
+		second := lease(t, &guber.LeaseReq{RateLimit: req, ReturnLeaseId: first.LeaseId, Returned: 10})
+		assert.Equal(t, int64(100), second.RateLimit.Remaining)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		global := rateLimit(guber.Algorithm_TOKEN_BUCKET)
+		global.Behavior = guber.Behavior_GLOBAL
+
+		resp, err := client.LeaseTokens(ctx, &guber.LeaseTokensReq{
+			Requests: []*guber.LeaseReq{
+				{Tokens: 1},
+				{RateLimit: &guber.RateLimitReq{Name: name}, Tokens: 1},
+				{RateLimit: rateLimit(guber.Algorithm_TOKEN_BUCKET), Tokens: -1},
+				{RateLimit: global, Tokens: 1},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 4)
+		assert.Equal(t, "field 'rate_limit' cannot be empty", resp.Responses[0].Error)
+		assert.Equal(t, "field 'unique_key' cannot be empty", resp.Responses[1].Error)
+		assert.Equal(t, "fields 'tokens' and 'returned' cannot be negative", resp.Responses[2].Error)
+		assert.Equal(t, "tokens cannot be leased from a rate limit with GLOBAL behavior", resp.Responses[3].Error)
+	})
+}
+
 // Request metrics and parse into map.
 // Optionally pass names to filter metrics by name.
+func TestRequestID(t *testing.T) {
+	name := t.Name()
+	ctx := context.Background()
+
+	send := func(t *testing.T, d *guber.Daemon, req *guber.RateLimitReq) *guber.RateLimitResp {
+		t.Helper()
+		resp, err := d.MustClient().GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	t.Run("Forwarded", func(t *testing.T) {
+		key := guber.RandomString(10)
+		peers, err := cluster.ListNonOwningDaemons(name, key)
+		require.NoError(t, err)
+		req := &guber.RateLimitReq{
+			Name:      name,
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+			RequestId: "request-1",
+		}
+
+		first := send(t, peers[0], req)
+		assert.Equal(t, int64(9), first.Remaining)
+
+		// A retry through any peer receives the original response
+		for _, p := range peers[:2] {
+			resp := send(t, p, req)
+			assert.Equal(t, first.Remaining, resp.Remaining)
+			assert.Equal(t, first.ResetTime, resp.ResetTime)
+		}
+
+		req.RequestId = "request-2"
+		assert.Equal(t, int64(8), send(t, peers[0], req).Remaining)
+		req.RequestId = ""
+		assert.Equal(t, int64(7), send(t, peers[0], req).Remaining)
+		assert.Equal(t, int64(6), send(t, peers[0], req).Remaining)
+	})
+
+	t.Run("Global", func(t *testing.T) {
+		key := guber.RandomString(10)
+		owner, err := cluster.FindOwningDaemon(name, key)
+		require.NoError(t, err)
+		peers, err := cluster.ListNonOwningDaemons(name, key)
+		require.NoError(t, err)
+		req := &guber.RateLimitReq{
+			Name:      name,
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Behavior:  guber.Behavior_GLOBAL,
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+			RequestId: "request-1",
+		}
+
+		assert.Equal(t, int64(9), send(t, peers[0], req).Remaining)
+		assert.Equal(t, int64(9), send(t, peers[0], req).Remaining)
+
+		// A retry on another peer is applied by that peer, but the owner ignores its hits
+		assert.Equal(t, int64(9), send(t, peers[1], req).Remaining)
+
+		// Hits without a `request_id` are aggregated with them and applied once each
+		req.RequestId = ""
+		send(t, peers[0], req)
+		send(t, peers[1], req)
+
+		// Only the first hit of the request and the two hits without an id reach the owner
+		req.Hits = 0
+		testutil.UntilPass(t, 20, clock.Millisecond*200, func(t testutil.TestingT) {
+			resp, err := owner.MustClient().GetRateLimits(ctx, &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{req},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), resp.Responses[0].Remaining)
+		})
+		// No more hits arrive later
+		clock.Sleep(clock.Millisecond * 500)
+		resp, err := owner.MustClient().GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), resp.Responses[0].Remaining)
+	})
+}
+
+func TestGetHotKeys(t *testing.T) {
+	name := t.Name()
+	ctx := context.Background()
+	hotKey, coldKey := guber.RandomString(10), guber.RandomString(10)
+	owner, err := cluster.FindOwningDaemon(name, hotKey)
+	require.NoError(t, err)
+
+	check := func(key string) {
+		_, err := owner.MustClient().GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{{
+				Name:      name,
+				UniqueKey: key,
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     5,
+				Hits:      1,
+			}},
+		})
+		require.NoError(t, err)
+	}
+	for i := 0; i < 20; i++ {
+		check(hotKey)
+	}
+	check(coldKey)
+
+	// findKey returns the hot key for `key` and its rank among the keys of this test
+	findKey := func(keys []*guber.HotKey, key string) (*guber.HotKey, int) {
+		var rank int
+		for _, k := range keys {
+			if k.Name != name {
+				continue
+			}
+			if k.UniqueKey == key {
+				return k, rank
+			}
+			rank++
+		}
+		return nil, -1
+	}
+
+	resp, err := owner.MustClient().GetHotKeys(ctx, &guber.GetHotKeysReq{Limit: 128})
+	require.NoError(t, err)
+
+	// Counts of the Space-Saving algorithm are overestimated by at most `error`
+	k, rank := findKey(resp.Checks, hotKey)
+	require.NotNil(t, k)
+	assert.Equal(t, 0, rank)
+	assert.GreaterOrEqual(t, k.Count, int64(20))
+	assert.LessOrEqual(t, k.Count-k.Error, int64(20))
+
+	k, rank = findKey(resp.OverLimit, hotKey)
+	require.NotNil(t, k)
+	assert.Equal(t, 0, rank)
+	assert.GreaterOrEqual(t, k.Count, int64(15))
+	assert.LessOrEqual(t, k.Count-k.Error, int64(15))
+	k, _ = findKey(resp.OverLimit, coldKey)
+	assert.Nil(t, k)
+
+	// Keys are only tracked by the peer which applied the rate limit
+	peers, err := cluster.ListNonOwningDaemons(name, hotKey)
+	require.NoError(t, err)
+	resp, err = peers[0].MustClient().GetHotKeys(ctx, &guber.GetHotKeysReq{Limit: 128})
+	require.NoError(t, err)
+	k, _ = findKey(resp.Checks, hotKey)
+	assert.Nil(t, k)
+
+	t.Run("Limits", func(t *testing.T) {
+		_, err := owner.MustClient().GetHotKeys(ctx, &guber.GetHotKeysReq{Limit: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// More keys than are tracked returns every tracked key
+		resp, err := owner.MustClient().GetHotKeys(ctx, &guber.GetHotKeysReq{Limit: math.MaxInt32})
+		require.NoError(t, err)
+		k, _ := findKey(resp.Checks, hotKey)
+		assert.NotNil(t, k)
+
+		r, err := http.Get(fmt.Sprintf("http://%s/v1/GetHotKeys?limit=-1", owner.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/v1/GetHotKeys?limit=128", owner.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "unique_key")
+		assert.Contains(t, string(b), "over_limit")
+		assert.Contains(t, string(b), hotKey)
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		m, err := getMetricRequest(fmt.Sprintf("http://%s/metrics", owner.Config().HTTPListenAddress),
+			"gubernator_hot_key_count")
+		require.NoError(t, err)
+		assert.NotNil(t, m)
+	})
+}
+
 func getMetrics(HTTPAddr string, names ...string) (map[string]*model.Sample, error) {
 	url := fmt.Sprintf("http://%s/metrics", HTTPAddr)
 	resp, err := http.Get(url)
@@ -2392,6 +3268,150 @@ func epochMillis(t time.Time) int64 {
 	return t.UnixNano() / 1_000_000
 }
 
+func TestGetPeersOwnership(t *testing.T) {
+	ctx := context.Background()
+	d := cluster.DaemonAt(0)
+
+	// ownership returns the ownership of each peer by address and the total of each data center
+	ownership := func() (map[string]*guber.Peer, map[string]float64) {
+		resp, err := d.MustClient().GetPeers(ctx, &guber.GetPeersReq{})
+		require.NoError(t, err)
+		peers := make(map[string]*guber.Peer)
+		totals := make(map[string]float64)
+		for _, p := range resp.Peers {
+			peers[p.GrpcAddress] = p
+			totals[p.DataCenter] += p.Ownership
+		}
+		return peers, totals
+	}
+
+	peers, totals := ownership()
+	require.Len(t, peers, cluster.NumOfDaemons())
+	for _, p := range peers {
+		assert.Zero(t, p.Weight)
+	}
+	assert.InDelta(t, 1.0, totals[cluster.DataCenterNone], 0.001)
+	assert.InDelta(t, 1.0, totals[cluster.DataCenterOne], 0.001)
+
+	// Weigh a peer in each data center
+	weighted := append([]guber.PeerInfo(nil), cluster.GetPeers()...)
+	defer d.SetPeers(cluster.GetPeers())
+	for i := range weighted {
+		if weighted[i].GRPCAddress == "127.0.0.1:9991" || weighted[i].GRPCAddress == "127.0.0.1:9891" {
+			weighted[i].Weight = 3
+		}
+	}
+	d.SetPeers(weighted)
+
+	peers, totals = ownership()
+	assert.Equal(t, int32(3), peers["127.0.0.1:9991"].Weight)
+	// 6 peers in DataCenterNone, one of which has a weight of 3
+	assert.InDelta(t, 3.0/8.0, peers["127.0.0.1:9991"].Ownership, 0.05)
+	assert.InDelta(t, 1.0/8.0, peers["127.0.0.1:9990"].Ownership, 0.05)
+	// 4 peers in DataCenterOne, one of which has a weight of 3
+	assert.InDelta(t, 3.0/6.0, peers["127.0.0.1:9891"].Ownership, 0.05)
+	assert.InDelta(t, 1.0/6.0, peers["127.0.0.1:9890"].Ownership, 0.05)
+	assert.InDelta(t, 1.0, totals[cluster.DataCenterNone], 0.001)
+	assert.InDelta(t, 1.0, totals[cluster.DataCenterOne], 0.001)
+}
+
+func TestGetTopology(t *testing.T) {
+	ctx := context.Background()
+	d := cluster.DaemonAt(0)
+	name := t.Name()
+	key := guber.RandomString(10)
+	owner, err := cluster.FindOwningDaemon(name, key)
+	require.NoError(t, err)
+
+	resp, err := d.MustClient().GetTopology(ctx, &guber.GetTopologyReq{Name: name, UniqueKey: key})
+	require.NoError(t, err)
+	assert.Len(t, resp.LocalPeers, 6)
+	assert.Len(t, resp.RegionPeers, 4)
+	require.NotNil(t, resp.KeyOwner)
+	assert.Equal(t, owner.PeerInfo.GRPCAddress, resp.KeyOwner.GrpcAddress)
+	assert.NotEmpty(t, resp.RingHash)
+	assert.True(t, resp.RingAgreement)
+	for _, p := range resp.LocalPeers {
+		assert.Equal(t, resp.RingHash, p.RingHash, p.Peer.GrpcAddress)
+		assert.Empty(t, p.RingHashError)
+	}
+
+	// The topology is also available from the HTTP gateway
+	httpResp, err := http.DefaultClient.Get(fmt.Sprintf("http://%s/v1/GetTopology?name=%s&unique_key=%s",
+		d.Config().HTTPListenAddress, name, key))
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	b, err := io.ReadAll(httpResp.Body)
+	require.NoError(t, err)
+	var gwResp guber.GetTopologyResp
+	require.NoError(t, json.Unmarshal(b, &gwResp))
+	assert.Equal(t, resp.KeyOwner.GrpcAddress, gwResp.KeyOwner.GrpcAddress)
+	assert.True(t, gwResp.RingAgreement)
+
+	// A peer with a different view of the cluster is detected
+	weighted := append([]guber.PeerInfo(nil), cluster.GetPeers()...)
+	defer d.SetPeers(cluster.GetPeers())
+	for i := range weighted {
+		if weighted[i].GRPCAddress == "127.0.0.1:9991" {
+			weighted[i].Weight = 3
+		}
+	}
+	d.SetPeers(weighted)
+
+	resp, err = cluster.DaemonAt(1).MustClient().GetTopology(ctx, &guber.GetTopologyReq{})
+	require.NoError(t, err)
+	assert.Nil(t, resp.KeyOwner)
+	assert.False(t, resp.RingAgreement)
+	for _, p := range resp.LocalPeers {
+		if p.Peer.GrpcAddress == d.PeerInfo.GRPCAddress {
+			assert.NotEqual(t, resp.RingHash, p.RingHash)
+		} else {
+			assert.Equal(t, resp.RingHash, p.RingHash)
+		}
+	}
+}
+
+func TestResizeWorkers(t *testing.T) {
+	ctx := context.Background()
+	name := t.Name()
+	key := guber.RandomString(10)
+	owner, err := cluster.FindOwningDaemon(name, key)
+	require.NoError(t, err)
+	// The cluster daemons use the default number of workers
+	defer func() {
+		require.NoError(t, owner.ResizeWorkers(ctx, runtime.NumCPU()))
+	}()
+
+	hit := func(expected int64) {
+		t.Helper()
+		resp, err := owner.MustClient().GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{{
+				Name:      name,
+				UniqueKey: key,
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     10,
+				Hits:      1,
+			}},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		assert.Equal(t, expected, resp.Responses[0].Remaining)
+	}
+
+	// The rate limits are kept while the number of workers changes
+	hit(9)
+	require.NoError(t, owner.ResizeWorkers(ctx, runtime.NumCPU()+3))
+	hit(8)
+	require.NoError(t, owner.ResizeWorkers(ctx, 1))
+	hit(7)
+
+	err = owner.ResizeWorkers(ctx, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid worker count")
+}
+
 func startGubernator() error {
 	err := cluster.StartWith([]guber.PeerInfo{
 		{GRPCAddress: "127.0.0.1:9990", HTTPAddress: "127.0.0.1:9980", DataCenter: cluster.DataCenterNone},
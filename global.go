@@ -18,13 +18,21 @@ package gubernator
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/syncutil"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
+// The number of times sendHits retries sending hits to an owning peer which timed out
+const globalSendRetries = 1
+
 // globalManager manages async hit queue and updates peers in
 // the cluster periodically when a global rate limit we own updates.
 type globalManager struct {
@@ -38,6 +46,40 @@ type globalManager struct {
 	metricGlobalSendQueueLength prometheus.Gauge
 	metricBroadcastDuration     prometheus.Summary
 	metricGlobalQueueLength     prometheus.Gauge
+	metricBroadcastPeerUpdates  *prometheus.CounterVec
+	// The peers interested in updates of each rate limit we own, nil if GlobalInterestTTL is not set
+	interest *globalInterest
+	// When we last sent hits or checks of each rate limit to the owner. GUARDED_BY(runAsyncHits)
+	interestSent map[string]time.Time
+	// The sequence of the last batch of hits sent to the owners. Starts at the time the instance
+	// started, such that the batches of a restarted instance keep increasing. GUARDED_BY(runAsyncHits)
+	sequence uint64
+	// The last batch of hits applied from each peer
+	batches *globalBatches
+}
+
+// globalBatches tracks the last batch of aggregated hits applied from each peer, such that the
+// hits of a retried batch which already reached this instance are not applied again
+type globalBatches struct {
+	mutex  sync.Mutex
+	window int64
+	peers  map[string]*globalBatch // GUARDED_BY(mutex)
+}
+
+type globalBatch struct {
+	sequence uint64
+	// The keys of the rate limits whose hits were applied
+	applied map[string]struct{}
+	// When the peer last sent a new batch, in Epoch milliseconds
+	updatedAt int64
+}
+
+// globalInterest tracks the peers which recently sent hits or checks for each global rate limit
+type globalInterest struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	peers    map[string]map[string]time.Time // GUARDED_BY(mutex)
+	prunedAt time.Time                       // GUARDED_BY(mutex)
 }
 
 func newGlobalManager(conf BehaviorConfig, instance *V1Instance) *globalManager {
@@ -65,6 +107,22 @@ func newGlobalManager(conf BehaviorConfig, instance *V1Instance) *globalManager
 			Name: "gubernator_global_queue_length",
 			Help: "The count of requests queued up for global broadcast.  This is only used for GetRateLimit requests using global behavior.",
 		}),
+		metricBroadcastPeerUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gubernator_broadcast_peer_update_count",
+			Help: "The count of global rate limit updates broadcast to each peer.  Label \"type\" may be \"sent\" for updates sent to a peer, or \"skipped\" for updates not sent to a peer which did not recently send hits or checks for the rate limit, see GUBER_GLOBAL_INTEREST_TTL.",
+		}, []string{"type"}),
+		interestSent: make(map[string]time.Time),
+		sequence:     uint64(clock.Now().UnixNano()),
+		batches: &globalBatches{
+			window: conf.RequestIDWindow.Milliseconds(),
+			peers:  make(map[string]*globalBatch),
+		},
+	}
+	if conf.GlobalInterestTTL > 0 {
+		gm.interest = &globalInterest{
+			ttl:   conf.GlobalInterestTTL,
+			peers: make(map[string]map[string]time.Time),
+		}
 	}
 	gm.runAsyncHits()
 	gm.runBroadcasts()
@@ -72,11 +130,23 @@ func newGlobalManager(conf BehaviorConfig, instance *V1Instance) *globalManager
 }
 
 func (gm *globalManager) QueueHit(r *RateLimitReq) {
-	if r.Hits != 0 {
+	// Checks without hits are sent to the owner to keep our interest in updates of the rate limit
+	if r.Hits != 0 || gm.interest != nil {
 		gm.hitsQueue <- r
 	}
 }
 
+// AddInterest records that the peer is interested in updates of a rate limit we own. A peer which
+// wasn't interested before is sent the current status of the rate limit with the next broadcast.
+func (gm *globalManager) AddInterest(r *RateLimitReq, peerAddress string) {
+	if gm.interest == nil {
+		return
+	}
+	if gm.interest.add(r.HashKey(), peerAddress) {
+		gm.broadcastQueue <- r
+	}
+}
+
 func (gm *globalManager) QueueUpdate(req *RateLimitReq) {
 	if req.Hits != 0 {
 		gm.broadcastQueue <- req
@@ -91,6 +161,7 @@ func (gm *globalManager) QueueUpdate(req *RateLimitReq) {
 func (gm *globalManager) runAsyncHits() {
 	var interval = NewInterval(gm.conf.GlobalSyncWait)
 	hits := make(map[string]*RateLimitReq)
+	aggregated := make(map[string][]*AggregatedRequest)
 
 	gm.wg.Until(func(done chan struct{}) bool {
 
@@ -98,7 +169,25 @@ func (gm *globalManager) runAsyncHits() {
 		case r := <-gm.hitsQueue:
 			// Aggregate the hits into a single request
 			key := r.HashKey()
+			if r.Hits != 0 && r.RequestId != "" {
+				// The owner ignores the hits of a request with a `request_id` it already applied,
+				// such as when the client retried the request on another peer
+				aggregated[key] = append(aggregated[key], &AggregatedRequest{
+					Key:       key,
+					RequestId: r.RequestId,
+					Hits:      r.Hits,
+				})
+				r.RequestId = ""
+			}
 			_, ok := hits[key]
+			if r.Hits == 0 {
+				// A check without hits is only sent to keep our interest in updates of the rate limit
+				if ok || !gm.interestExpiring(key) {
+					return true
+				}
+				r = proto.Clone(r).(*RateLimitReq)
+				SetBehavior(&r.Behavior, Behavior_RESET_REMAINING, false)
+			}
 			if ok {
 				// If any of our hits includes a request to RESET_REMAINING
 				// ensure the owning peer gets this behavior
@@ -113,8 +202,9 @@ func (gm *globalManager) runAsyncHits() {
 
 			// Send the hits if we reached our batch limit
 			if len(hits) == gm.conf.GlobalBatchLimit {
-				gm.sendHits(hits)
+				gm.sendHits(hits, aggregated)
 				hits = make(map[string]*RateLimitReq)
+				aggregated = make(map[string][]*AggregatedRequest)
 				gm.metricGlobalSendQueueLength.Set(0)
 				return true
 			}
@@ -127,8 +217,9 @@ func (gm *globalManager) runAsyncHits() {
 
 		case <-interval.C:
 			if len(hits) != 0 {
-				gm.sendHits(hits)
+				gm.sendHits(hits, aggregated)
 				hits = make(map[string]*RateLimitReq)
+				aggregated = make(map[string][]*AggregatedRequest)
 				gm.metricGlobalSendQueueLength.Set(0)
 			}
 		case <-done:
@@ -140,41 +231,57 @@ func (gm *globalManager) runAsyncHits() {
 }
 
 // sendHits takes the hits collected by runAsyncHits and sends them to their
-// owning peers
-func (gm *globalManager) sendHits(hits map[string]*RateLimitReq) {
+// owning peers, along with the client requests with a `request_id` aggregated in them
+func (gm *globalManager) sendHits(hits map[string]*RateLimitReq, aggregated map[string][]*AggregatedRequest) {
 	type pair struct {
 		client *PeerClient
 		req    GetPeerRateLimitsReq
 	}
 	defer prometheus.NewTimer(gm.metricGlobalSendDuration).ObserveDuration()
 	peerRequests := make(map[string]*pair)
+	peerAddress := gm.instance.conf.AdvertiseAddr
+	// The owner uses the sequence to ignore the hits if we retry a batch which already reached it
+	gm.sequence++
 
 	// Assign each request to a peer
-	for _, r := range hits {
-		peer, err := gm.instance.GetPeer(context.Background(), r.HashKey())
+	for key, r := range hits {
+		peer, err := gm.instance.GetPeer(context.Background(), key)
 		if err != nil {
-			gm.log.WithError(err).Errorf("while getting peer for hash key '%s'", r.HashKey())
+			gm.log.WithError(err).Errorf("while getting peer for hash key '%s'", key)
 			continue
 		}
 		p, ok := peerRequests[peer.Info().GRPCAddress]
-		if ok {
-			p.req.Requests = append(p.req.Requests, r)
-		} else {
-			peerRequests[peer.Info().GRPCAddress] = &pair{
+		if !ok {
+			p = &pair{
 				client: peer,
-				req:    GetPeerRateLimitsReq{Requests: []*RateLimitReq{r}},
+				req: GetPeerRateLimitsReq{
+					PeerAddress: peerAddress,
+					Sequence:    gm.sequence,
+				},
 			}
+			peerRequests[peer.Info().GRPCAddress] = p
 		}
+		p.req.Requests = append(p.req.Requests, r)
+		p.req.AggregatedRequests = append(p.req.AggregatedRequests, aggregated[key]...)
 	}
+	gm.markInterestSent(hits)
 
 	fan := syncutil.NewFanOut(gm.conf.GlobalPeerRequestsConcurrency)
 	// Send the rate limit requests to their respective owning peers.
 	for _, p := range peerRequests {
 		fan.Run(func(in interface{}) error {
 			p := in.(*pair)
-			ctx, cancel := context.WithTimeout(context.Background(), gm.conf.GlobalTimeout)
-			_, err := p.client.GetPeerRateLimits(ctx, &p.req)
-			cancel()
+			var err error
+			for attempt := 0; attempt <= globalSendRetries; attempt++ {
+				ctx, cancel := context.WithTimeout(context.Background(), gm.conf.GlobalTimeout)
+				_, err = p.client.GetPeerRateLimits(ctx, &p.req)
+				cancel()
+
+				// Retrying is safe as the owner ignores hits it has already applied
+				if status.Code(err) != codes.DeadlineExceeded {
+					break
+				}
+			}
 
 			if err != nil {
 				gm.log.WithError(err).
@@ -264,10 +371,27 @@ func (gm *globalManager) broadcastPeers(ctx context.Context, updates map[string]
 			continue
 		}
 
+		// Only send the updates the peer is interested in. Peers which don't send their address with
+		// their hits are sent every update.
+		peerReq := &req
+		if gm.interest != nil && peer.HasCapability(CapabilityGlobalInterest) {
+			peerReq = &UpdatePeerGlobalsReq{}
+			for _, g := range req.Globals {
+				if gm.interest.interested(g.Key, peer.Info().GRPCAddress) {
+					peerReq.Globals = append(peerReq.Globals, g)
+				}
+			}
+		}
+		gm.metricBroadcastPeerUpdates.WithLabelValues("sent").Add(float64(len(peerReq.Globals)))
+		gm.metricBroadcastPeerUpdates.WithLabelValues("skipped").Add(float64(len(req.Globals) - len(peerReq.Globals)))
+		if len(peerReq.Globals) == 0 {
+			continue
+		}
+
 		fan.Run(func(in interface{}) error {
 			peer := in.(*PeerClient)
 			ctx, cancel := context.WithTimeout(ctx, gm.conf.GlobalTimeout)
-			_, err := peer.UpdatePeerGlobals(ctx, &req)
+			_, err := peer.UpdatePeerGlobals(ctx, peerReq)
 			cancel()
 
 			if err != nil {
@@ -282,6 +406,115 @@ func (gm *globalManager) broadcastPeers(ctx context.Context, updates map[string]
 	fan.Wait()
 }
 
+// interestExpiring returns true if the owner of the rate limit should be sent a check to keep our
+// interest in its updates, which we do once half of GlobalInterestTTL has passed since the last one.
+func (gm *globalManager) interestExpiring(key string) bool {
+	sentAt, ok := gm.interestSent[key]
+	return !ok || clock.Since(sentAt) >= gm.interest.ttl/2
+}
+
+// markInterestSent records when the hits or checks of each rate limit were sent to their owners
+func (gm *globalManager) markInterestSent(hits map[string]*RateLimitReq) {
+	if gm.interest == nil {
+		return
+	}
+	now := clock.Now()
+	for key := range hits {
+		gm.interestSent[key] = now
+	}
+	for key, sentAt := range gm.interestSent {
+		if now.Sub(sentAt) >= gm.interest.ttl {
+			delete(gm.interestSent, key)
+		}
+	}
+}
+
+// ClaimBatchHits returns true if the hits of the rate limit in a batch of aggregated hits from the
+// peer should be applied, and records that they were. Returns false for a retried batch which
+// already reached us, or a batch older than the last batch of the peer.
+func (gm *globalManager) ClaimBatchHits(peerAddress string, sequence uint64, key string) bool {
+	if peerAddress == "" || sequence == 0 {
+		return true
+	}
+	return gm.batches.claim(peerAddress, sequence, key, MillisecondNow())
+}
+
+func (gb *globalBatches) claim(peerAddress string, sequence uint64, key string, now int64) bool {
+	gb.mutex.Lock()
+	defer gb.mutex.Unlock()
+
+	b, ok := gb.peers[peerAddress]
+	if ok && sequence < b.sequence {
+		return false
+	}
+	if !ok || sequence > b.sequence {
+		// Forget the peers which sent no batch within the window, such as peers which left
+		for addr, b := range gb.peers {
+			if b.updatedAt+gb.window <= now {
+				delete(gb.peers, addr)
+			}
+		}
+		b = &globalBatch{sequence: sequence, applied: make(map[string]struct{}), updatedAt: now}
+		gb.peers[peerAddress] = b
+	}
+	if _, ok := b.applied[key]; ok {
+		return false
+	}
+	b.applied[key] = struct{}{}
+	return true
+}
+
+// add records the interest of the peer in the rate limit for the next TTL, and returns true if
+// the peer wasn't already interested. An empty peer address is interested in every rate limit.
+func (gi *globalInterest) add(key, peerAddress string) bool {
+	gi.mutex.Lock()
+	defer gi.mutex.Unlock()
+
+	now := clock.Now()
+	gi.prune(now)
+
+	peers, ok := gi.peers[key]
+	if !ok {
+		peers = make(map[string]time.Time)
+		gi.peers[key] = peers
+	}
+	expire, ok := peers[peerAddress]
+	peers[peerAddress] = now.Add(gi.ttl)
+	return !ok || now.After(expire)
+}
+
+// interested returns true if the peer sent hits or checks for the rate limit within the TTL
+func (gi *globalInterest) interested(key, peerAddress string) bool {
+	gi.mutex.Lock()
+	defer gi.mutex.Unlock()
+
+	now := clock.Now()
+	for _, addr := range []string{peerAddress, ""} {
+		if expire, ok := gi.peers[key][addr]; ok && !now.After(expire) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune removes expired interests, at most once per TTL
+func (gi *globalInterest) prune(now time.Time) {
+	if now.Sub(gi.prunedAt) < gi.ttl {
+		return
+	}
+	gi.prunedAt = now
+	for key, peers := range gi.peers {
+		for addr, expire := range peers {
+			if now.After(expire) {
+				delete(peers, addr)
+			}
+		}
+		if len(peers) == 0 {
+			delete(gi.peers, key)
+		}
+	}
+}
+
 // Close stops all goroutines and shuts down all the peers.
 func (gm *globalManager) Close() {
 	gm.wg.Stop()
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestGlobalBatches(t *testing.T) {
+	s, err := NewV1Instance(Config{GRPCServers: []*grpc.Server{grpc.NewServer()}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	req := func(hits int64, requestID string) *RateLimitReq {
+		return &RateLimitReq{
+			Name:      t.Name(),
+			UniqueKey: "key",
+			Algorithm: Algorithm_TOKEN_BUCKET,
+			Behavior:  Behavior_GLOBAL,
+			Duration:  Minute,
+			Limit:     10,
+			Hits:      hits,
+			RequestId: requestID,
+		}
+	}
+	key := req(0, "").HashKey()
+	sendBatch := func(sequence uint64, hits int64, aggregated ...*AggregatedRequest) int64 {
+		t.Helper()
+		resp, err := s.GetPeerRateLimits(context.Background(), &GetPeerRateLimitsReq{
+			Requests:           []*RateLimitReq{req(hits, "")},
+			PeerAddress:        "10.0.0.1:1051",
+			Sequence:           sequence,
+			AggregatedRequests: aggregated,
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.RateLimits[0].Error)
+		return resp.RateLimits[0].Remaining
+	}
+
+	t.Run("Retried batch", func(t *testing.T) {
+		assert.Equal(t, int64(8), sendBatch(5, 2))
+		assert.Equal(t, int64(8), sendBatch(5, 2))
+		// A late retry of an earlier batch
+		assert.Equal(t, int64(8), sendBatch(4, 3))
+		assert.Equal(t, int64(7), sendBatch(6, 1))
+
+		// The batches are not remembered as client requests
+		assert.Equal(t, 0, s.requestIDs.ll.Len())
+	})
+
+	t.Run("Aggregated requests", func(t *testing.T) {
+		// The client sent a request to the owner, then retried it on another peer
+		resp, err := s.getLocalRateLimit(context.Background(), req(1, "request-1"), RateLimitReqState{IsOwner: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), resp.Remaining)
+
+		// Only the hits of the request which didn't reach the owner are applied
+		assert.Equal(t, int64(4), sendBatch(7, 3,
+			&AggregatedRequest{Key: key, RequestId: "request-1", Hits: 1},
+			&AggregatedRequest{Key: key, RequestId: "request-2", Hits: 2},
+		))
+
+		// A retry of the aggregated request is not applied again
+		resp, err = s.getLocalRateLimit(context.Background(), req(2, "request-2"), RateLimitReqState{IsOwner: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), resp.Remaining)
+		assert.Equal(t, int64(4), sendBatch(8, 0))
+		assert.Equal(t, 2, s.requestIDs.ll.Len())
+	})
+}
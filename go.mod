@@ -9,6 +9,7 @@ require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.19.0
 	github.com/hashicorp/memberlist v0.5.0
+	github.com/klauspost/compress v1.17.11
 	github.com/mailgun/errors v0.1.5
 	github.com/mailgun/holster/v4 v4.19.0
 	github.com/miekg/dns v1.1.50
@@ -37,6 +38,7 @@ require (
 	k8s.io/apimachinery v0.23.3
 	k8s.io/client-go v0.23.3
 	k8s.io/klog/v2 v2.120.1
+	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
@@ -100,5 +102,4 @@ require (
 	k8s.io/utils v0.0.0-20211116205334-6203023598ed // indirect
 	sigs.k8s.io/json v0.0.0-20211020170558-c049b76a60c6 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.1 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
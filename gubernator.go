@@ -19,6 +19,8 @@ package gubernator
 import (
 	"context"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -26,9 +28,11 @@ import (
 
 	"github.com/mailgun/errors"
 	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/setter"
 	"github.com/mailgun/holster/v4/syncutil"
 	"github.com/mailgun/holster/v4/tracing"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/fasthash/fnv1a"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
@@ -48,11 +52,21 @@ type V1Instance struct {
 	UnimplementedV1Server
 	UnimplementedPeersV1Server
 	global     *globalManager
+	crdt       *crdtManager
 	peerMutex  sync.RWMutex
 	log        FieldLogger
 	conf       Config
 	isClosed   atomic.Bool
-	workerPool *WorkerPool
+	workerPool CacheEngine
+	leases     *leaseTracker
+	requestIDs *requestIDCache
+	hotKeys    *hotKeys
+	wg         syncutil.WaitGroup
+
+	// Set once the instance is draining, see Drain()
+	draining atomic.Bool
+	// When the last request forwarded by a peer was received in unix nanoseconds
+	lastPeerRequest atomic.Int64
 }
 
 type RateLimitReqState struct {
@@ -62,7 +76,7 @@ type RateLimitReqState struct {
 var (
 	metricGetRateLimitCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "gubernator_getratelimit_counter",
-		Help: "The count of getLocalRateLimit() calls.  Label \"calltype\" may be \"local\" for calls handled by the same peer, or \"global\" for global rate limits.",
+		Help: "The count of getLocalRateLimit() calls.  Label \"calltype\" may be \"local\" for calls handled by the same peer, \"global\" for global rate limits, or \"crdt\" for rate limits with GLOBAL_CRDT behavior.",
 	}, []string{"calltype"})
 	metricFuncTimeDuration = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 		Name: "gubernator_func_duration",
@@ -93,6 +107,26 @@ var (
 		Name: "gubernator_worker_queue_length",
 		Help: "The count of requests queued up in WorkerPool.",
 	}, []string{"method", "worker"})
+	metricLeaseTokenCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gubernator_lease_token_count",
+		Help: "The count of tokens leased to clients.  Label \"type\" may be \"granted\" for tokens leased, \"returned\" for unused tokens returned before the lease expired, or \"expired\" for tokens of leases which expired before they were returned.",
+	}, []string{"type"})
+	metricCacheSweptCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gubernator_cache_swept_count",
+		Help: "The count of expired items removed from the cache of each worker by the background sweeper.",
+	}, []string{"worker"})
+	metricCacheSweepDuration = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "gubernator_cache_sweep_duration",
+		Help: "The duration of each background sweep of expired cache items in seconds.",
+		Objectives: map[float64]float64{
+			0.5:  0.05,
+			0.99: 0.001,
+		},
+	})
+	metricRequestIDDuplicateCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gubernator_request_id_duplicate_count",
+		Help: "The count of rate limit requests with a previously seen request_id which received the original response.",
+	})
 
 	// Batch behavior.
 	metricBatchSendRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -124,12 +158,21 @@ func NewV1Instance(conf Config) (s *V1Instance, err error) {
 	}
 
 	s = &V1Instance{
-		log:  conf.Logger,
-		conf: conf,
+		log:    conf.Logger,
+		conf:   conf,
+		leases: newLeaseTracker(),
+		requestIDs: newRequestIDCache(conf.Behaviors.RequestIDWindow,
+			conf.Behaviors.RequestIDCacheSize),
+		hotKeys: newHotKeys(conf.HotKeysCapacity, conf.HotKeysHalfLife),
 	}
 
-	s.workerPool = NewWorkerPool(&conf)
+	cacheNames.setLimit(conf.CacheNameMetricsLimit)
+	s.workerPool = NewCacheEngine(&conf)
 	s.global = newGlobalManager(conf.Behaviors, s)
+	s.crdt = newCRDTManager(conf.Behaviors, s)
+	if _, ok := s.conf.LocalPicker.(peerLoadBalancer); ok {
+		s.runPeerLoad()
+	}
 
 	// Register our instance with all GRPC servers
 	for _, srv := range conf.GRPCServers {
@@ -156,6 +199,8 @@ func (s *V1Instance) Close() (err error) {
 	}
 
 	s.global.Close()
+	s.crdt.Close()
+	s.wg.Stop()
 
 	if s.conf.Loader != nil {
 		err = s.workerPool.Store(context.Background())
@@ -232,6 +277,18 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (*G
 			SetBehavior(&req.Behavior, Behavior_GLOBAL, true)
 		}
 
+		// Rate limits with GLOBAL_CRDT behavior have no owner, every peer applies them locally
+		if HasBehavior(req.Behavior, Behavior_GLOBAL_CRDT) {
+			resp.Responses[i], err = s.getCRDTRateLimit(req)
+			if err != nil {
+				err = errors.Wrapf(err, "Error while apply rate limit for '%s'", key)
+				span := trace.SpanFromContext(ctx)
+				span.RecordError(err)
+				resp.Responses[i] = &RateLimitResp{Error: err.Error()}
+			}
+			continue
+		}
+
 		peer, err = s.GetPeer(ctx, key)
 		if err != nil {
 			countError(err, "Error in GetPeer")
@@ -268,6 +325,16 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (*G
 				continue
 			}
 
+			// Fail fast rather than wait for a peer which is failing to time out
+			if peer.CircuitOpen() {
+				metricCheckErrorCounter.WithLabelValues("Circuit breaker open").Inc()
+				metricCircuitBreakerRejected.WithLabelValues(peer.Info().GRPCAddress).Inc()
+				err = fmt.Errorf("while fetching rate limit '%s' from peer '%s': %w",
+					key, peer.Info().GRPCAddress, errCircuitOpen)
+				resp.Responses[i] = &RateLimitResp{Error: err.Error()}
+				continue
+			}
+
 			// Request must be forwarded to peer that owns the key.
 			// Launch remote peer request in goroutine.
 			wg.Add(1)
@@ -398,24 +465,30 @@ func (s *V1Instance) getGlobalRateLimit(ctx context.Context, req *RateLimitReq)
 		attribute.String("ratelimit.name", req.Name),
 	))
 	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.getGlobalRateLimit")).ObserveDuration()
-	defer func() {
-		if err == nil {
-			s.global.QueueHit(req)
-		}
-		tracing.EndScope(ctx, err)
-	}()
+	defer func() { tracing.EndScope(ctx, err) }()
+
+	// Retries of a request with a `request_id` must not queue the same hits twice
+	if key := requestIDKey(req); key != "" {
+		return s.requestIDs.apply(ctx, key, func() (*RateLimitResp, error) {
+			return s.applyGlobalRateLimit(ctx, req)
+		})
+	}
+	return s.applyGlobalRateLimit(ctx, req)
+}
 
+func (s *V1Instance) applyGlobalRateLimit(ctx context.Context, req *RateLimitReq) (*RateLimitResp, error) {
 	req2 := proto.Clone(req).(*RateLimitReq)
 	SetBehavior(&req2.Behavior, Behavior_NO_BATCHING, true)
 	SetBehavior(&req2.Behavior, Behavior_GLOBAL, false)
 	reqState := RateLimitReqState{IsOwner: false}
 
 	// Process the rate limit like we own it
-	resp, err = s.getLocalRateLimit(ctx, req2, reqState)
+	resp, err := s.getLocalRateLimit(ctx, req2, reqState)
 	if err != nil {
 		return nil, errors.Wrap(err, "during in getLocalRateLimit")
 	}
 
+	s.global.QueueHit(req)
 	metricGetRateLimitCounter.WithLabelValues("global").Inc()
 	return resp, nil
 }
@@ -458,9 +531,29 @@ func (s *V1Instance) UpdatePeerGlobals(ctx context.Context, r *UpdatePeerGlobals
 	return &UpdatePeerGlobalsResp{}, nil
 }
 
+// UpdatePeerCounters merges the hits other peers counted for rate limits with GLOBAL_CRDT behavior
+func (s *V1Instance) UpdatePeerCounters(_ context.Context, r *UpdatePeerCountersReq) (*UpdatePeerCountersResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.UpdatePeerCounters")).ObserveDuration()
+	s.crdt.merge(r.Counters)
+	return &UpdatePeerCountersResp{}, nil
+}
+
+// getCRDTRateLimit applies a rate limit with GLOBAL_CRDT behavior
+func (s *V1Instance) getCRDTRateLimit(r *RateLimitReq) (*RateLimitResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.getCRDTRateLimit")).ObserveDuration()
+	resp, err := s.crdt.GetRateLimit(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "during crdt.GetRateLimit")
+	}
+	metricGetRateLimitCounter.WithLabelValues("crdt").Inc()
+	s.hotKeys.record(r, resp)
+	return resp, nil
+}
+
 // GetPeerRateLimits is called by other peers to get the rate limits owned by this peer.
 func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimitsReq) (resp *GetPeerRateLimitsResp, err error) {
 	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.GetPeerRateLimits")).ObserveDuration()
+	s.lastPeerRequest.Store(clock.Now().UnixNano())
 	if len(r.Requests) > maxBatchSize {
 		err := fmt.Errorf("'PeerRequest.rate_limits' list too large; max size is '%d'", maxBatchSize)
 		metricCheckErrorCounter.WithLabelValues("Request too large").Inc()
@@ -494,6 +587,11 @@ func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 		respWg.Done()
 	}()
 
+	aggregated := make(map[string][]*AggregatedRequest)
+	for _, a := range r.AggregatedRequests {
+		aggregated[a.Key] = append(aggregated[a.Key], a)
+	}
+
 	// Fan out requests.
 	fan := syncutil.NewFanOut(s.conf.Workers)
 	for idx, req := range r.Requests {
@@ -517,7 +615,24 @@ func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 				rin.req.CreatedAt = &createdAt
 			}
 
+			var claimed []string
+			if HasBehavior(rin.req.Behavior, Behavior_GLOBAL) {
+				// The peer which sent the hits or checks is interested in updates of the rate limit
+				s.global.AddInterest(rin.req, r.PeerAddress)
+
+				key := rin.req.HashKey()
+				if s.global.ClaimBatchHits(r.PeerAddress, r.Sequence, key) {
+					claimed = s.claimAggregatedRequests(rin.req, aggregated[key])
+				} else {
+					// The hits of a retried batch were applied already
+					rin.req.Hits = 0
+				}
+			}
+
 			rl, err := s.getLocalRateLimit(ctx, rin.req, reqState)
+			for _, key := range claimed {
+				s.requestIDs.complete(key, rl, err)
+			}
 			if err != nil {
 				// Return the error for this request
 				err = errors.Wrap(err, "Error in getLocalRateLimit")
@@ -538,6 +653,103 @@ func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 	return resp, nil
 }
 
+// claimAggregatedRequests removes the hits of the client requests aggregated in the GLOBAL
+// hits of `r` which were already applied, such as when the client retried the request on
+// another peer. Returns the keys of the requests which were not, which must be completed with
+// the response to `r`, such that retries of them are not applied either.
+func (s *V1Instance) claimAggregatedRequests(r *RateLimitReq, aggregated []*AggregatedRequest) []string {
+	var claimed []string
+	for _, a := range aggregated {
+		key := requestIDKeyOf(r.HashKey(), a.RequestId)
+		if s.requestIDs.claim(key) {
+			claimed = append(claimed, key)
+		} else {
+			r.Hits -= a.Hits
+		}
+	}
+	return claimed
+}
+
+// PeerStream is called by other peers to multiplex requests over a single stream. Up to
+// PeerStreamWindow requests are handled concurrently, and the response to each request is
+// sent as soon as it completes.
+func (s *V1Instance) PeerStream(stream PeersV1_PeerStreamServer) error {
+	var sendMutex sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx := stream.Context()
+	window := make(chan struct{}, s.conf.Behaviors.PeerStreamWindow)
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		// The timeout starts when the request is received, such that it doesn't depend on the
+		// clocks of the peers being in sync
+		reqCtx, cancel := ctx, context.CancelFunc(func() {})
+		if req.Timeout > 0 {
+			reqCtx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Millisecond)
+		}
+
+		// Stop receiving until a request completes, which applies back pressure on the peer
+		select {
+		case window <- struct{}{}:
+		case <-ctx.Done():
+			cancel()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			resp := s.handlePeerStream(reqCtx, req)
+			<-window
+
+			sendMutex.Lock()
+			err := stream.Send(resp)
+			sendMutex.Unlock()
+			if err != nil {
+				s.log.WithError(err).Debug("while sending PeerStream response")
+			}
+		}()
+	}
+}
+
+// handlePeerStream handles a request received on a PeerStream
+func (s *V1Instance) handlePeerStream(ctx context.Context, req *PeerStreamReq) *PeerStreamResp {
+	resp := &PeerStreamResp{Id: req.Id}
+	// The sender stopped waiting for the response while the request waited to be handled
+	if err := ctx.Err(); err != nil {
+		resp.Error = errors.Wrap(err, "request was not applied").Error()
+		return resp
+	}
+	switch r := req.Request.(type) {
+	case *PeerStreamReq_GetPeerRateLimits:
+		out, err := s.GetPeerRateLimits(ctx, r.GetPeerRateLimits)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Response = &PeerStreamResp_GetPeerRateLimits{GetPeerRateLimits: out}
+	case *PeerStreamReq_UpdatePeerGlobals:
+		out, err := s.UpdatePeerGlobals(ctx, r.UpdatePeerGlobals)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Response = &PeerStreamResp_UpdatePeerGlobals{UpdatePeerGlobals: out}
+	default:
+		resp.Error = "unknown PeerStream request"
+	}
+	return resp
+}
+
 // HealthCheck Returns the health of our instance.
 func (s *V1Instance) HealthCheck(ctx context.Context, r *HealthCheckReq) (health *HealthCheckResp, err error) {
 	span := trace.SpanFromContext(ctx)
@@ -550,7 +762,18 @@ func (s *V1Instance) HealthCheck(ctx context.Context, r *HealthCheckReq) (health
 
 	// Iterate through local peers and get their last errors
 	localPeers := s.conf.LocalPicker.Peers()
+	var peerCapabilities []*PeerCapabilities
+	var peerCircuitBreakers []*PeerCircuitBreaker
 	for _, peer := range localPeers {
+		peerCapabilities = append(peerCapabilities, peer.Capabilities())
+		if cb := peer.CircuitBreaker(); cb != nil {
+			peerCircuitBreakers = append(peerCircuitBreakers, cb)
+			if cb.State == CircuitOpen {
+				err := fmt.Errorf("circuit breaker of local peer '%s' is open", cb.GrpcAddress)
+				span.RecordError(err)
+				errs = append(errs, err.Error())
+			}
+		}
 		for _, errMsg := range peer.GetLastErr() {
 			err := fmt.Errorf("error returned from local peer.GetLastErr: %s", errMsg)
 			span.RecordError(err)
@@ -578,9 +801,12 @@ func (s *V1Instance) HealthCheck(ctx context.Context, r *HealthCheckReq) (health
 	}
 
 	health = &HealthCheckResp{
-		PeerCount:        int32(len(localPeers) + len(regionPeers)),
-		Status:           Healthy,
-		AdvertiseAddress: ownPeerAddress,
+		PeerCount:           int32(len(localPeers) + len(regionPeers)),
+		Status:              Healthy,
+		AdvertiseAddress:    ownPeerAddress,
+		Capabilities:        commonCapabilities(peerCapabilities),
+		PeerCapabilities:    peerCapabilities,
+		PeerCircuitBreakers: peerCircuitBreakers,
 	}
 
 	if len(errs) != 0 {
@@ -593,6 +819,11 @@ func (s *V1Instance) HealthCheck(ctx context.Context, r *HealthCheckReq) (health
 		health.Message = strings.Join(append(errs, "this instance is not found in the peer list"), "|")
 	}
 
+	if s.draining.Load() {
+		health.Status = UnHealthy
+		health.Message = strings.Join(append(errs, "this instance is draining"), "|")
+	}
+
 	span.SetAttributes(
 		attribute.Int64("health.peerCount", int64(health.PeerCount)),
 		attribute.String("health.status", health.Status),
@@ -619,6 +850,414 @@ func (s *V1Instance) LiveCheck(_ context.Context, _ *LiveCheckReq) (health *Live
 	return &LiveCheckResp{}, nil
 }
 
+// GetPeers returns the local and region peers this instance knows about.
+func (s *V1Instance) GetPeers(_ context.Context, _ *GetPeersReq) (*GetPeersResp, error) {
+	s.peerMutex.RLock()
+	defer s.peerMutex.RUnlock()
+
+	var resp GetPeersResp
+	pickers := []PeerPicker{s.conf.LocalPicker}
+	for _, picker := range s.conf.RegionPicker.Pickers() {
+		pickers = append(pickers, picker)
+	}
+	for _, picker := range pickers {
+		ownership := pickerOwnership(picker)
+		for _, peer := range picker.Peers() {
+			resp.Peers = append(resp.Peers, newPeer(peer.Info(), ownership))
+		}
+	}
+	return &resp, nil
+}
+
+func newPeer(info PeerInfo, ownership map[string]float64) *Peer {
+	return &Peer{
+		GrpcAddress: info.GRPCAddress,
+		HttpAddress: info.HTTPAddress,
+		DataCenter:  info.DataCenter,
+		IsOwner:     info.IsOwner,
+		Weight:      int32(info.Weight),
+		Ownership:   ownership[info.GRPCAddress],
+	}
+}
+
+// GetTopology returns the peers known by this instance and the state of their connections, the
+// owner of a rate limit, and whether every local peer agrees on the set of local peers.
+func (s *V1Instance) GetTopology(ctx context.Context, r *GetTopologyReq) (*GetTopologyResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.GetTopology")).ObserveDuration()
+	s.peerMutex.RLock()
+	localPicker := s.conf.LocalPicker
+	regionPickers := s.conf.RegionPicker.Pickers()
+	s.peerMutex.RUnlock()
+
+	resp := GetTopologyResp{
+		RingHash:      ringHash(localPicker.Peers()),
+		RingAgreement: true,
+	}
+
+	ownership := pickerOwnership(localPicker)
+	if r.Name != "" || r.UniqueKey != "" {
+		key := (&RateLimitReq{Name: r.Name, UniqueKey: r.UniqueKey}).HashKey()
+		peer, err := localPicker.Get(key)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "while finding the owner of '%s': %s", key, err)
+		}
+		resp.KeyOwner = newPeer(peer.Info(), ownership)
+	}
+
+	for _, peer := range localPicker.Peers() {
+		resp.LocalPeers = append(resp.LocalPeers, newPeerTopology(peer, ownership))
+	}
+	for _, picker := range regionPickers {
+		ownership := pickerOwnership(picker)
+		for _, peer := range picker.Peers() {
+			resp.RegionPeers = append(resp.RegionPeers, newPeerTopology(peer, ownership))
+		}
+	}
+
+	// Ask every local peer for its ring hash
+	ctx, cancel := context.WithTimeout(ctx, s.conf.Behaviors.GlobalTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, topology := range resp.LocalPeers {
+		if topology.Peer.IsOwner {
+			topology.RingHash = resp.RingHash
+			continue
+		}
+		peer := localPicker.GetByPeerInfo(PeerInfo{GRPCAddress: topology.Peer.GrpcAddress})
+		wg.Add(1)
+		go func(topology *PeerTopology) {
+			defer wg.Done()
+			r, err := peer.GetPeerRingHash(ctx)
+			if err != nil {
+				topology.RingHashError = err.Error()
+				return
+			}
+			topology.RingHash = r.RingHash
+		}(topology)
+	}
+	wg.Wait()
+
+	for _, topology := range resp.LocalPeers {
+		if topology.RingHash != resp.RingHash {
+			resp.RingAgreement = false
+		}
+	}
+	return &resp, nil
+}
+
+func newPeerTopology(peer *PeerClient, ownership map[string]float64) *PeerTopology {
+	return &PeerTopology{
+		Peer:             newPeer(peer.Info(), ownership),
+		LastErrors:       peer.GetLastErr(),
+		BatchQueueLength: int32(peer.BatchQueueLength()),
+	}
+}
+
+// GetPeerRingHash is called by other peers to check they have the same view of the cluster
+func (s *V1Instance) GetPeerRingHash(_ context.Context, _ *GetPeerRingHashReq) (*GetPeerRingHashResp, error) {
+	s.peerMutex.RLock()
+	defer s.peerMutex.RUnlock()
+	return &GetPeerRingHashResp{RingHash: ringHash(s.conf.LocalPicker.Peers())}, nil
+}
+
+// ringHash returns a hash of the address and weight of the peers, which is the same for every
+// instance with the same view of the cluster regardless of the order of the peers
+func ringHash(peers []*PeerClient) string {
+	var keys []string
+	for _, peer := range peers {
+		keys = append(keys, fmt.Sprintf("%s/%d", peer.Info().GRPCAddress, peer.Info().weight()))
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%016x", fnv1a.HashString64(strings.Join(keys, ",")))
+}
+
+// GetPeerLoad is called by other peers to get the load this instance observed on each peer
+func (s *V1Instance) GetPeerLoad(_ context.Context, _ *GetPeerLoadReq) (*GetPeerLoadResp, error) {
+	s.peerMutex.RLock()
+	defer s.peerMutex.RUnlock()
+
+	var resp GetPeerLoadResp
+	if lb, ok := s.conf.LocalPicker.(peerLoadBalancer); ok {
+		resp.Load = lb.ObservedLoad()
+		resp.ShedVersion, resp.Shed = lb.ShedTable()
+	}
+	return &resp, nil
+}
+
+// GetPeerCapabilities is called by other peers to negotiate the peer protocol capabilities they may use
+func (s *V1Instance) GetPeerCapabilities(_ context.Context, _ *GetPeerCapabilitiesReq) (*GetPeerCapabilitiesResp, error) {
+	return &GetPeerCapabilitiesResp{
+		ProtocolVersion: PeerProtocolVersion,
+		Capabilities:    capabilities,
+	}, nil
+}
+
+// runPeerLoad periodically samples the load this instance observed on each peer, and sets the
+// total load all peers observed on the LocalPicker.
+func (s *V1Instance) runPeerLoad() {
+	ticker := clock.NewTicker(s.conf.Behaviors.PeerLoadInterval)
+	s.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-ticker.C():
+			s.syncPeerLoad()
+			return true
+		case <-done:
+			ticker.Stop()
+			return false
+		}
+	})
+}
+
+// syncPeerLoad samples the load observed by this instance. The peer which leads the load
+// balancing sums its load and the load observed by the other peers, then sets the total on the
+// LocalPicker. The other peers use the shed table of the leader.
+func (s *V1Instance) syncPeerLoad() {
+	s.peerMutex.RLock()
+	picker := s.conf.LocalPicker
+	s.peerMutex.RUnlock()
+
+	lb, ok := picker.(peerLoadBalancer)
+	if !ok {
+		return
+	}
+
+	total := make(map[string]float64)
+	for addr, rate := range lb.SampleLoad() {
+		total[addr] += rate
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.Behaviors.PeerLoadInterval)
+	defer cancel()
+
+	if leader := peerLoadLeader(picker.Peers()); leader != nil {
+		resp, err := leader.GetPeerLoad(ctx)
+		if err == nil {
+			// Keep the current table until the leader computed its first table
+			if resp.ShedVersion != 0 {
+				lb.SetShedTable(resp.ShedVersion, resp.Shed)
+			}
+			return
+		}
+		// Compute the table from the load this instance can fetch until the leader is back
+		// or is removed from the peers.
+		s.log.WithError(err).WithField("peer", leader.Info().GRPCAddress).
+			Debug("while fetching the shed table of the load balancing leader")
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, peer := range picker.Peers() {
+		// The load of peers which don't report it is missing from the total
+		if peer.Info().IsOwner || !peer.HasCapability(CapabilityPeerLoad) {
+			continue
+		}
+		wg.Add(1)
+		go func(peer *PeerClient) {
+			defer wg.Done()
+			resp, err := peer.GetPeerLoad(ctx)
+			if err != nil {
+				// The peer's load is missing from the total until the next sync
+				s.log.WithError(err).WithField("peer", peer.Info().GRPCAddress).
+					Debug("while fetching peer load")
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			for addr, rate := range resp.Load {
+				total[addr] += rate
+			}
+		}(peer)
+	}
+	wg.Wait()
+
+	lb.SetLoad(total)
+}
+
+// peerLoadLeader returns the peer with the lowest GRPC address of the peers which publish their
+// shed table, or nil if that is this instance. Every peer with the same view of the cluster
+// picks the same leader.
+func peerLoadLeader(peers []*PeerClient) *PeerClient {
+	var leader *PeerClient
+	for _, peer := range peers {
+		if !peer.Info().IsOwner && !peer.HasCapability(CapabilityPeerLoadShed) {
+			continue
+		}
+		if leader == nil || peer.Info().GRPCAddress < leader.Info().GRPCAddress {
+			leader = peer
+		}
+	}
+	if leader == nil || leader.Info().IsOwner {
+		return nil
+	}
+	return leader
+}
+
+// GetHotKeys returns the rate limits this instance applied most often recently
+func (s *V1Instance) GetHotKeys(_ context.Context, r *GetHotKeysReq) (*GetHotKeysResp, error) {
+	if r.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limit '%d' is invalid; must not be negative", r.Limit)
+	}
+	limit := int(r.Limit)
+	setter.SetDefault(&limit, 10)
+	return s.hotKeys.get(limit), nil
+}
+
+// LeaseTokens is the public interface used by clients to lease tokens from rate limits. If the rate
+// limit is not owned by this instance, then we forward the request to the peer that does.
+func (s *V1Instance) LeaseTokens(ctx context.Context, r *LeaseTokensReq) (*LeaseTokensResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.LeaseTokens")).ObserveDuration()
+
+	if len(r.Requests) > maxBatchSize {
+		metricCheckErrorCounter.WithLabelValues("Request too large").Inc()
+		return nil, status.Errorf(codes.OutOfRange,
+			"Requests.LeaseTokens list too large; max size is '%d'", maxBatchSize)
+	}
+
+	createdAt := epochMillis(clock.Now())
+	resp := LeaseTokensResp{
+		Responses: make([]*LeaseResp, len(r.Requests)),
+	}
+	var wg sync.WaitGroup
+
+	for i, req := range r.Requests {
+		if err := validateLeaseReq(req); err != nil {
+			metricCheckErrorCounter.WithLabelValues("Invalid request").Inc()
+			resp.Responses[i] = &LeaseResp{Error: err.Error()}
+			continue
+		}
+		if req.RateLimit.CreatedAt == nil || *req.RateLimit.CreatedAt == 0 {
+			req.RateLimit.CreatedAt = &createdAt
+		}
+
+		key := req.RateLimit.HashKey()
+		peer, err := s.GetPeer(ctx, key)
+		if err != nil {
+			countError(err, "Error in GetPeer")
+			err = errors.Wrapf(err, "Error in GetPeer, looking up peer that owns rate limit '%s'", key)
+			resp.Responses[i] = &LeaseResp{Error: err.Error()}
+			continue
+		}
+
+		if peer.Info().IsOwner {
+			resp.Responses[i] = s.leaseLocalTokens(ctx, req)
+			continue
+		}
+
+		// Request must be forwarded to peer that owns the key.
+		wg.Add(1)
+		go func(idx int, peer *PeerClient, req *LeaseReq) {
+			defer wg.Done()
+			r, err := peer.LeasePeerTokens(ctx, &LeaseTokensReq{Requests: []*LeaseReq{req}})
+			if err != nil {
+				err = fmt.Errorf("while leasing tokens for '%s' from peer: %w", req.RateLimit.HashKey(), err)
+				resp.Responses[idx] = &LeaseResp{Error: err.Error()}
+				return
+			}
+			resp.Responses[idx] = r.Responses[0]
+		}(i, peer, req)
+	}
+
+	wg.Wait()
+	return &resp, nil
+}
+
+// LeasePeerTokens is called by other peers to lease tokens from the rate limits owned by this peer.
+func (s *V1Instance) LeasePeerTokens(ctx context.Context, r *LeaseTokensReq) (*LeaseTokensResp, error) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.LeasePeerTokens")).ObserveDuration()
+	s.lastPeerRequest.Store(clock.Now().UnixNano())
+
+	if len(r.Requests) > maxBatchSize {
+		err := fmt.Errorf("'LeaseTokensReq.requests' list too large; max size is '%d'", maxBatchSize)
+		metricCheckErrorCounter.WithLabelValues("Request too large").Inc()
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	}
+
+	resp := LeaseTokensResp{
+		Responses: make([]*LeaseResp, len(r.Requests)),
+	}
+	for i, req := range r.Requests {
+		if err := validateLeaseReq(req); err != nil {
+			resp.Responses[i] = &LeaseResp{Error: err.Error()}
+			continue
+		}
+		// Assign default to CreatedAt for backwards compatibility.
+		if req.RateLimit.CreatedAt == nil || *req.RateLimit.CreatedAt == 0 {
+			createdAt := epochMillis(clock.Now())
+			req.RateLimit.CreatedAt = &createdAt
+		}
+		resp.Responses[i] = s.leaseLocalTokens(ctx, req)
+	}
+	return &resp, nil
+}
+
+// leaseLocalTokens returns the unused tokens of the previous lease and grants a new lease
+// from a rate limit owned by this instance.
+func (s *V1Instance) leaseLocalTokens(ctx context.Context, r *LeaseReq) *LeaseResp {
+	key := r.RateLimit.HashKey()
+	now := *r.RateLimit.CreatedAt
+
+	var returned int64
+	var released *tokenLease
+	if r.ReturnLeaseId != "" {
+		returned, released = s.leases.release(r.ReturnLeaseId, key, r.Returned, now)
+	}
+
+	rl, granted, err := s.workerPool.LeaseTokens(ctx, r, returned)
+	if err != nil {
+		// Once the request reached the cache, the returned tokens may have been credited back
+		// even if it failed. Only if it never did, the client may return them again.
+		var notApplied notAppliedError
+		if errors.As(err, &notApplied) {
+			s.leases.restore(r.ReturnLeaseId, released)
+		}
+		err = errors.Wrapf(err, "Error while leasing tokens for '%s'", key)
+		trace.SpanFromContext(ctx).RecordError(err)
+		return &LeaseResp{Error: err.Error()}
+	}
+	metricLeaseTokenCounter.WithLabelValues("returned").Add(float64(returned))
+
+	resp := &LeaseResp{
+		RateLimit: rl,
+		Tokens:    granted,
+	}
+	if granted == 0 {
+		return resp
+	}
+
+	resp.ExpireAt, err = leaseExpiration(r, rl, now)
+	if err != nil {
+		// The tokens have been removed from the rate limit, the client will never
+		// spend them, so they are dropped until the rate limit resets.
+		err = errors.Wrapf(err, "Error while computing lease expiration for '%s'", key)
+		return &LeaseResp{Error: err.Error()}
+	}
+	resp.LeaseId = s.leases.grant(key, granted, resp.ExpireAt, now)
+	metricLeaseTokenCounter.WithLabelValues("granted").Add(float64(granted))
+	return resp
+}
+
+func validateLeaseReq(r *LeaseReq) error {
+	if r.RateLimit == nil {
+		return errors.New("field 'rate_limit' cannot be empty")
+	}
+	if r.RateLimit.UniqueKey == "" {
+		return errors.New("field 'unique_key' cannot be empty")
+	}
+	if r.RateLimit.Name == "" {
+		return errors.New("field 'namespace' cannot be empty")
+	}
+	if r.Tokens < 0 || r.Returned < 0 {
+		return errors.New("fields 'tokens' and 'returned' cannot be negative")
+	}
+	if HasBehavior(r.RateLimit.Behavior, Behavior_GLOBAL) {
+		return errors.New("tokens cannot be leased from a rate limit with GLOBAL behavior")
+	}
+	if HasBehavior(r.RateLimit.Behavior, Behavior_GLOBAL_CRDT) {
+		return errors.New("tokens cannot be leased from a rate limit with GLOBAL_CRDT behavior")
+	}
+	return nil
+}
+
 func (s *V1Instance) getLocalRateLimit(ctx context.Context, r *RateLimitReq, reqState RateLimitReqState) (_ *RateLimitResp, err error) {
 	ctx = tracing.StartNamedScope(ctx, "V1Instance.getLocalRateLimit", trace.WithAttributes(
 		attribute.String("ratelimit.key", r.UniqueKey),
@@ -629,10 +1268,21 @@ func (s *V1Instance) getLocalRateLimit(ctx context.Context, r *RateLimitReq, req
 	defer func() { tracing.EndScope(ctx, err) }()
 	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("V1Instance.getLocalRateLimit")).ObserveDuration()
 
+	// The owner returns the original response to retries of a request with a `request_id`
+	if key := requestIDKey(r); reqState.IsOwner && key != "" {
+		return s.requestIDs.apply(ctx, key, func() (*RateLimitResp, error) {
+			return s.applyLocalRateLimit(ctx, r, reqState)
+		})
+	}
+	return s.applyLocalRateLimit(ctx, r, reqState)
+}
+
+func (s *V1Instance) applyLocalRateLimit(ctx context.Context, r *RateLimitReq, reqState RateLimitReqState) (*RateLimitResp, error) {
 	resp, err := s.workerPool.GetRateLimit(ctx, r, reqState)
 	if err != nil {
 		return nil, errors.Wrap(err, "during workerPool.GetRateLimit")
 	}
+	s.hotKeys.record(r, resp)
 
 	// If global behavior, then broadcast update to all peers.
 	if HasBehavior(r.Behavior, Behavior_GLOBAL) {
@@ -657,6 +1307,12 @@ func (s *V1Instance) getLocalRateLimit(ctx context.Context, r *RateLimitReq, req
 	return resp, nil
 }
 
+// ResizeWorkers changes the number of workers which partition the cache without
+// losing the items in the cache. Only the worker pool engine can be resized.
+func (s *V1Instance) ResizeWorkers(ctx context.Context, n int) error {
+	return s.workerPool.Resize(ctx, n)
+}
+
 // SetPeers replaces the peers and shuts down all the previous peers.
 // TODO this should return an error if we failed to connect to any of the new peers
 func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
@@ -667,8 +1323,9 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 		// Add peers that are not in our local DC to the RegionPicker
 		if info.DataCenter != s.conf.DataCenter {
 			peer := s.conf.RegionPicker.GetByPeerInfo(info)
-			// If we don't have an existing PeerClient create a new one
-			if peer == nil {
+			// If we don't have an existing PeerClient create a new one. The picker reads
+			// the weight from the PeerClient, so a peer whose weight changed is replaced.
+			if peer == nil || peer.Info().weight() != info.weight() {
 				var err error
 				peer, err = NewPeerClient(PeerConfig{
 					TraceGRPC: s.conf.PeerTraceGRPC,
@@ -687,7 +1344,7 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 		}
 		// If we don't have an existing PeerClient create a new one
 		peer := s.conf.LocalPicker.GetByPeerInfo(info)
-		if peer == nil {
+		if peer == nil || peer.Info().weight() != info.weight() {
 			var err error
 			peer, err = NewPeerClient(PeerConfig{
 				TraceGRPC: s.conf.PeerTraceGRPC,
@@ -721,14 +1378,14 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 
 	var shutdownPeers []*PeerClient
 	for _, peer := range oldLocalPicker.Peers() {
-		if peerInfo := s.conf.LocalPicker.GetByPeerInfo(peer.Info()); peerInfo == nil {
+		if s.conf.LocalPicker.GetByPeerInfo(peer.Info()) != peer {
 			shutdownPeers = append(shutdownPeers, peer)
 		}
 	}
 
 	for _, regionPicker := range oldRegionPicker.Pickers() {
 		for _, peer := range regionPicker.Peers() {
-			if peerInfo := s.conf.RegionPicker.GetByPeerInfo(peer.Info()); peerInfo == nil {
+			if s.conf.RegionPicker.GetByPeerInfo(peer.Info()) != peer {
 				shutdownPeers = append(shutdownPeers, peer)
 			}
 		}
@@ -747,6 +1404,17 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 	}
 	wg.Wait()
 
+	// A peer replaced by a new PeerClient for the same address keeps its metrics
+	addresses := make(map[string]struct{}, len(peerInfo))
+	for _, info := range peerInfo {
+		addresses[info.GRPCAddress] = struct{}{}
+	}
+	for _, p := range shutdownPeers {
+		if _, ok := addresses[p.Info().GRPCAddress]; !ok {
+			deletePeerMetrics(p.Info().GRPCAddress)
+		}
+	}
+
 	if len(shutdownPeers) > 0 {
 		var peers []string
 		for _, p := range shutdownPeers {
@@ -787,17 +1455,29 @@ func (s *V1Instance) Describe(ch chan<- *prometheus.Desc) {
 	metricBatchQueueLength.Describe(ch)
 	metricBatchSendDuration.Describe(ch)
 	metricBatchSendRetries.Describe(ch)
+	metricCacheSweepDuration.Describe(ch)
+	metricCacheSweptCounter.Describe(ch)
 	metricCheckErrorCounter.Describe(ch)
+	metricCircuitBreakerRejected.Describe(ch)
+	metricCircuitBreakerState.Describe(ch)
 	metricCommandCounter.Describe(ch)
 	metricConcurrentChecks.Describe(ch)
 	metricFuncTimeDuration.Describe(ch)
 	metricGetRateLimitCounter.Describe(ch)
+	metricHandoffCounter.Describe(ch)
+	metricLeaseTokenCounter.Describe(ch)
 	metricOverLimitCounter.Describe(ch)
+	metricPeerTimeout.Describe(ch)
+	metricRequestIDDuplicateCounter.Describe(ch)
 	metricWorkerQueue.Describe(ch)
 	s.global.metricBroadcastDuration.Describe(ch)
+	s.global.metricBroadcastPeerUpdates.Describe(ch)
 	s.global.metricGlobalQueueLength.Describe(ch)
 	s.global.metricGlobalSendDuration.Describe(ch)
 	s.global.metricGlobalSendQueueLength.Describe(ch)
+	s.crdt.metricCounterCount.Describe(ch)
+	s.crdt.metricGossipDuration.Describe(ch)
+	ch <- metricHotKeyCount
 }
 
 // Collect fetches metrics from the server for use by prometheus
@@ -805,17 +1485,29 @@ func (s *V1Instance) Collect(ch chan<- prometheus.Metric) {
 	metricBatchQueueLength.Collect(ch)
 	metricBatchSendDuration.Collect(ch)
 	metricBatchSendRetries.Collect(ch)
+	metricCacheSweepDuration.Collect(ch)
+	metricCacheSweptCounter.Collect(ch)
 	metricCheckErrorCounter.Collect(ch)
+	metricCircuitBreakerRejected.Collect(ch)
+	metricCircuitBreakerState.Collect(ch)
 	metricCommandCounter.Collect(ch)
 	metricConcurrentChecks.Collect(ch)
 	metricFuncTimeDuration.Collect(ch)
 	metricGetRateLimitCounter.Collect(ch)
+	metricHandoffCounter.Collect(ch)
+	metricLeaseTokenCounter.Collect(ch)
 	metricOverLimitCounter.Collect(ch)
+	metricPeerTimeout.Collect(ch)
+	metricRequestIDDuplicateCounter.Collect(ch)
 	metricWorkerQueue.Collect(ch)
 	s.global.metricBroadcastDuration.Collect(ch)
+	s.global.metricBroadcastPeerUpdates.Collect(ch)
 	s.global.metricGlobalQueueLength.Collect(ch)
 	s.global.metricGlobalSendDuration.Collect(ch)
 	s.global.metricGlobalSendQueueLength.Collect(ch)
+	s.crdt.metricCounterCount.Collect(ch)
+	s.crdt.metricGossipDuration.Collect(ch)
+	s.hotKeys.collect(ch, s.conf.HotKeysMetricsLimit)
 }
 
 // HasBehavior returns true if the provided behavior is set
@@ -37,6 +37,10 @@ const (
 	V1_GetRateLimits_FullMethodName = "/pb.gubernator.V1/GetRateLimits"
 	V1_HealthCheck_FullMethodName   = "/pb.gubernator.V1/HealthCheck"
 	V1_LiveCheck_FullMethodName     = "/pb.gubernator.V1/LiveCheck"
+	V1_GetPeers_FullMethodName      = "/pb.gubernator.V1/GetPeers"
+	V1_LeaseTokens_FullMethodName   = "/pb.gubernator.V1/LeaseTokens"
+	V1_GetHotKeys_FullMethodName    = "/pb.gubernator.V1/GetHotKeys"
+	V1_GetTopology_FullMethodName   = "/pb.gubernator.V1/GetTopology"
 )
 
 // V1Client is the client API for V1 service.
@@ -50,6 +54,24 @@ type V1Client interface {
 	HealthCheck(ctx context.Context, in *HealthCheckReq, opts ...grpc.CallOption) (*HealthCheckResp, error)
 	// This method is used to determine if the server is running.
 	LiveCheck(ctx context.Context, in *LiveCheckReq, opts ...grpc.CallOption) (*LiveCheckResp, error)
+	// Returns the list of peers this instance currently knows about. Clients
+	// use this to discover the cluster and send requests directly to the
+	// peer that owns the rate limit.
+	GetPeers(ctx context.Context, in *GetPeersReq, opts ...grpc.CallOption) (*GetPeersResp, error)
+	// Lease a block of tokens from the owner of a rate limit. The leased tokens
+	// are removed from the rate limit and may be spent by the client without
+	// contacting the owner until the lease expires. Unused tokens from a previous
+	// lease may be returned to the rate limit in the same request.
+	LeaseTokens(ctx context.Context, in *LeaseTokensReq, opts ...grpc.CallOption) (*LeaseTokensResp, error)
+	// Returns the rate limits this instance applied most often recently and the
+	// rate limits which were most often over the limit. Used to find the keys
+	// responsible for a busy worker.
+	GetHotKeys(ctx context.Context, in *GetHotKeysReq, opts ...grpc.CallOption) (*GetHotKeysResp, error)
+	// Returns the local and region peers known by this instance along with the
+	// state of their connections, the peer which owns a rate limit, and whether
+	// every local peer agrees on the set of local peers. Used to debug requests
+	// which are sent to the wrong peer.
+	GetTopology(ctx context.Context, in *GetTopologyReq, opts ...grpc.CallOption) (*GetTopologyResp, error)
 }
 
 type v1Client struct {
@@ -87,6 +109,42 @@ func (c *v1Client) LiveCheck(ctx context.Context, in *LiveCheckReq, opts ...grpc
 	return out, nil
 }
 
+func (c *v1Client) GetPeers(ctx context.Context, in *GetPeersReq, opts ...grpc.CallOption) (*GetPeersResp, error) {
+	out := new(GetPeersResp)
+	err := c.cc.Invoke(ctx, V1_GetPeers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *v1Client) LeaseTokens(ctx context.Context, in *LeaseTokensReq, opts ...grpc.CallOption) (*LeaseTokensResp, error) {
+	out := new(LeaseTokensResp)
+	err := c.cc.Invoke(ctx, V1_LeaseTokens_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *v1Client) GetHotKeys(ctx context.Context, in *GetHotKeysReq, opts ...grpc.CallOption) (*GetHotKeysResp, error) {
+	out := new(GetHotKeysResp)
+	err := c.cc.Invoke(ctx, V1_GetHotKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *v1Client) GetTopology(ctx context.Context, in *GetTopologyReq, opts ...grpc.CallOption) (*GetTopologyResp, error) {
+	out := new(GetTopologyResp)
+	err := c.cc.Invoke(ctx, V1_GetTopology_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // V1Server is the server API for V1 service.
 // All implementations should embed UnimplementedV1Server
 // for forward compatibility
@@ -98,6 +156,24 @@ type V1Server interface {
 	HealthCheck(context.Context, *HealthCheckReq) (*HealthCheckResp, error)
 	// This method is used to determine if the server is running.
 	LiveCheck(context.Context, *LiveCheckReq) (*LiveCheckResp, error)
+	// Returns the list of peers this instance currently knows about. Clients
+	// use this to discover the cluster and send requests directly to the
+	// peer that owns the rate limit.
+	GetPeers(context.Context, *GetPeersReq) (*GetPeersResp, error)
+	// Lease a block of tokens from the owner of a rate limit. The leased tokens
+	// are removed from the rate limit and may be spent by the client without
+	// contacting the owner until the lease expires. Unused tokens from a previous
+	// lease may be returned to the rate limit in the same request.
+	LeaseTokens(context.Context, *LeaseTokensReq) (*LeaseTokensResp, error)
+	// Returns the rate limits this instance applied most often recently and the
+	// rate limits which were most often over the limit. Used to find the keys
+	// responsible for a busy worker.
+	GetHotKeys(context.Context, *GetHotKeysReq) (*GetHotKeysResp, error)
+	// Returns the local and region peers known by this instance along with the
+	// state of their connections, the peer which owns a rate limit, and whether
+	// every local peer agrees on the set of local peers. Used to debug requests
+	// which are sent to the wrong peer.
+	GetTopology(context.Context, *GetTopologyReq) (*GetTopologyResp, error)
 }
 
 // UnimplementedV1Server should be embedded to have forward compatible implementations.
@@ -113,6 +189,18 @@ func (UnimplementedV1Server) HealthCheck(context.Context, *HealthCheckReq) (*Hea
 func (UnimplementedV1Server) LiveCheck(context.Context, *LiveCheckReq) (*LiveCheckResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LiveCheck not implemented")
 }
+func (UnimplementedV1Server) GetPeers(context.Context, *GetPeersReq) (*GetPeersResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeers not implemented")
+}
+func (UnimplementedV1Server) LeaseTokens(context.Context, *LeaseTokensReq) (*LeaseTokensResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaseTokens not implemented")
+}
+func (UnimplementedV1Server) GetHotKeys(context.Context, *GetHotKeysReq) (*GetHotKeysResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHotKeys not implemented")
+}
+func (UnimplementedV1Server) GetTopology(context.Context, *GetTopologyReq) (*GetTopologyResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTopology not implemented")
+}
 
 // UnsafeV1Server may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to V1Server will
@@ -179,6 +267,78 @@ func _V1_LiveCheck_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _V1_GetPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).GetPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: V1_GetPeers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).GetPeers(ctx, req.(*GetPeersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _V1_LeaseTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseTokensReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).LeaseTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: V1_LeaseTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).LeaseTokens(ctx, req.(*LeaseTokensReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _V1_GetHotKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHotKeysReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).GetHotKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: V1_GetHotKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).GetHotKeys(ctx, req.(*GetHotKeysReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _V1_GetTopology_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopologyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).GetTopology(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: V1_GetTopology_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).GetTopology(ctx, req.(*GetTopologyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // V1_ServiceDesc is the grpc.ServiceDesc for V1 service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -198,6 +358,22 @@ var V1_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "LiveCheck",
 			Handler:    _V1_LiveCheck_Handler,
 		},
+		{
+			MethodName: "GetPeers",
+			Handler:    _V1_GetPeers_Handler,
+		},
+		{
+			MethodName: "LeaseTokens",
+			Handler:    _V1_LeaseTokens_Handler,
+		},
+		{
+			MethodName: "GetHotKeys",
+			Handler:    _V1_GetHotKeys_Handler,
+		},
+		{
+			MethodName: "GetTopology",
+			Handler:    _V1_GetTopology_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "gubernator.proto",
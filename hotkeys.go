@@ -0,0 +1,259 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricHotKeyCount = prometheus.NewDesc(
+	"gubernator_hot_key_count",
+	"The estimated recent count of the rate limits this instance applied most often. Label \"type\" = checks|over_limit.",
+	[]string{"type", "name", "key"}, nil,
+)
+
+// The number of shards of each shardedTopK, such that the workers recording checks of
+// different rate limits rarely wait for each other
+const hotKeysShards = 16
+
+// hotKeys tracks the rate limits which are checked and over the limit most often
+type hotKeys struct {
+	checks    *shardedTopK
+	overLimit *shardedTopK
+}
+
+func newHotKeys(capacity int, halfLife time.Duration) *hotKeys {
+	if capacity <= 0 {
+		return &hotKeys{}
+	}
+	return &hotKeys{
+		checks:    newShardedTopK(capacity, halfLife),
+		overLimit: newShardedTopK(capacity, halfLife),
+	}
+}
+
+// record counts a check of the rate limit and whether it was over the limit
+func (h *hotKeys) record(r *RateLimitReq, resp *RateLimitResp) {
+	if h.checks == nil {
+		return
+	}
+	h.checks.record(r.Name, r.UniqueKey)
+	if resp.Status == Status_OVER_LIMIT {
+		h.overLimit.record(r.Name, r.UniqueKey)
+	}
+}
+
+// get returns at most `limit` of the hot keys of each type
+func (h *hotKeys) get(limit int) *GetHotKeysResp {
+	var resp GetHotKeysResp
+	if h.checks == nil {
+		return &resp
+	}
+	resp.Checks = h.checks.top(limit)
+	resp.OverLimit = h.overLimit.top(limit)
+	return &resp
+}
+
+// collect reports at most `limit` hot keys of each type, such that the cardinality of the metric is bounded
+func (h *hotKeys) collect(ch chan<- prometheus.Metric, limit int) {
+	resp := h.get(limit)
+	for _, k := range resp.Checks {
+		ch <- prometheus.MustNewConstMetric(metricHotKeyCount, prometheus.GaugeValue,
+			float64(k.Count), "checks", k.Name, k.UniqueKey)
+	}
+	for _, k := range resp.OverLimit {
+		ch <- prometheus.MustNewConstMetric(metricHotKeyCount, prometheus.GaugeValue,
+			float64(k.Count), "over_limit", k.Name, k.UniqueKey)
+	}
+}
+
+// shardedTopK spreads the keys over topK shards by their hash. Checks of a rate limit are
+// always counted by the same shard, so the counts of each key are those of a single topK.
+// Each shard tracks up to `capacity` keys, such that the hot keys are found even if most of
+// them belong to the same shard.
+type shardedTopK struct {
+	shards [hotKeysShards]*topK
+}
+
+func newShardedTopK(capacity int, halfLife time.Duration) *shardedTopK {
+	var s shardedTopK
+	for i := range s.shards {
+		s.shards[i] = newTopK(capacity, halfLife)
+	}
+	return &s
+}
+
+func (s *shardedTopK) record(name, key string) {
+	id := name + "_" + key
+	s.shards[xxhash.ChecksumString64(id)%hotKeysShards].record(name, key)
+}
+
+// top returns at most `limit` keys of all the shards in descending order of their count
+func (s *shardedTopK) top(limit int) []*HotKey {
+	var keys []*HotKey
+	for _, shard := range s.shards {
+		keys = append(keys, shard.top(limit)...)
+	}
+	return sortHotKeys(keys, limit)
+}
+
+// topK estimates the most frequent keys in a stream using the Space-Saving algorithm, see
+// https://www.cs.ucsb.edu/sites/default/files/documents/2005-23.pdf
+// At most `capacity` keys are counted. Once full, a new key replaces the key with the lowest
+// count and inherits its count, which is recorded as the error of the new key. Counts are
+// halved every half life, such that the tracker reports keys which are hot now.
+type topK struct {
+	mutex    sync.Mutex
+	capacity int
+	halfLife time.Duration
+	decayAt  time.Time
+	entries  map[string]*topKEntry
+	// Min heap of the entries ordered by count
+	heap topKHeap
+}
+
+type topKEntry struct {
+	name  string
+	key   string
+	count int64
+	err   int64
+	index int
+}
+
+func newTopK(capacity int, halfLife time.Duration) *topK {
+	return &topK{
+		capacity: capacity,
+		halfLife: halfLife,
+		decayAt:  clock.Now().Add(halfLife),
+		entries:  make(map[string]*topKEntry, capacity),
+	}
+}
+
+func (t *topK) record(name, key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.decay()
+
+	id := name + "_" + key
+	if e, ok := t.entries[id]; ok {
+		e.count++
+		heap.Fix(&t.heap, e.index)
+		return
+	}
+
+	if len(t.heap) < t.capacity {
+		e := &topKEntry{name: name, key: key, count: 1}
+		t.entries[id] = e
+		heap.Push(&t.heap, e)
+		return
+	}
+
+	// Replace the entry with the lowest count
+	e := t.heap[0]
+	delete(t.entries, e.name+"_"+e.key)
+	e.name, e.key, e.err = name, key, e.count
+	e.count++
+	t.entries[id] = e
+	heap.Fix(&t.heap, 0)
+}
+
+// decay halves the counts once for every half life which passed. GUARDED_BY(mutex)
+func (t *topK) decay() {
+	now := clock.Now()
+	if now.Before(t.decayAt) {
+		return
+	}
+	n := int64(now.Sub(t.decayAt)/t.halfLife) + 1
+	t.decayAt = t.decayAt.Add(time.Duration(n) * t.halfLife)
+
+	// Halving preserves the order of the heap
+	shift := uint(63)
+	if n < 63 {
+		shift = uint(n)
+	}
+	for _, e := range t.heap {
+		e.count >>= shift
+		e.err >>= shift
+	}
+}
+
+// top returns at most `limit` keys in descending order of their count
+func (t *topK) top(limit int) []*HotKey {
+	t.mutex.Lock()
+	t.decay()
+	keys := make([]*HotKey, 0, len(t.heap))
+	for _, e := range t.heap {
+		if e.count == 0 {
+			continue
+		}
+		keys = append(keys, &HotKey{
+			Name:      e.name,
+			UniqueKey: e.key,
+			Count:     e.count,
+			Error:     e.err,
+		})
+	}
+	t.mutex.Unlock()
+	return sortHotKeys(keys, limit)
+}
+
+// sortHotKeys sorts the keys in descending order of their count and returns at most `limit` keys
+func sortHotKeys(keys []*HotKey, limit int) []*HotKey {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Error < keys[j].Error
+	})
+	if limit < 0 {
+		limit = 0
+	}
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+type topKHeap []*topKEntry
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *topKHeap) Push(x interface{}) {
+	e := x.(*topKEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topKHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopK(t *testing.T) {
+	t.Run("Hot keys survive a stream of unique keys", func(t *testing.T) {
+		tk := newTopK(10, time.Minute)
+		for i := 0; i < 1000; i++ {
+			tk.record("name", "hot")
+			if i%2 == 0 {
+				tk.record("name", "warm")
+			}
+			tk.record("name", fmt.Sprintf("unique-%d", i))
+		}
+
+		top := tk.top(2)
+		require.Len(t, top, 2)
+		assert.Equal(t, "hot", top[0].UniqueKey)
+		assert.Equal(t, "warm", top[1].UniqueKey)
+		// The error bounds the overestimate of the count
+		assert.GreaterOrEqual(t, top[0].Count, int64(1000))
+		assert.LessOrEqual(t, top[0].Count-top[0].Error, int64(1000))
+		assert.GreaterOrEqual(t, top[1].Count, int64(500))
+		assert.LessOrEqual(t, top[1].Count-top[1].Error, int64(500))
+		assert.Len(t, tk.top(100), 10)
+	})
+
+	t.Run("Counts decay every half life", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		tk := newTopK(10, time.Minute)
+		for i := 0; i < 100; i++ {
+			tk.record("name", "old")
+		}
+
+		clock.Advance(time.Minute)
+		assert.Equal(t, int64(50), tk.top(1)[0].Count)
+
+		// Recent checks outweigh older checks
+		for i := 0; i < 60; i++ {
+			tk.record("name", "new")
+		}
+		assert.Equal(t, "new", tk.top(1)[0].UniqueKey)
+
+		clock.Advance(2 * time.Minute)
+		assert.Equal(t, int64(15), tk.top(1)[0].Count)
+
+		// Keys with a count of zero are not reported
+		clock.Advance(time.Hour)
+		assert.Empty(t, tk.top(10))
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		tk := newTopK(10, time.Minute)
+		for i := 0; i < 5; i++ {
+			tk.record("name", fmt.Sprintf("key-%d", i))
+		}
+		assert.Empty(t, tk.top(-1))
+		assert.Empty(t, tk.top(0))
+		assert.Len(t, tk.top(3), 3)
+		assert.Len(t, tk.top(1000), 5)
+	})
+
+	t.Run("Sharded", func(t *testing.T) {
+		h := newHotKeys(4, time.Minute)
+		for i := 0; i < 1000; i++ {
+			h.record(&RateLimitReq{Name: "name", UniqueKey: "hot"}, &RateLimitResp{})
+			if i%4 == 0 {
+				h.record(&RateLimitReq{Name: "name", UniqueKey: "warm"}, &RateLimitResp{Status: Status_OVER_LIMIT})
+			}
+			h.record(&RateLimitReq{Name: "name", UniqueKey: fmt.Sprintf("unique-%d", i)}, &RateLimitResp{})
+		}
+
+		// The top keys of every shard are merged
+		resp := h.get(2)
+		require.Len(t, resp.Checks, 2)
+		assert.Equal(t, "hot", resp.Checks[0].UniqueKey)
+		assert.Equal(t, "warm", resp.Checks[1].UniqueKey)
+		require.Len(t, resp.OverLimit, 1)
+		assert.Equal(t, "warm", resp.OverLimit[0].UniqueKey)
+		assert.Len(t, h.get(1000).Checks, 4*hotKeysShards)
+		assert.Empty(t, h.get(-1).Checks)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		h := newHotKeys(-1, time.Minute)
+		h.record(&RateLimitReq{Name: "name", UniqueKey: "key"}, &RateLimitResp{Status: Status_OVER_LIMIT})
+		resp := h.get(10)
+		assert.Empty(t, resp.Checks)
+		assert.Empty(t, resp.OverLimit)
+	})
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// requestIDCache remembers the response to each rate limit request with a `request_id` for
+// a bounded window, such that retries of a request which already reached this instance do
+// not consume more hits.
+type requestIDCache struct {
+	mutex   sync.Mutex
+	window  int64
+	maxSize int
+	// Entries in the order they were added, which is also the order they expire in
+	ll      *list.List
+	entries map[string]*list.Element
+	// Requests which are currently being applied, closed once the response is known
+	pending map[string]chan struct{}
+}
+
+type requestIDEntry struct {
+	key      string
+	resp     *RateLimitResp
+	expireAt int64
+}
+
+func newRequestIDCache(window time.Duration, maxSize int) *requestIDCache {
+	return &requestIDCache{
+		window:  window.Milliseconds(),
+		maxSize: maxSize,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+		pending: make(map[string]chan struct{}),
+	}
+}
+
+// requestIDKey returns the key the response to `r` is remembered by, or an empty
+// string if the request has no `request_id`.
+func requestIDKey(r *RateLimitReq) string {
+	if r.RequestId == "" {
+		return ""
+	}
+	return requestIDKeyOf(r.HashKey(), r.RequestId)
+}
+
+// requestIDKeyOf returns the key the response to the request with `requestID` for the rate
+// limit with `hashKey` is remembered by
+func requestIDKeyOf(hashKey, requestID string) string {
+	return hashKey + "_" + requestID
+}
+
+// apply calls `fn` unless a response for `key` is remembered, in which case a copy of the remembered
+// response is returned instead. If a request with the same key is currently being applied, apply
+// waits for it to complete. The response is only remembered if `fn` returns no error.
+func (c *requestIDCache) apply(ctx context.Context, key string, fn func() (*RateLimitResp, error)) (*RateLimitResp, error) {
+	for {
+		c.mutex.Lock()
+		now := MillisecondNow()
+		if ele, ok := c.entries[key]; ok {
+			e := ele.Value.(*requestIDEntry)
+			if e.expireAt > now {
+				c.mutex.Unlock()
+				metricRequestIDDuplicateCounter.Inc()
+				return proto.Clone(e.resp).(*RateLimitResp), nil
+			}
+			c.removeElement(ele)
+		}
+
+		wait, ok := c.pending[key]
+		if !ok {
+			break
+		}
+		c.mutex.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.pending[key] = make(chan struct{})
+	c.mutex.Unlock()
+
+	resp, err := fn()
+	c.complete(key, resp, err)
+	return resp, err
+}
+
+// claim marks the request as being applied and returns true, unless a response for `key` is
+// remembered or a request with the same key is being applied. A claimed request must be
+// completed with complete().
+func (c *requestIDCache) claim(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if ele, ok := c.entries[key]; ok {
+		if ele.Value.(*requestIDEntry).expireAt > MillisecondNow() {
+			metricRequestIDDuplicateCounter.Inc()
+			return false
+		}
+		c.removeElement(ele)
+	}
+	if _, ok := c.pending[key]; ok {
+		metricRequestIDDuplicateCounter.Inc()
+		return false
+	}
+	c.pending[key] = make(chan struct{})
+	return true
+}
+
+// complete remembers the response to a request being applied unless it failed, and wakes the
+// requests waiting for it.
+func (c *requestIDCache) complete(key string, resp *RateLimitResp, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if done, ok := c.pending[key]; ok {
+		delete(c.pending, key)
+		close(done)
+	}
+	if err == nil && resp.Error == "" {
+		c.add(key, proto.Clone(resp).(*RateLimitResp), MillisecondNow())
+	}
+}
+
+// add remembers the response, evicting expired or the oldest entries. GUARDED_BY(mutex)
+func (c *requestIDCache) add(key string, resp *RateLimitResp, now int64) {
+	for ele := c.ll.Back(); ele != nil; ele = c.ll.Back() {
+		if ele.Value.(*requestIDEntry).expireAt > now && c.ll.Len() < c.maxSize {
+			break
+		}
+		c.removeElement(ele)
+	}
+
+	c.entries[key] = c.ll.PushFront(&requestIDEntry{
+		key:      key,
+		resp:     resp,
+		expireAt: now + c.window,
+	})
+}
+
+// removeElement GUARDED_BY(mutex)
+func (c *requestIDCache) removeElement(ele *list.Element) {
+	c.ll.Remove(ele)
+	delete(c.entries, ele.Value.(*requestIDEntry).key)
+}
@@ -50,7 +50,12 @@ func (i *Interval) run(d clock.Duration) {
 		select {
 		case <-i.in:
 			time.Sleep(d)
-			i.C <- struct{}{}
+			// The previous tick may not have been read if the consumer has stopped
+			select {
+			case i.C <- struct{}{}:
+			case <-done:
+				return false
+			}
 			return true
 		case <-done:
 			return false
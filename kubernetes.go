@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	"github.com/mailgun/holster/v4/setter"
 	"github.com/pkg/errors"
@@ -42,6 +43,10 @@ type K8sPool struct {
 	done        chan struct{}
 }
 
+// K8sWeightAnnotation is the pod annotation which sets the `PeerInfo.Weight` of the
+// gubernator instance in the pod. Requires the `pods` watch mechanism.
+const K8sWeightAnnotation = "gubernator.io/weight"
+
 type WatchMechanism string
 
 const (
@@ -195,7 +200,10 @@ main:
 			e.log.Errorf("expected type v1.Endpoints got '%s' instead", reflect.TypeOf(obj).String())
 		}
 
-		peer := PeerInfo{GRPCAddress: fmt.Sprintf("%s:%s", pod.Status.PodIP, e.conf.PodPort)}
+		peer := PeerInfo{
+			GRPCAddress: fmt.Sprintf("%s:%s", pod.Status.PodIP, e.conf.PodPort),
+			Weight:      e.podWeight(pod),
+		}
 		if pod.Status.PodIP == e.conf.PodIP {
 			peer.IsOwner = true
 		}
@@ -218,6 +226,21 @@ main:
 	e.conf.OnUpdate(peers)
 }
 
+// podWeight returns the weight set by the K8sWeightAnnotation of the pod, or 0 if not set
+func (e *K8sPool) podWeight(pod *api_v1.Pod) int {
+	v, ok := pod.Annotations[K8sWeightAnnotation]
+	if !ok {
+		return 0
+	}
+	weight, err := strconv.Atoi(v)
+	if err != nil || weight < 0 || weight > MaxPeerWeight {
+		e.log.Errorf("pod '%s' has an invalid '%s' annotation '%s'; expected an integer between 0 and %d",
+			pod.Name, K8sWeightAnnotation, v, MaxPeerWeight)
+		return 0
+	}
+	return weight
+}
+
 func (e *K8sPool) updatePeersFromEndpoints() {
 	e.log.Debug("Fetching peer list from endpoints API")
 	var peers []PeerInfo
@@ -0,0 +1,144 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/mailgun/holster/v4/clock"
+)
+
+// How often expired leases are removed from the leaseTracker
+const leaseSweepInterval = clock.Second
+
+// leaseTracker remembers the token leases granted by this instance, such that unused
+// tokens of a lease can only be returned once, and only before the lease expires.
+type leaseTracker struct {
+	mutex     sync.Mutex
+	leases    map[string]tokenLease
+	nextSweep int64
+}
+
+type tokenLease struct {
+	key      string
+	tokens   int64
+	expireAt int64
+}
+
+func newLeaseTracker() *leaseTracker {
+	return &leaseTracker{
+		leases: make(map[string]tokenLease),
+	}
+}
+
+// grant records a lease of tokens for the provided key and returns the id of the lease.
+func (t *leaseTracker) grant(key string, tokens, expireAt, now int64) string {
+	id := newRandomID()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.leases[id] = tokenLease{
+		key:      key,
+		tokens:   tokens,
+		expireAt: expireAt,
+	}
+	if now >= t.nextSweep {
+		t.sweep(now)
+		t.nextSweep = now + leaseSweepInterval.Milliseconds()
+	}
+	return id
+}
+
+// release removes the lease and returns the number of tokens which may be returned to
+// the rate limit, along with the removed lease such that it can be restored if the tokens
+// could not be returned. No tokens are returned if the lease is unknown, belongs to another
+// key or has expired, as the tokens of an expired lease are considered spent.
+func (t *leaseTracker) release(id, key string, returned, now int64) (int64, *tokenLease) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	l, ok := t.leases[id]
+	if !ok || l.key != key {
+		return 0, nil
+	}
+	delete(t.leases, id)
+
+	if l.expireAt <= now {
+		metricLeaseTokenCounter.WithLabelValues("expired").Add(float64(l.tokens))
+		return 0, nil
+	}
+	if returned < 0 {
+		returned = 0
+	}
+	if returned > l.tokens {
+		returned = l.tokens
+	}
+	return returned, &l
+}
+
+// restore adds back a lease removed by release
+func (t *leaseTracker) restore(id string, l *tokenLease) {
+	if l == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.leases[id] = *l
+}
+
+// sweep removes all the expired leases. GUARDED_BY(mutex)
+func (t *leaseTracker) sweep(now int64) {
+	for id, l := range t.leases {
+		if l.expireAt <= now {
+			metricLeaseTokenCounter.WithLabelValues("expired").Add(float64(l.tokens))
+			delete(t.leases, id)
+		}
+	}
+}
+
+// newRandomID returns a random 128-bit hex encoded id
+func newRandomID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// leaseExpiration returns the time a lease granted at `now` expires.
+func leaseExpiration(r *LeaseReq, rl *RateLimitResp, now int64) (int64, error) {
+	duration := r.RateLimit.Duration
+	if HasBehavior(r.RateLimit.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		d, err := GregorianDuration(clock.Now(), duration)
+		if err != nil {
+			return 0, err
+		}
+		duration = d
+	}
+
+	if r.Duration > 0 && r.Duration < duration {
+		duration = r.Duration
+	}
+	expireAt := now + duration
+
+	// Tokens leased from a token bucket cannot be returned once the bucket has reset
+	if r.RateLimit.Algorithm == Algorithm_TOKEN_BUCKET && rl.ResetTime != 0 && rl.ResetTime < expireAt {
+		expireAt = rl.ResetTime
+	}
+	return expireAt, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// failingEngine fails every lease. Unless the error is a notAppliedError, the lease is applied
+// first, as when the context expires while waiting for the response of the worker.
+type failingEngine struct {
+	CacheEngine
+	err error
+}
+
+func (e *failingEngine) LeaseTokens(ctx context.Context, req *LeaseReq, returned int64) (*RateLimitResp, int64, error) {
+	if _, ok := e.err.(notAppliedError); !ok {
+		_, _, _ = e.CacheEngine.LeaseTokens(ctx, req, returned)
+	}
+	return nil, 0, e.err
+}
+
+func TestLeaseReturnFailed(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		err       error
+		remaining int64
+	}{
+		{
+			name: "Applied",
+			err:  context.DeadlineExceeded,
+			// The tokens were returned, so returning them again is ignored
+			remaining: 90,
+		},
+		{
+			name: "Not applied",
+			err:  notAppliedError{context.DeadlineExceeded},
+			// The tokens were not returned, so they may be returned again
+			remaining: 90,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewV1Instance(Config{GRPCServers: []*grpc.Server{grpc.NewServer()}})
+			require.NoError(t, err)
+			defer s.Close()
+
+			lease := func(tokens int64, returnID string) *LeaseResp {
+				createdAt := MillisecondNow()
+				return s.leaseLocalTokens(context.Background(), &LeaseReq{
+					RateLimit: &RateLimitReq{
+						Name:      t.Name(),
+						UniqueKey: "key",
+						Algorithm: Algorithm_TOKEN_BUCKET,
+						Limit:     100,
+						Duration:  Minute,
+						CreatedAt: &createdAt,
+					},
+					Tokens:        tokens,
+					ReturnLeaseId: returnID,
+					Returned:      10,
+				})
+			}
+
+			first := lease(10, "")
+			require.Empty(t, first.Error)
+			second := lease(10, "")
+			require.Empty(t, second.Error)
+			assert.Equal(t, int64(80), second.RateLimit.Remaining)
+
+			engine := s.workerPool
+			s.workerPool = &failingEngine{CacheEngine: engine, err: tt.err}
+			resp := lease(0, first.LeaseId)
+			assert.Contains(t, resp.Error, context.DeadlineExceeded.Error())
+			s.workerPool = engine
+
+			resp = lease(0, first.LeaseId)
+			require.Empty(t, resp.Error)
+			assert.Equal(t, tt.remaining, resp.RateLimit.Remaining)
+		})
+	}
+}
@@ -20,7 +20,12 @@ package gubernator
 
 import (
 	"container/list"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/setter"
@@ -34,15 +39,31 @@ type LRUCache struct {
 	ll        *list.List
 	cacheSize int
 	cacheLen  int64
+	maxBytes  int64
+	bytes     int64
+	// The next element Sweep() inspects, nil to start over from the oldest element
+	sweepCursor *list.Element
+	stats       CacheStats
 }
 
 // LRUCacheCollector provides prometheus metrics collector for LRUCache.
 // Register only one collector, add one or more caches to this collector.
 type LRUCacheCollector struct {
-	caches []Cache
+	// Caches are added while collecting when the WorkerPool is resized
+	mutex  sync.Mutex
+	caches []collectedCache
+	// The number of caches added without a name, used to name the next one
+	unnamed int
+}
+
+// collectedCache is a cache and the name of the worker or shard which owns it
+type collectedCache struct {
+	name  string
+	cache Cache
 }
 
 var _ Cache = &LRUCache{}
+var _ cacheStatsProvider = &LRUCache{}
 var _ prometheus.Collector = &LRUCacheCollector{}
 
 var metricCacheSize = prometheus.NewGauge(prometheus.GaugeOpts{
@@ -57,6 +78,16 @@ var metricCacheUnexpiredEvictions = prometheus.NewCounter(prometheus.CounterOpts
 	Name: "gubernator_unexpired_evictions_count",
 	Help: "Count the number of cache items which were evicted while unexpired.",
 })
+var metricCacheBytes = prometheus.NewDesc(
+	"gubernator_cache_bytes",
+	"The estimated bytes used by the items in the cache of each worker.",
+	[]string{"worker"}, nil,
+)
+
+// The estimated bytes used by a CacheItem in the LRUCache excluding the key and the value;
+// the CacheItem, its list.Element and its entry in the map.
+const cacheItemOverhead = int64(unsafe.Sizeof(CacheItem{})) + int64(unsafe.Sizeof(list.Element{})) +
+	int64(unsafe.Sizeof("")) + int64(unsafe.Sizeof(&list.Element{})) + 16
 
 // NewLRUCache creates a new Cache with a maximum size.
 func NewLRUCache(maxSize int) *LRUCache {
@@ -69,6 +100,30 @@ func NewLRUCache(maxSize int) *LRUCache {
 	}
 }
 
+// SetMaxBytes limits the estimated bytes used by the items in the cache, the least recently
+// used items are evicted once either the max size or max bytes is exceeded. A `maxBytes` of 0
+// means the bytes are not limited.
+func (c *LRUCache) SetMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+// CacheItemBytes returns the estimated bytes used by the item while stored in the cache
+func CacheItemBytes(item *CacheItem) int64 {
+	size := cacheItemOverhead + int64(len(item.Key))
+	switch v := item.Value.(type) {
+	case *TokenBucketItem:
+		size += int64(unsafe.Sizeof(*v))
+	case *LeakyBucketItem:
+		size += int64(unsafe.Sizeof(*v))
+	case string:
+		size += int64(len(v))
+	case []byte:
+		size += int64(len(v))
+	}
+	return size
+}
+
 // Each is not thread-safe. Each() maintains a goroutine that iterates.
 // Other go routines cannot safely access the Cache while iterating.
 // It would be safer if this were done using an iterator or delegate pattern
@@ -89,19 +144,40 @@ func (c *LRUCache) Add(item *CacheItem) bool {
 	// If the key already exist, set the new value
 	if ee, ok := c.cache[item.Key]; ok {
 		c.ll.MoveToFront(ee)
+		c.addBytes(CacheItemBytes(item) - CacheItemBytes(ee.Value.(*CacheItem)))
 		ee.Value = item
+		c.evict()
 		return true
 	}
 
 	ele := c.ll.PushFront(item)
 	c.cache[item.Key] = ele
-	if c.cacheSize != 0 && c.ll.Len() > c.cacheSize {
-		c.removeOldest()
-	}
+	c.addBytes(CacheItemBytes(item))
+	c.evict()
 	atomic.StoreInt64(&c.cacheLen, int64(c.ll.Len()))
 	return false
 }
 
+// evict removes the oldest items until the cache is within its limits. The most
+// recently added item is never evicted.
+func (c *LRUCache) evict() {
+	for c.ll.Len() > 1 {
+		if c.cacheSize != 0 && c.ll.Len() > c.cacheSize {
+			c.removeOldest()
+			continue
+		}
+		if c.maxBytes != 0 && c.bytes > c.maxBytes {
+			c.removeOldest()
+			continue
+		}
+		return
+	}
+}
+
+func (c *LRUCache) addBytes(n int64) {
+	atomic.StoreInt64(&c.bytes, c.bytes+n)
+}
+
 // MillisecondNow returns unix epoch in milliseconds
 func MillisecondNow() int64 {
 	return clock.Now().UnixNano() / 1000000
@@ -115,15 +191,19 @@ func (c *LRUCache) GetItem(key string) (item *CacheItem, ok bool) {
 		if entry.IsExpired() {
 			c.removeElement(ele)
 			metricCacheAccess.WithLabelValues("miss").Add(1)
+			atomic.AddInt64(&c.stats.Expired, 1)
+			atomic.AddInt64(&c.stats.Misses, 1)
 			return
 		}
 
 		metricCacheAccess.WithLabelValues("hit").Add(1)
+		atomic.AddInt64(&c.stats.Hits, 1)
 		c.ll.MoveToFront(ele)
 		return entry, true
 	}
 
 	metricCacheAccess.WithLabelValues("miss").Add(1)
+	atomic.AddInt64(&c.stats.Misses, 1)
 	return
 }
 
@@ -142,6 +222,9 @@ func (c *LRUCache) removeOldest() {
 
 		if MillisecondNow() < entry.ExpireAt {
 			metricCacheUnexpiredEvictions.Add(1)
+			atomic.AddInt64(&c.stats.Evicted, 1)
+		} else {
+			atomic.AddInt64(&c.stats.Expired, 1)
 		}
 
 		c.removeElement(ele)
@@ -152,14 +235,77 @@ func (c *LRUCache) removeElement(e *list.Element) {
 	c.ll.Remove(e)
 	kv := e.Value.(*CacheItem)
 	delete(c.cache, kv.Key)
+	c.addBytes(-CacheItemBytes(kv))
 	atomic.StoreInt64(&c.cacheLen, int64(c.ll.Len()))
 }
 
+// Sweep removes the items expired at now from the cache, starting where the previous call stopped. Stops once
+// all items have been inspected or the budget is spent, returns the number of items removed.
+func (c *LRUCache) Sweep(now time.Time, budget time.Duration) int {
+	if c.ll == nil {
+		return 0
+	}
+	// The budget is spent in real time, while now is usually the time of the sweep ticker, such
+	// that the sweeper doesn't read the clock from its own goroutine.
+	start := time.Now()
+	nowMs := now.UnixNano() / 1000000
+
+	// Start over if the cursor was removed from the cache since the last sweep
+	ele := c.sweepCursor
+	if ele == nil || c.cache[ele.Value.(*CacheItem).Key] != ele {
+		ele = c.ll.Back()
+	}
+
+	var swept int
+	for i := 1; ele != nil; i++ {
+		// Checking the clock is expensive relative to inspecting an item
+		if i%64 == 0 && time.Since(start) >= budget {
+			break
+		}
+		prev := ele.Prev()
+		item := ele.Value.(*CacheItem)
+		if item.ExpireAt < nowMs || (item.InvalidAt != 0 && item.InvalidAt < nowMs) {
+			c.removeElement(ele)
+			swept++
+		}
+		ele = prev
+	}
+	c.sweepCursor = ele
+	atomic.AddInt64(&c.stats.Expired, int64(swept))
+	return swept
+}
+
 // Size returns the number of items in the cache.
 func (c *LRUCache) Size() int64 {
 	return atomic.LoadInt64(&c.cacheLen)
 }
 
+// Bytes returns the estimated bytes used by the items in the cache.
+func (c *LRUCache) Bytes() int64 {
+	return atomic.LoadInt64(&c.bytes)
+}
+
+// Stats returns the counts of accesses to the cache. Unlike other methods, Stats is safe to
+// call concurrently.
+func (c *LRUCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:        atomic.LoadInt64(&c.stats.Hits),
+		Misses:      atomic.LoadInt64(&c.stats.Misses),
+		StoreHits:   atomic.LoadInt64(&c.stats.StoreHits),
+		StoreMisses: atomic.LoadInt64(&c.stats.StoreMisses),
+		Expired:     atomic.LoadInt64(&c.stats.Expired),
+		Evicted:     atomic.LoadInt64(&c.stats.Evicted),
+	}
+}
+
+func (c *LRUCache) countStoreAccess(found bool) {
+	if found {
+		atomic.AddInt64(&c.stats.StoreHits, 1)
+		return
+	}
+	atomic.AddInt64(&c.stats.StoreMisses, 1)
+}
+
 // UpdateExpiration updates the expiration time for the key
 func (c *LRUCache) UpdateExpiration(key string, expireAt int64) bool {
 	if ele, hit := c.cache[key]; hit {
@@ -174,18 +320,43 @@ func (c *LRUCache) Close() error {
 	c.cache = nil
 	c.ll = nil
 	c.cacheLen = 0
+	c.bytes = 0
+	c.sweepCursor = nil
 	return nil
 }
 
 func NewLRUCacheCollector() *LRUCacheCollector {
 	return &LRUCacheCollector{
-		caches: []Cache{},
+		caches: []collectedCache{},
 	}
 }
 
-// AddCache adds a Cache object to be tracked by the collector.
+// AddCache adds a Cache object to be tracked by the collector. The per worker metrics of the
+// cache are labeled with a generated `worker` name, see AddNamedCache().
 func (collector *LRUCacheCollector) AddCache(cache Cache) {
-	collector.caches = append(collector.caches, cache)
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	name := "cache-" + strconv.Itoa(collector.unnamed)
+	collector.unnamed++
+	collector.caches = append(collector.caches, collectedCache{name: name, cache: cache})
+}
+
+// AddNamedCache adds a Cache object to be tracked by the collector. The per worker metrics
+// of the cache are labeled with `worker`, the name of the worker or shard which owns it.
+func (collector *LRUCacheCollector) AddNamedCache(worker string, cache Cache) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.caches = append(collector.caches, collectedCache{name: worker, cache: cache})
+}
+
+// RemoveCache stops tracking a Cache previously added with AddCache or AddNamedCache, such as
+// the cache of a worker retired by WorkerPool.Resize().
+func (collector *LRUCacheCollector) RemoveCache(cache Cache) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.caches = slices.DeleteFunc(collector.caches, func(cc collectedCache) bool {
+		return cc.cache == cache
+	})
 }
 
 // Describe fetches prometheus metrics to be registered
@@ -193,21 +364,55 @@ func (collector *LRUCacheCollector) Describe(ch chan<- *prometheus.Desc) {
 	metricCacheSize.Describe(ch)
 	metricCacheAccess.Describe(ch)
 	metricCacheUnexpiredEvictions.Describe(ch)
+	ch <- metricCacheBytes
+	ch <- metricCacheWorkerAccess
+	ch <- metricCacheWorkerRemoved
+	metricCacheNameAccess.Describe(ch)
 }
 
 // Collect fetches metric counts and gauges from the cache
 func (collector *LRUCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
 	metricCacheSize.Set(collector.getSize())
 	metricCacheSize.Collect(ch)
 	metricCacheAccess.Collect(ch)
 	metricCacheUnexpiredEvictions.Collect(ch)
+
+	for _, cc := range collector.caches {
+		if c, ok := cc.cache.(interface{ Bytes() int64 }); ok {
+			ch <- prometheus.MustNewConstMetric(metricCacheBytes, prometheus.GaugeValue,
+				float64(c.Bytes()), cc.name)
+		}
+		if c, ok := cc.cache.(cacheStatsProvider); ok {
+			collector.collectStats(ch, cc.name, c.Stats())
+		}
+	}
+	metricCacheNameAccess.Collect(ch)
+}
+
+func (collector *LRUCacheCollector) collectStats(ch chan<- prometheus.Metric, worker string, stats CacheStats) {
+	for _, m := range []struct {
+		desc  *prometheus.Desc
+		label string
+		value int64
+	}{
+		{metricCacheWorkerAccess, "hit", stats.Hits},
+		{metricCacheWorkerAccess, "miss", stats.Misses},
+		{metricCacheWorkerAccess, "store_hit", stats.StoreHits},
+		{metricCacheWorkerAccess, "store_miss", stats.StoreMisses},
+		{metricCacheWorkerRemoved, "expired", stats.Expired},
+		{metricCacheWorkerRemoved, "evicted", stats.Evicted},
+	} {
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.CounterValue, float64(m.value), worker, m.label)
+	}
 }
 
 func (collector *LRUCacheCollector) getSize() float64 {
 	var size float64
 
-	for _, cache := range collector.caches {
-		size += float64(cache.Size())
+	for _, cc := range collector.caches {
+		size += float64(cc.cache.Size())
 	}
 
 	return size
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -325,7 +326,7 @@ func TestLRUCache(t *testing.T) {
 
 				for i := 0; i < iterations; i++ {
 					// Get metrics.
-					ch := make(chan prometheus.Metric, 10)
+					ch := make(chan prometheus.Metric, 64)
 					collector.Collect(ch)
 				}
 			}()
@@ -426,6 +427,166 @@ func TestLRUCache(t *testing.T) {
 		assert.Contains(t, m.Desc().String(), "gubernator_unexpired_evictions_count")
 		assert.Equal(t, 1, int(*met.Counter.Value))
 	})
+
+	t.Run("Evict by bytes", func(t *testing.T) {
+		newItem := func(key string) *gubernator.CacheItem {
+			return &gubernator.CacheItem{
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Key:       key,
+				Value:     &gubernator.TokenBucketItem{Limit: 10},
+				ExpireAt:  expireAt,
+			}
+		}
+		itemBytes := gubernator.CacheItemBytes(newItem("key-00"))
+
+		cacheCollector := gubernator.NewLRUCacheCollector()
+		cache := gubernator.NewLRUCache(0)
+		cache.SetMaxBytes(itemBytes * 5)
+		cacheCollector.AddNamedCache("3", cache)
+
+		for i := 0; i < 10; i++ {
+			cache.Add(newItem(fmt.Sprintf("key-%02d", i)))
+		}
+		assert.Equal(t, int64(5), cache.Size())
+		assert.Equal(t, itemBytes*5, cache.Bytes())
+
+		// The least recently used items were evicted
+		_, ok := cache.GetItem("key-04")
+		assert.False(t, ok)
+		_, ok = cache.GetItem("key-05")
+		assert.True(t, ok)
+
+		// Longer keys use more bytes
+		cache.Add(newItem("a-much-longer-key"))
+		assert.Equal(t, int64(4), cache.Size())
+		_, ok = cache.GetItem("key-05")
+		assert.True(t, ok)
+		_, ok = cache.GetItem("key-06")
+		assert.False(t, ok)
+
+		cache.Remove("key-05")
+		assert.Equal(t, itemBytes*2+gubernator.CacheItemBytes(newItem("a-much-longer-key")), cache.Bytes())
+
+		// Bytes are reported per worker
+		collChan := make(chan prometheus.Metric, 64)
+		cacheCollector.Collect(collChan)
+		close(collChan)
+		var found bool
+		for m := range collChan {
+			if !strings.Contains(m.Desc().String(), "gubernator_cache_bytes") {
+				continue
+			}
+			met := new(dto.Metric)
+			require.NoError(t, m.Write(met))
+			assert.Equal(t, float64(cache.Bytes()), met.GetGauge().GetValue())
+			assert.Equal(t, "3", met.GetLabel()[0].GetValue())
+			found = true
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Sweep expired items", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		cache := gubernator.NewLRUCache(0)
+
+		// Every other item expires
+		for i := 0; i < 200; i++ {
+			ttl := time.Hour
+			if i%2 == 0 {
+				ttl = time.Minute
+			}
+			cache.Add(&gubernator.CacheItem{
+				Key:      fmt.Sprintf("key-%03d", i),
+				Value:    i,
+				ExpireAt: clock.Now().Add(ttl).UnixMilli(),
+			})
+		}
+		// Invalidated items are also swept
+		cache.Add(&gubernator.CacheItem{
+			Key:       "invalid",
+			Value:     "value",
+			ExpireAt:  clock.Now().Add(time.Hour).UnixMilli(),
+			InvalidAt: clock.Now().Add(time.Minute).UnixMilli(),
+		})
+
+		assert.Equal(t, 0, cache.Sweep(clock.Now(), time.Second))
+		clock.Advance(2 * time.Minute)
+
+		// The frozen clock never spends the budget of the first 64 items
+		swept := cache.Sweep(clock.Now(), 0)
+		assert.Equal(t, 32, swept)
+		assert.Equal(t, int64(201-32), cache.Size())
+
+		// The next sweep continues where the previous one stopped
+		swept += cache.Sweep(clock.Now(), time.Second)
+		assert.Equal(t, 101, swept)
+		assert.Equal(t, int64(100), cache.Size())
+		_, ok := cache.GetItem("key-000")
+		assert.False(t, ok)
+		_, ok = cache.GetItem("key-199")
+		assert.True(t, ok)
+		_, ok = cache.GetItem("invalid")
+		assert.False(t, ok)
+
+		// Starts over once every item was inspected
+		assert.Equal(t, 0, cache.Sweep(clock.Now(), time.Second))
+	})
+
+	t.Run("Stats by worker", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		cacheCollector := gubernator.NewLRUCacheCollector()
+		cache := gubernator.NewLRUCache(2)
+		// Labeled with the names of the workers rather than the order they were added
+		cacheCollector.AddNamedCache("7", gubernator.NewLRUCache(0))
+		cacheCollector.AddNamedCache("12", cache)
+
+		newItem := func(key string, ttl time.Duration) *gubernator.CacheItem {
+			return &gubernator.CacheItem{
+				Key:      key,
+				Value:    "value",
+				ExpireAt: clock.Now().Add(ttl).UnixMilli(),
+			}
+		}
+		cache.Add(newItem("short", time.Minute))
+		cache.Add(newItem("long", time.Hour))
+		_, ok := cache.GetItem("short")
+		assert.True(t, ok)
+		_, ok = cache.GetItem("unknown")
+		assert.False(t, ok)
+
+		// "long" was used least recently and is evicted before it expired
+		cache.Add(newItem("new", time.Hour))
+		clock.Advance(2 * time.Minute)
+		_, ok = cache.GetItem("short")
+		assert.False(t, ok)
+
+		assert.Equal(t, gubernator.CacheStats{
+			Hits:    1,
+			Misses:  2,
+			Expired: 1,
+			Evicted: 1,
+		}, cache.Stats())
+
+		collChan := make(chan prometheus.Metric, 64)
+		cacheCollector.Collect(collChan)
+		close(collChan)
+		values := make(map[string]float64)
+		for m := range collChan {
+			met := new(dto.Metric)
+			require.NoError(t, m.Write(met))
+			if met.Counter == nil || len(met.GetLabel()) != 2 {
+				continue
+			}
+			labels := met.GetLabel()
+			values[labels[1].GetValue()+"/"+labels[0].GetValue()] = met.GetCounter().GetValue()
+		}
+		assert.Equal(t, float64(1), values["12/hit"])
+		assert.Equal(t, float64(2), values["12/miss"])
+		assert.Equal(t, float64(0), values["12/store_hit"])
+		assert.Equal(t, float64(1), values["12/expired"])
+		assert.Equal(t, float64(1), values["12/evicted"])
+		assert.Equal(t, float64(0), values["7/hit"])
+	})
 }
 
 func BenchmarkLRUCache(b *testing.B) {
@@ -0,0 +1,162 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaglevTableSize is the size of the lookup table of the MaglevHash. A table
+// much larger than the number of peers assigns each peer a near equal share of the keys.
+const DefaultMaglevTableSize = 65537
+
+// Implements PeerPicker using Maglev hashing, see
+// https://research.google/pubs/maglev-a-fast-and-reliable-software-network-load-balancer/
+// Each peer fills the slots of a lookup table in the order of its own permutation of
+// the table, taking turns with the other peers until the table is full. On each turn a
+// peer claims as many slots as its weight. Get() is a single table lookup, and adding
+// or removing a peer reassigns few keys besides the keys of that peer.
+type MaglevHash struct {
+	hashFunc  HashString64
+	peers     map[string]*PeerClient
+	tableSize int
+	table     []*PeerClient
+}
+
+// NewMaglevHash creates a MaglevHash with a lookup table of `tableSize` slots. The size
+// is rounded up to a prime, such that the permutation of every peer visits every slot.
+func NewMaglevHash(fn HashString64, tableSize int) *MaglevHash {
+	mh := &MaglevHash{
+		hashFunc:  fn,
+		peers:     make(map[string]*PeerClient),
+		tableSize: nextPrime(tableSize),
+	}
+
+	if mh.hashFunc == nil {
+		mh.hashFunc = defaultHashString64
+	}
+	return mh
+}
+
+func (mh *MaglevHash) New() PeerPicker {
+	return &MaglevHash{
+		hashFunc:  mh.hashFunc,
+		peers:     make(map[string]*PeerClient),
+		tableSize: mh.tableSize,
+	}
+}
+
+func (mh *MaglevHash) Peers() []*PeerClient {
+	var results []*PeerClient
+	for _, v := range mh.peers {
+		results = append(results, v)
+	}
+	return results
+}
+
+// Adds a peer to the hash
+func (mh *MaglevHash) Add(peer *PeerClient) {
+	mh.peers[peer.Info().GRPCAddress] = peer
+	mh.populate()
+}
+
+// populate fills the lookup table with the current peers
+func (mh *MaglevHash) populate() {
+	// Peers take turns in the order of their address, such that every instance builds the same table
+	addrs := make([]string, 0, len(mh.peers))
+	for addr := range mh.peers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	size := uint64(mh.tableSize)
+	offsets := make([]uint64, len(addrs))
+	skips := make([]uint64, len(addrs))
+	next := make([]uint64, len(addrs))
+	for i, addr := range addrs {
+		hash := mh.hashFunc(addr)
+		offsets[i] = mixHash64(hash) % size
+		skips[i] = mixHash64(^hash)%(size-1) + 1
+	}
+
+	table := make([]*PeerClient, mh.tableSize)
+	for filled := 0; filled < mh.tableSize; {
+		for i, addr := range addrs {
+			peer := mh.peers[addr]
+			for n := 0; n < peer.Info().weight() && filled < mh.tableSize; n++ {
+				// Claim the next slot of the permutation of this peer which is not already taken
+				slot := (offsets[i] + next[i]*skips[i]) % size
+				for table[slot] != nil {
+					next[i]++
+					slot = (offsets[i] + next[i]*skips[i]) % size
+				}
+				table[slot] = peer
+				next[i]++
+				filled++
+			}
+		}
+	}
+	mh.table = table
+}
+
+// Ownership returns the fraction of the lookup table owned by each peer
+func (mh *MaglevHash) Ownership() map[string]float64 {
+	results := make(map[string]float64, len(mh.peers))
+	for _, peer := range mh.table {
+		results[peer.Info().GRPCAddress] += 1 / float64(mh.tableSize)
+	}
+	return results
+}
+
+// Returns number of peers in the picker
+func (mh *MaglevHash) Size() int {
+	return len(mh.peers)
+}
+
+// Returns the peer by hostname
+func (mh *MaglevHash) GetByPeerInfo(peer PeerInfo) *PeerClient {
+	return mh.peers[peer.GRPCAddress]
+}
+
+// Given a key, return the peer that key is assigned too
+func (mh *MaglevHash) Get(key string) (*PeerClient, error) {
+	if mh.Size() == 0 {
+		return nil, errors.New("unable to pick a peer; pool is empty")
+	}
+	return mh.table[mixHash64(mh.hashFunc(key))%uint64(mh.tableSize)], nil
+}
+
+// nextPrime returns the smallest prime greater than or equal to n
+func nextPrime(n int) int {
+	if n <= 2 {
+		return 2
+	}
+	for ; ; n++ {
+		prime := true
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
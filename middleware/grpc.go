@@ -0,0 +1,225 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCKeyFunc returns the rate limit key for the call to `fullMethod`. If the returned
+// key is empty the rate limit does not apply to the call.
+type GRPCKeyFunc func(ctx context.Context, fullMethod string) (string, error)
+
+// GRPCLimit is a rate limit applied to gRPC calls
+type GRPCLimit struct {
+	Limit
+
+	// (Required) Extracts the rate limit key from the call
+	Key GRPCKeyFunc
+
+	// (Optional) The full method names this rate limit applies to, for example
+	// "/pb.gubernator.V1/GetRateLimits". If empty the rate limit applies to all methods.
+	Methods []string
+}
+
+func (l GRPCLimit) appliesTo(fullMethod string) bool {
+	if len(l.Methods) == 0 {
+		return true
+	}
+	for _, m := range l.Methods {
+		if m == fullMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// GRPCConfig for GRPCInterceptor
+type GRPCConfig struct {
+	// (Required) The client used to check rate limits, typically from guber.DialV1Server()
+	Client guber.V1Client
+
+	// (Required) The rate limits applied to each call, the call is rejected with
+	// `ResourceExhausted` if any of the rate limits are over the limit.
+	Limits []GRPCLimit
+
+	// (Optional) If true, calls are allowed when the rate limits could not be checked.
+	// Otherwise, the call is rejected with `Unavailable`.
+	FailOpen bool
+
+	// (Optional) How long to wait for gubernator to respond. Defaults to 500ms
+	Timeout time.Duration
+
+	// (Optional) A Logger which implements the declared logger interface (typically *logrus.Entry)
+	Logger guber.FieldLogger
+}
+
+func (c *GRPCConfig) SetDefaults() error {
+	if c.Client == nil {
+		return errors.New("GRPCConfig.Client is required")
+	}
+	if len(c.Limits) == 0 {
+		return errors.New("at least one GRPCLimit is required")
+	}
+	for _, l := range c.Limits {
+		if err := l.validate(); err != nil {
+			return err
+		}
+		if l.Key == nil {
+			return fmt.Errorf("GRPCLimit.Key for '%s' is required", l.Name)
+		}
+	}
+
+	setter.SetDefault(&c.Timeout, time.Millisecond*500)
+	setter.SetDefault(&c.Logger, logrus.WithField("category", "gubernator-middleware"))
+	return nil
+}
+
+// GRPCInterceptor rate limits the calls to a gRPC server
+type GRPCInterceptor struct {
+	conf GRPCConfig
+	log  guber.FieldLogger
+}
+
+// NewGRPCInterceptor creates interceptors which apply the configured rate limits to each call.
+func NewGRPCInterceptor(conf GRPCConfig) (*GRPCInterceptor, error) {
+	if err := conf.SetDefaults(); err != nil {
+		return nil, err
+	}
+	return &GRPCInterceptor{
+		conf: conf,
+		log:  conf.Logger,
+	}, nil
+}
+
+// Unary returns a unary server interceptor, install with `grpc.ChainUnaryInterceptor()`
+func (i *GRPCInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := i.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns a stream server interceptor, install with `grpc.ChainStreamInterceptor()`.
+// The rate limits are checked once when the stream is opened.
+func (i *GRPCInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := i.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// check returns a status error if the call should be rejected. The rate limit
+// headers are sent as response header metadata.
+func (i *GRPCInterceptor) check(ctx context.Context, fullMethod string) error {
+	result, err := i.result(ctx, fullMethod)
+	if err != nil {
+		if i.conf.FailOpen {
+			i.log.WithError(err).WithField("method", fullMethod).
+				Warn("while checking rate limits; allowing call")
+			return nil
+		}
+		i.log.WithError(err).WithField("method", fullMethod).
+			Error("while checking rate limits; rejecting call")
+		return status.Error(codes.Unavailable, "unable to check rate limits")
+	}
+
+	if headers := result.Headers(); len(headers) != 0 {
+		md := metadata.MD{}
+		for k, v := range headers {
+			md.Set(k, v)
+		}
+		// Fails if the call is not a server call or headers were already sent; neither of
+		// which should stop the call.
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			i.log.WithError(err).Debug("while setting rate limit headers")
+		}
+	}
+
+	if result.OverLimit {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded; retry in %ss",
+			result.Headers()[HeaderRetryAfter])
+	}
+	return nil
+}
+
+func (i *GRPCInterceptor) result(ctx context.Context, fullMethod string) (Result, error) {
+	var reqs []*guber.RateLimitReq
+	for _, l := range i.conf.Limits {
+		if !l.appliesTo(fullMethod) {
+			continue
+		}
+		key, err := l.Key(ctx, fullMethod)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "while extracting key for '%s'", l.Name)
+		}
+		if key == "" {
+			continue
+		}
+		reqs = append(reqs, l.request(key))
+	}
+	return check(ctx, i.conf.Client, i.conf.Timeout, reqs)
+}
+
+// PeerIPKey uses the IP address of the client as the rate limit key
+func PeerIPKey(ctx context.Context, _ string) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", errors.New("no peer address found in context")
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String(), nil
+	}
+	return host, nil
+}
+
+// MetadataKey uses the first value of the named incoming metadata as the rate limit
+// key. Calls without the metadata are not rate limited by this key.
+func MetadataKey(name string) GRPCKeyFunc {
+	name = strings.ToLower(name)
+	return func(ctx context.Context, _ string) (string, error) {
+		values := metadata.ValueFromIncomingContext(ctx, name)
+		if len(values) == 0 {
+			return "", nil
+		}
+		return values[0], nil
+	}
+}
+
+// MethodKey uses the full method name of the call as the rate limit key
+func MethodKey(_ context.Context, fullMethod string) (string, error) {
+	return fullMethod, nil
+}
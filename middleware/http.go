@@ -0,0 +1,182 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPKeyFunc returns the rate limit key for the request. If the returned key is
+// empty the rate limit does not apply to the request.
+type HTTPKeyFunc func(r *http.Request) (string, error)
+
+// HTTPLimit is a rate limit applied to HTTP requests
+type HTTPLimit struct {
+	Limit
+
+	// (Required) Extracts the rate limit key from the request
+	Key HTTPKeyFunc
+}
+
+// HTTPConfig for HTTPMiddleware
+type HTTPConfig struct {
+	// (Required) The client used to check rate limits, typically from guber.DialV1Server()
+	Client guber.V1Client
+
+	// (Required) The rate limits applied to each request, the request is rejected if any
+	// of the rate limits are over the limit.
+	Limits []HTTPLimit
+
+	// (Optional) If true, requests are allowed when the rate limits could not be checked.
+	// Otherwise, OnError is called and the request is rejected.
+	FailOpen bool
+
+	// (Optional) How long to wait for gubernator to respond. Defaults to 500ms
+	Timeout time.Duration
+
+	// (Optional) Called when the request is over the limit. The rate limit headers have already been
+	// set on the response. Defaults to responding with `429 Too Many Requests`
+	OnLimited func(w http.ResponseWriter, r *http.Request, result Result)
+
+	// (Optional) Called when the rate limits could not be checked and FailOpen is false.
+	// Defaults to responding with `503 Service Unavailable`
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+
+	// (Optional) A Logger which implements the declared logger interface (typically *logrus.Entry)
+	Logger guber.FieldLogger
+}
+
+func (c *HTTPConfig) SetDefaults() error {
+	if c.Client == nil {
+		return errors.New("HTTPConfig.Client is required")
+	}
+	if len(c.Limits) == 0 {
+		return errors.New("at least one HTTPLimit is required")
+	}
+	for _, l := range c.Limits {
+		if err := l.validate(); err != nil {
+			return err
+		}
+		if l.Key == nil {
+			return fmt.Errorf("HTTPLimit.Key for '%s' is required", l.Name)
+		}
+	}
+
+	setter.SetDefault(&c.Timeout, time.Millisecond*500)
+	setter.SetDefault(&c.Logger, logrus.WithField("category", "gubernator-middleware"))
+	if c.OnLimited == nil {
+		c.OnLimited = func(w http.ResponseWriter, r *http.Request, _ Result) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+	if c.OnError == nil {
+		c.OnError = func(w http.ResponseWriter, r *http.Request, _ error) {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		}
+	}
+	return nil
+}
+
+// HTTPMiddleware rate limits requests to a `net/http` handler
+type HTTPMiddleware struct {
+	conf HTTPConfig
+	log  guber.FieldLogger
+}
+
+// NewHTTPMiddleware creates middleware which applies the configured rate limits to each request.
+// Wrap each route with its own middleware to apply different rate limits to different routes.
+func NewHTTPMiddleware(conf HTTPConfig) (*HTTPMiddleware, error) {
+	if err := conf.SetDefaults(); err != nil {
+		return nil, err
+	}
+	return &HTTPMiddleware{
+		conf: conf,
+		log:  conf.Logger,
+	}, nil
+}
+
+// Handler returns a handler which calls `next` only if the request is under all the rate limits.
+func (m *HTTPMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := m.check(r)
+		if err != nil {
+			if m.conf.FailOpen {
+				m.log.WithError(err).Warn("while checking rate limits; allowing request")
+				next.ServeHTTP(w, r)
+				return
+			}
+			m.log.WithError(err).Error("while checking rate limits; rejecting request")
+			m.conf.OnError(w, r, err)
+			return
+		}
+
+		for k, v := range result.Headers() {
+			w.Header().Set(k, v)
+		}
+		if result.OverLimit {
+			m.conf.OnLimited(w, r, result)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *HTTPMiddleware) check(r *http.Request) (Result, error) {
+	var reqs []*guber.RateLimitReq
+	for _, l := range m.conf.Limits {
+		key, err := l.Key(r)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "while extracting key for '%s'", l.Name)
+		}
+		if key == "" {
+			continue
+		}
+		reqs = append(reqs, l.request(key))
+	}
+	return check(r.Context(), m.conf.Client, m.conf.Timeout, reqs)
+}
+
+// RemoteIPKey uses the IP address of the client as the rate limit key. If the server is
+// behind a proxy, use HeaderKey() with the header the proxy sets instead.
+func RemoteIPKey(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr, nil
+	}
+	return host, nil
+}
+
+// HeaderKey uses the value of the named request header as the rate limit key. Requests
+// without the header are not rate limited by this key.
+func HeaderKey(name string) HTTPKeyFunc {
+	return func(r *http.Request) (string, error) {
+		return r.Header.Get(name), nil
+	}
+}
+
+// PathKey uses the URL path of the request as the rate limit key
+func PathKey(r *http.Request) (string, error) {
+	return r.URL.Path, nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package middleware provides `net/http` middleware and gRPC server interceptors which
+// derive rate limit keys from each request, check them with gubernator and reject the
+// request once any of the rate limits are over the limit.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
+)
+
+const (
+	// HeaderLimit is the response header containing the limit of the most restrictive rate limit
+	HeaderLimit = "RateLimit-Limit"
+	// HeaderRemaining is the response header containing the remaining hits of the most restrictive rate limit
+	HeaderRemaining = "RateLimit-Remaining"
+	// HeaderReset is the response header containing the number of seconds until the most
+	// restrictive rate limit resets
+	HeaderReset = "RateLimit-Reset"
+	// HeaderRetryAfter is the response header containing the number of seconds a client
+	// should wait before retrying a request which was over the limit
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Limit describes a rate limit applied to each request. The key of the rate limit
+// is derived from the request by the key extractor of the HTTPLimit or GRPCLimit.
+type Limit struct {
+	// (Required) The name of the rate limit, for example "requests_per_ip"
+	Name string
+
+	// (Required) The number of hits allowed within Duration
+	Limit int64
+
+	// (Required) The duration of the rate limit
+	Duration time.Duration
+
+	// (Optional) The number of hits each request counts against the rate limit. Defaults to 1
+	Hits int64
+
+	// (Optional) The algorithm used to calculate the rate limit. Defaults to TOKEN_BUCKET
+	Algorithm guber.Algorithm
+
+	// (Optional) The behavior of the rate limit
+	Behavior guber.Behavior
+
+	// (Optional) The burst of a LEAKY_BUCKET rate limit
+	Burst int64
+}
+
+func (l Limit) validate() error {
+	if l.Name == "" {
+		return errors.New("Limit.Name is required")
+	}
+	if l.Limit <= 0 {
+		return fmt.Errorf("Limit.Limit for '%s' must be greater than 0", l.Name)
+	}
+	if l.Duration <= 0 {
+		return fmt.Errorf("Limit.Duration for '%s' must be greater than 0", l.Name)
+	}
+	return nil
+}
+
+func (l Limit) request(key string) *guber.RateLimitReq {
+	hits := l.Hits
+	if hits == 0 {
+		hits = 1
+	}
+	return &guber.RateLimitReq{
+		Name:      l.Name,
+		UniqueKey: key,
+		Hits:      hits,
+		Limit:     l.Limit,
+		Duration:  l.Duration.Milliseconds(),
+		Algorithm: l.Algorithm,
+		Behavior:  l.Behavior,
+		Burst:     l.Burst,
+	}
+}
+
+// Result is the outcome of checking the rate limits of a request
+type Result struct {
+	// Is true if any of the rate limits are over the limit
+	OverLimit bool
+	// The response of the most restrictive rate limit; the first rate limit over the
+	// limit, or else the rate limit with the fewest hits remaining. Is nil if no rate
+	// limits applied to the request.
+	Response *guber.RateLimitResp
+}
+
+// Headers returns the standard rate limit response headers for the result
+func (r Result) Headers() map[string]string {
+	if r.Response == nil {
+		return nil
+	}
+
+	reset := resetSeconds(r.Response.ResetTime)
+	headers := map[string]string{
+		HeaderLimit:     strconv.FormatInt(r.Response.Limit, 10),
+		HeaderRemaining: strconv.FormatInt(r.Response.Remaining, 10),
+		HeaderReset:     strconv.FormatInt(reset, 10),
+	}
+	if r.OverLimit {
+		headers[HeaderRetryAfter] = strconv.FormatInt(reset, 10)
+	}
+	return headers
+}
+
+// check sends the rate limit requests to gubernator and returns the most restrictive result.
+func check(ctx context.Context, client guber.V1Client, timeout time.Duration, reqs []*guber.RateLimitReq) (Result, error) {
+	if len(reqs) == 0 {
+		return Result{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{Requests: reqs})
+	if err != nil {
+		return Result{}, errors.Wrap(err, "while checking rate limits")
+	}
+	if len(resp.Responses) != len(reqs) {
+		return Result{}, errors.New("server responded with incorrect rate limit list size")
+	}
+
+	var result Result
+	for i, rl := range resp.Responses {
+		if rl.Error != "" {
+			return Result{}, fmt.Errorf("while checking rate limit '%s': %s", reqs[i].Name, rl.Error)
+		}
+		if result.OverLimit {
+			continue
+		}
+		if rl.Status == guber.Status_OVER_LIMIT {
+			result = Result{OverLimit: true, Response: rl}
+			continue
+		}
+		if result.Response == nil || rl.Remaining < result.Response.Remaining {
+			result.Response = rl
+		}
+	}
+	return result, nil
+}
+
+// resetSeconds returns the number of whole seconds until the provided unix millisecond time
+func resetSeconds(resetTime int64) int64 {
+	ms := resetTime - clock.Now().UnixNano()/1_000_000
+	if ms <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(ms) / 1000))
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/gubernator-io/gubernator/v2/cluster"
+	"github.com/gubernator-io/gubernator/v2/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMain(m *testing.M) {
+	err := cluster.StartWith([]guber.PeerInfo{
+		{GRPCAddress: "127.0.0.1:9590", HTTPAddress: "127.0.0.1:9580"},
+		{GRPCAddress: "127.0.0.1:9591", HTTPAddress: "127.0.0.1:9581"},
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	cluster.Stop()
+	os.Exit(code)
+}
+
+func newClient(t *testing.T) guber.V1Client {
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+	return client
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func(h http.Handler, header string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/v1/resource", nil)
+		if header != "" {
+			r.Header.Set("X-Account", header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Over the limit", func(t *testing.T) {
+		m, err := middleware.NewHTTPMiddleware(middleware.HTTPConfig{
+			Client: newClient(t),
+			Limits: []middleware.HTTPLimit{
+				{
+					Limit: middleware.Limit{
+						Name:     "test_http_per_ip",
+						Limit:    100,
+						Duration: time.Minute,
+					},
+					Key: middleware.RemoteIPKey,
+				},
+				{
+					Limit: middleware.Limit{
+						Name:     "test_http_per_account",
+						Limit:    2,
+						Duration: time.Minute,
+					},
+					Key: middleware.HeaderKey("X-Account"),
+				},
+			},
+		})
+		require.NoError(t, err)
+		h := m.Handler(ok)
+		account := guber.RandomString(10)
+
+		w := send(h, account)
+		assert.Equal(t, http.StatusOK, w.Code)
+		// Headers describe the most restrictive rate limit
+		assert.Equal(t, "2", w.Header().Get(middleware.HeaderLimit))
+		assert.Equal(t, "1", w.Header().Get(middleware.HeaderRemaining))
+		assert.Equal(t, "60", w.Header().Get(middleware.HeaderReset))
+		assert.Empty(t, w.Header().Get(middleware.HeaderRetryAfter))
+
+		w = send(h, account)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get(middleware.HeaderRemaining))
+
+		w = send(h, account)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get(middleware.HeaderRemaining))
+		assert.NotEmpty(t, w.Header().Get(middleware.HeaderRetryAfter))
+
+		// Requests without the header are only limited by IP
+		w = send(h, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "100", w.Header().Get(middleware.HeaderLimit))
+	})
+
+	t.Run("Fail open", func(t *testing.T) {
+		// Nothing is listening on this address
+		client, err := guber.DialV1Server("127.0.0.1:1", nil)
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			failOpen bool
+			code     int
+		}{
+			{failOpen: true, code: http.StatusOK},
+			{failOpen: false, code: http.StatusServiceUnavailable},
+		} {
+			m, err := middleware.NewHTTPMiddleware(middleware.HTTPConfig{
+				Client:   client,
+				FailOpen: tc.failOpen,
+				Timeout:  time.Millisecond * 100,
+				Limits: []middleware.HTTPLimit{
+					{
+						Limit: middleware.Limit{Name: "test_http_fail_open", Limit: 1, Duration: time.Minute},
+						Key:   middleware.PathKey,
+					},
+				},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.code, send(m.Handler(ok), "").Code)
+		}
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := middleware.NewHTTPMiddleware(middleware.HTTPConfig{
+			Client: newClient(t),
+			Limits: []middleware.HTTPLimit{
+				{Limit: middleware.Limit{Name: "test_http_invalid", Limit: 1, Duration: time.Minute}},
+			},
+		})
+		assert.EqualError(t, err, "HTTPLimit.Key for 'test_http_invalid' is required")
+	})
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestGRPCInterceptor(t *testing.T) {
+	const method = "/test.Service/Method"
+	i, err := middleware.NewGRPCInterceptor(middleware.GRPCConfig{
+		Client: newClient(t),
+		Limits: []middleware.GRPCLimit{
+			{
+				Limit: middleware.Limit{
+					Name:     "test_grpc_per_account",
+					Limit:    1,
+					Duration: time.Minute,
+				},
+				Key:     middleware.MetadataKey("X-Account"),
+				Methods: []string{method},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	newCtx := func(account string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-account", account))
+	}
+
+	t.Run("Unary", func(t *testing.T) {
+		unary := i.Unary()
+		handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+		ctx := newCtx(guber.RandomString(10))
+
+		resp, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+
+		_, err = unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		// The rate limit does not apply to other methods
+		_, err = unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Other"}, handler)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream := i.Stream()
+		handler := func(srv any, ss grpc.ServerStream) error { return nil }
+		ss := &mockServerStream{ctx: newCtx(guber.RandomString(10))}
+
+		err := stream(nil, ss, &grpc.StreamServerInfo{FullMethod: method}, handler)
+		require.NoError(t, err)
+
+		err = stream(nil, ss, &grpc.StreamServerInfo{FullMethod: method}, handler)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("Fail closed", func(t *testing.T) {
+		// Nothing is listening on this address
+		client, err := guber.DialV1Server("127.0.0.1:1", nil)
+		require.NoError(t, err)
+
+		i, err := middleware.NewGRPCInterceptor(middleware.GRPCConfig{
+			Client:  client,
+			Timeout: time.Millisecond * 100,
+			Limits: []middleware.GRPCLimit{
+				{
+					Limit: middleware.Limit{Name: "test_grpc_fail_closed", Limit: 1, Duration: time.Minute},
+					Key:   middleware.MethodKey,
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = i.Unary()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) { return nil, nil })
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
@@ -20,12 +20,15 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/collections"
 	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
 	"github.com/mailgun/holster/v4/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -48,6 +51,57 @@ type PeerPicker interface {
 	Add(*PeerClient)
 }
 
+// peerOwnership is implemented by PeerPickers which can report the fraction of the keys owned by each peer
+type peerOwnership interface {
+	// Ownership returns the fraction of the keys owned by each peer by GRPC address
+	Ownership() map[string]float64
+}
+
+// pickerOwnership returns the fraction of the keys owned by each peer of the picker. The ownership
+// of pickers which can't report it is estimated by picking the peers of a sample of keys.
+func pickerOwnership(picker PeerPicker) map[string]float64 {
+	if o, ok := picker.(peerOwnership); ok {
+		return o.Ownership()
+	}
+
+	const samples = 10_000
+	results := make(map[string]float64)
+	for i := 0; i < samples; i++ {
+		peer, err := picker.Get(strconv.Itoa(i))
+		if err != nil {
+			break
+		}
+		results[peer.Info().GRPCAddress] += 1.0 / samples
+	}
+	return results
+}
+
+// peerLoadBalancer is implemented by PeerPickers which bound the load of each peer, using the load
+// every instance in the cluster observed on each peer
+type peerLoadBalancer interface {
+	// SampleLoad returns the rate of checks per second hashed to each peer by GRPC address since the
+	// previous sample, and keeps the result to be returned by ObservedLoad()
+	SampleLoad() map[string]float64
+	// ObservedLoad returns the result of the latest SampleLoad()
+	ObservedLoad() map[string]float64
+	// SetLoad sets the total rate of checks per second all instances hashed to each peer by GRPC address,
+	// and computes a new version of the shed table from it
+	SetLoad(map[string]float64)
+	// ShedTable returns the version of the shed table and the fraction of the keys of each peer by GRPC
+	// address which are moved to other peers. The version is 0 until the picker has a table.
+	ShedTable() (int64, map[string]float64)
+	// SetShedTable replaces the shed table with the table another instance computed with SetLoad()
+	SetShedTable(version int64, shed map[string]float64)
+}
+
+const (
+	// How long to wait for the peer to respond to the capabilities handshake
+	peerHandshakeTimeout = clock.Second * 5
+	// How long to wait before retrying a failed handshake, doubled after each failure
+	peerHandshakeRetryWait = clock.Second
+	peerHandshakeMaxWait   = clock.Minute
+)
+
 type PeerClient struct {
 	client      PeersV1Client
 	conn        *grpc.ClientConn
@@ -56,6 +110,16 @@ type PeerClient struct {
 	queueClosed atomic.Bool
 	lastErrs    *collections.LRUCache
 	isShutdown  atomic.Bool
+	// Multiplexes requests over a single stream to the peer, nil unless PeerStreaming is enabled
+	stream *peerStream
+	// The capabilities negotiated with the peer, nil until the handshake completed
+	capabilities atomic.Pointer[PeerCapabilities]
+	// Stops the handshake
+	cancel context.CancelFunc
+	// Fails requests to the peer while it's failing, nil unless PeerCircuitBreaker is enabled
+	breaker *circuitBreaker
+	// Adapts the timeout of requests to the peer, nil unless PeerAdaptiveTimeout is enabled
+	latency *latencyTracker
 
 	wgMutex sync.RWMutex
 	wg      sync.WaitGroup // Monitor the number of in-flight requests. GUARDED_BY(wgMutex)
@@ -84,6 +148,7 @@ type PeerConfig struct {
 // NewPeerClient tries to establish a connection to a peer in a non-blocking fashion.
 // If batching is enabled, it also starts a goroutine where batches will be processed.
 func NewPeerClient(conf PeerConfig) (*PeerClient, error) {
+	setter.SetDefault(&conf.Log, logrus.WithField("category", "gubernator"))
 	peerClient := &PeerClient{
 		queue:    make(chan *request, 1000),
 		conf:     conf,
@@ -97,6 +162,12 @@ func NewPeerClient(conf PeerConfig) (*PeerClient, error) {
 		}
 	}
 
+	if conf.Behavior.PeerCompression != CompressionNone {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(peerClient.compressUnary),
+			grpc.WithChainStreamInterceptor(peerClient.compressStream))
+	}
+
 	if conf.TLS != nil {
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(conf.TLS)))
 	} else {
@@ -110,6 +181,20 @@ func NewPeerClient(conf PeerConfig) (*PeerClient, error) {
 	}
 	peerClient.client = NewPeersV1Client(peerClient.conn)
 
+	if conf.Behavior.PeerStreaming {
+		peerClient.stream = newPeerStream(peerClient.client, conf)
+	}
+	if conf.Behavior.PeerCircuitBreaker {
+		peerClient.breaker = newCircuitBreaker(conf.Behavior, conf.Info.GRPCAddress)
+	}
+	if conf.Behavior.PeerAdaptiveTimeout {
+		peerClient.latency = newLatencyTracker(conf.Behavior, conf.Info.GRPCAddress)
+	}
+
+	var ctx context.Context
+	ctx, peerClient.cancel = context.WithCancel(context.Background())
+	go peerClient.handshake(ctx)
+
 	if !conf.Behavior.DisableBatching {
 		go peerClient.runBatch()
 	}
@@ -121,6 +206,67 @@ func (c *PeerClient) Info() PeerInfo {
 	return c.conf.Info
 }
 
+// Capabilities returns the capabilities negotiated with the peer
+func (c *PeerClient) Capabilities() *PeerCapabilities {
+	if caps := c.capabilities.Load(); caps != nil {
+		return caps
+	}
+	return &PeerCapabilities{GrpcAddress: c.conf.Info.GRPCAddress}
+}
+
+// HasCapability returns true if the handshake with the peer completed and the peer supports the capability
+func (c *PeerClient) HasCapability(capability string) bool {
+	return c.capabilities.Load().Has(capability)
+}
+
+// handshake negotiates the capabilities of the peer, retrying until it succeeds or the client shuts down
+func (c *PeerClient) handshake(ctx context.Context) {
+	wait := peerHandshakeRetryWait
+	for {
+		caps, err := c.getPeerCapabilities(ctx)
+		if err == nil {
+			c.capabilities.Store(caps)
+			return
+		}
+		// Errors are not recorded by setLastErr(), as the peer may not have started yet
+		c.conf.Log.WithError(err).WithField("peer", c.conf.Info.GRPCAddress).
+			Debug("while negotiating peer capabilities")
+
+		// The retry waits in real time, as the handshake runs on its own goroutine which must not
+		// read the clock, which tests may freeze at any time
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if wait *= 2; wait > peerHandshakeMaxWait {
+			wait = peerHandshakeMaxWait
+		}
+	}
+}
+
+func (c *PeerClient) getPeerCapabilities(ctx context.Context) (*PeerCapabilities, error) {
+	ctx, cancel := context.WithTimeout(ctx, peerHandshakeTimeout)
+	defer cancel()
+
+	resp, err := c.client.GetPeerCapabilities(ctx, &GetPeerCapabilitiesReq{})
+	if status.Code(err) == codes.Unimplemented {
+		// The peer runs a version without the handshake, which supports none of the capabilities
+		return &PeerCapabilities{GrpcAddress: c.conf.Info.GRPCAddress, Negotiated: true}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in client.GetPeerCapabilities")
+	}
+	return &PeerCapabilities{
+		GrpcAddress:     c.conf.Info.GRPCAddress,
+		Negotiated:      true,
+		ProtocolVersion: resp.ProtocolVersion,
+		Capabilities:    resp.Capabilities,
+	}, nil
+}
+
 // GetPeerRateLimit forwards a rate limit request to a peer. If the rate limit has `behavior == BATCHING` configured,
 // this method will attempt to batch the rate limits
 func (c *PeerClient) GetPeerRateLimit(ctx context.Context, r *RateLimitReq) (resp *RateLimitResp, err error) {
@@ -171,7 +317,7 @@ func (c *PeerClient) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 	c.wgMutex.Unlock()
 	defer c.wg.Done()
 
-	resp, err = c.client.GetPeerRateLimits(ctx, r)
+	resp, err = c.getPeerRateLimits(ctx, r)
 	if err != nil {
 		err = errors.Wrap(err, "Error in client.GetPeerRateLimits")
 		// metricCheckErrorCounter is updated within client.GetPeerRateLimits().
@@ -196,7 +342,7 @@ func (c *PeerClient) UpdatePeerGlobals(ctx context.Context, r *UpdatePeerGlobals
 	c.wgMutex.Unlock()
 	defer c.wg.Done()
 
-	resp, err = c.client.UpdatePeerGlobals(ctx, r)
+	resp, err = c.updatePeerGlobals(ctx, r)
 	if err != nil {
 		_ = c.setLastErr(err)
 	}
@@ -204,6 +350,203 @@ func (c *PeerClient) UpdatePeerGlobals(ctx context.Context, r *UpdatePeerGlobals
 	return resp, err
 }
 
+// getPeerRateLimits sends the requests on the stream to the peer, or as a unary request if the stream is unavailable
+func (c *PeerClient) getPeerRateLimits(ctx context.Context, r *GetPeerRateLimitsReq) (resp *GetPeerRateLimitsResp, err error) {
+	err = c.call(ctx, func(ctx context.Context) error {
+		if c.stream != nil && c.HasCapability(CapabilityPeerStream) {
+			sr, err := c.stream.call(ctx, &PeerStreamReq{
+				Request: &PeerStreamReq_GetPeerRateLimits{GetPeerRateLimits: r},
+			})
+			if err == nil {
+				resp = sr.GetGetPeerRateLimits()
+				return nil
+			}
+			if !errors.Is(err, errPeerStreamUnavailable) {
+				return err
+			}
+		}
+		resp, err = c.client.GetPeerRateLimits(ctx, r)
+		return err
+	})
+	return resp, err
+}
+
+// updatePeerGlobals sends the updates on the stream to the peer, or as a unary request if the stream is unavailable
+func (c *PeerClient) updatePeerGlobals(ctx context.Context, r *UpdatePeerGlobalsReq) (resp *UpdatePeerGlobalsResp, err error) {
+	err = c.call(ctx, func(ctx context.Context) error {
+		if c.stream != nil && c.HasCapability(CapabilityPeerStream) {
+			sr, err := c.stream.call(ctx, &PeerStreamReq{
+				Request: &PeerStreamReq_UpdatePeerGlobals{UpdatePeerGlobals: r},
+			})
+			if err == nil {
+				resp = sr.GetUpdatePeerGlobals()
+				return nil
+			}
+			if !errors.Is(err, errPeerStreamUnavailable) {
+				return err
+			}
+		}
+		resp, err = c.client.UpdatePeerGlobals(ctx, r)
+		return err
+	})
+	return resp, err
+}
+
+// call sends a request to the peer with send(), applying the circuit breaker and the adaptive
+// timeout of the peer when they are enabled
+func (c *PeerClient) call(ctx context.Context, send func(ctx context.Context) error) error {
+	if c.breaker == nil && c.latency == nil {
+		return send(ctx)
+	}
+
+	var probe bool
+	if c.breaker != nil {
+		var err error
+		if probe, err = c.breaker.allow(); err != nil {
+			return err
+		}
+	}
+
+	sendCtx := ctx
+	if c.latency != nil {
+		var cancel context.CancelFunc
+		sendCtx, cancel = context.WithTimeout(ctx, c.latency.get())
+		defer cancel()
+	}
+
+	start := clock.Now()
+	err := send(sendCtx)
+	latency := clock.Since(start)
+
+	// Requests canceled by the caller say nothing about the peer
+	canceled := errors.Is(ctx.Err(), context.Canceled)
+	if c.latency != nil && !canceled {
+		c.latency.observe(latency)
+	}
+	if c.breaker != nil {
+		c.breaker.record(probe, latency, err, canceled)
+	}
+	return err
+}
+
+// CircuitOpen returns true while the circuit breaker of the peer fails requests without sending them
+func (c *PeerClient) CircuitOpen() bool {
+	return c.breaker != nil && c.breaker.isOpen()
+}
+
+// CircuitBreaker returns the state of the circuit breaker and the timeout of the peer, nil unless
+// PeerCircuitBreaker or PeerAdaptiveTimeout is enabled
+func (c *PeerClient) CircuitBreaker() *PeerCircuitBreaker {
+	if c.breaker == nil && c.latency == nil {
+		return nil
+	}
+	cb := &PeerCircuitBreaker{
+		GrpcAddress: c.conf.Info.GRPCAddress,
+		State:       CircuitClosed,
+		TimeoutMs:   c.conf.Behavior.BatchTimeout.Milliseconds(),
+	}
+	if c.breaker != nil {
+		cb.State, cb.ErrorRate = c.breaker.status()
+	}
+	if c.latency != nil {
+		cb.TimeoutMs = c.latency.get().Milliseconds()
+	}
+	return cb
+}
+
+// UpdatePeerCounters sends the hits counted for GLOBAL_CRDT rate limits to a peer
+func (c *PeerClient) UpdatePeerCounters(ctx context.Context, r *UpdatePeerCountersReq) (resp *UpdatePeerCountersResp, err error) {
+	// See NOTE above about RLock and wg.Add(1)
+	c.wgMutex.Lock()
+	c.wg.Add(1)
+	c.wgMutex.Unlock()
+	defer c.wg.Done()
+
+	resp, err = c.client.UpdatePeerCounters(ctx, r)
+	if err != nil {
+		_ = c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// LeasePeerTokens sends token lease requests to the peer which owns the rate limits
+func (c *PeerClient) LeasePeerTokens(ctx context.Context, r *LeaseTokensReq) (resp *LeaseTokensResp, err error) {
+	// See NOTE above about RLock and wg.Add(1)
+	c.wgMutex.Lock()
+	c.wg.Add(1)
+	c.wgMutex.Unlock()
+	defer c.wg.Done()
+
+	err = c.call(ctx, func(ctx context.Context) error {
+		resp, err = c.client.LeasePeerTokens(ctx, r)
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error in client.LeasePeerTokens")
+		return nil, c.setLastErr(err)
+	}
+
+	// Unlikely, but this avoids a panic if something wonky happens
+	if len(resp.Responses) != len(r.Requests) {
+		err = errors.New("number of lease responses in peer response does not match request")
+		metricCheckErrorCounter.WithLabelValues("Item mismatch").Add(1)
+		return nil, c.setLastErr(err)
+	}
+	return resp, nil
+}
+
+// GetPeerLoad fetches the load the peer observed on each peer
+func (c *PeerClient) GetPeerLoad(ctx context.Context) (resp *GetPeerLoadResp, err error) {
+	// See NOTE above about RLock and wg.Add(1)
+	c.wgMutex.Lock()
+	c.wg.Add(1)
+	c.wgMutex.Unlock()
+	defer c.wg.Done()
+
+	resp, err = c.client.GetPeerLoad(ctx, &GetPeerLoadReq{})
+	if err != nil {
+		err = errors.Wrap(err, "Error in client.GetPeerLoad")
+		return nil, c.setLastErr(err)
+	}
+	return resp, nil
+}
+
+// GetPeerRingHash fetches the hash of the local peers known by the peer
+func (c *PeerClient) GetPeerRingHash(ctx context.Context) (resp *GetPeerRingHashResp, err error) {
+	// See NOTE above about RLock and wg.Add(1)
+	c.wgMutex.Lock()
+	c.wg.Add(1)
+	c.wgMutex.Unlock()
+	defer c.wg.Done()
+
+	resp, err = c.client.GetPeerRingHash(ctx, &GetPeerRingHashReq{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in client.GetPeerRingHash")
+	}
+	return resp, nil
+}
+
+// HandoffPeerRateLimits sends the rate limits this instance owned before it started draining to the peer
+func (c *PeerClient) HandoffPeerRateLimits(ctx context.Context, r *HandoffPeerRateLimitsReq) (resp *HandoffPeerRateLimitsResp, err error) {
+	// See NOTE above about RLock and wg.Add(1)
+	c.wgMutex.Lock()
+	c.wg.Add(1)
+	c.wgMutex.Unlock()
+	defer c.wg.Done()
+
+	resp, err = c.client.HandoffPeerRateLimits(ctx, r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in client.HandoffPeerRateLimits")
+	}
+	return resp, nil
+}
+
+// BatchQueueLength returns the number of requests waiting to be sent to the peer in a batch
+func (c *PeerClient) BatchQueueLength() int {
+	return len(c.queue)
+}
+
 func (c *PeerClient) setLastErr(err error) error {
 	// If we get a nil error return without caching it
 	if err == nil {
@@ -364,7 +707,7 @@ func (c *PeerClient) sendBatch(ctx context.Context, queue []*request) {
 	}
 
 	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, c.conf.Behavior.BatchTimeout)
-	resp, err := c.client.GetPeerRateLimits(timeoutCtx, &req)
+	resp, err := c.getPeerRateLimits(timeoutCtx, &req)
 	timeoutCancel()
 
 	// An error here indicates the entire request failed
@@ -413,6 +756,7 @@ func (c *PeerClient) Shutdown(ctx context.Context) error {
 
 	// ensure we don't leak goroutines, even if the Shutdown times out
 	defer c.conn.Close()
+	c.cancel()
 
 	waitChan := make(chan struct{})
 	go func() {
@@ -428,6 +772,10 @@ func (c *PeerClient) Shutdown(ctx context.Context) error {
 		c.queueClosed.Store(true)
 		close(c.queue)
 
+		if c.stream != nil {
+			c.stream.close()
+		}
+
 		close(waitChan)
 	}()
 
@@ -18,16 +18,24 @@ package gubernator_test
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gubernator-io/gubernator/v2"
 	"github.com/gubernator-io/gubernator/v2/cluster"
 	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
 )
 
 func TestPeerClientShutdown(t *testing.T) {
@@ -101,3 +109,404 @@ func TestPeerClientShutdown(t *testing.T) {
 		})
 	}
 }
+
+func TestPeerClientStream(t *testing.T) {
+	const threads = 10
+	createdAt := epochMillis(clock.Now())
+	d := cluster.DaemonAt(0)
+	config := gubernator.BehaviorConfig{
+		BatchTimeout:  clock.Second,
+		BatchWait:     clock.Millisecond,
+		BatchLimit:    100,
+		PeerStreaming: true,
+	}
+
+	metric := fmt.Sprintf(`gubernator_grpc_request_duration_count{method="%s"}`, gubernator.PeersV1_PeerStream_FullMethodName)
+	streams := func() int {
+		m, err := getMetricRequest(fmt.Sprintf("http://%s/metrics", d.Config().HTTPListenAddress), metric)
+		require.NoError(t, err)
+		if m == nil {
+			return 0
+		}
+		return int(m.Value)
+	}
+	before := streams()
+
+	client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info:     gubernator.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress},
+		Behavior: config,
+	})
+	require.NoError(t, err)
+
+	// The stream is used once the peer reported it supports the stream
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.True(t, client.HasCapability(gubernator.CapabilityPeerStream))
+	})
+
+	// Forwarded checks with and without batching share the stream
+	key := gubernator.RandomString(10)
+	var wg errgroup.Group
+	for i := 0; i < threads; i++ {
+		behavior := gubernator.Behavior_BATCHING
+		if i%2 == 0 {
+			behavior = gubernator.Behavior_NO_BATCHING
+		}
+		wg.Go(func() error {
+			resp, err := client.GetPeerRateLimit(context.Background(), &gubernator.RateLimitReq{
+				Name:      t.Name(),
+				UniqueKey: key,
+				Hits:      1,
+				Limit:     100,
+				Duration:  gubernator.Minute,
+				Behavior:  behavior,
+				CreatedAt: &createdAt,
+			})
+			if err != nil {
+				return err
+			}
+			if resp.Error != "" {
+				return errors.New(resp.Error)
+			}
+			return nil
+		})
+	}
+	require.NoError(t, wg.Wait())
+
+	resp, err := client.GetPeerRateLimits(context.Background(), &gubernator.GetPeerRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{{
+			Name:      t.Name(),
+			UniqueKey: key,
+			Limit:     100,
+			Duration:  gubernator.Minute,
+			CreatedAt: &createdAt,
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100-threads), resp.RateLimits[0].Remaining)
+
+	_, err = client.UpdatePeerGlobals(context.Background(), &gubernator.UpdatePeerGlobalsReq{
+		Globals: []*gubernator.UpdatePeerGlobal{{
+			Key:       gubernator.RandomString(10),
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Duration:  gubernator.Minute,
+			Status:    &gubernator.RateLimitResp{Limit: 100, Remaining: 50, ResetTime: createdAt + gubernator.Minute},
+			CreatedAt: createdAt,
+		}},
+	})
+	require.NoError(t, err)
+
+	// Every request was sent on a single stream, which ends when the client shuts down
+	require.NoError(t, client.Shutdown(context.Background()))
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.Equal(t, before+1, streams())
+	})
+}
+
+// unaryPeer is a peer running a version which doesn't support PeerStream
+type unaryPeer struct {
+	gubernator.UnimplementedPeersV1Server
+	requests atomic.Int32
+}
+
+func (p *unaryPeer) GetPeerRateLimits(_ context.Context, r *gubernator.GetPeerRateLimitsReq) (*gubernator.GetPeerRateLimitsResp, error) {
+	p.requests.Add(1)
+	resp := &gubernator.GetPeerRateLimitsResp{}
+	for _, req := range r.Requests {
+		resp.RateLimits = append(resp.RateLimits, &gubernator.RateLimitResp{Limit: req.Limit, Remaining: req.Limit - req.Hits})
+	}
+	return resp, nil
+}
+
+func TestPeerClientStreamUnsupported(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	peer := &unaryPeer{}
+	srv := grpc.NewServer()
+	gubernator.RegisterPeersV1Server(srv, peer)
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Stop()
+
+	client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info: gubernator.PeerInfo{GRPCAddress: listener.Addr().String()},
+		Behavior: gubernator.BehaviorConfig{
+			DisableBatching: true,
+			PeerStreaming:   true,
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.Shutdown(context.Background()) }()
+
+	// The peer doesn't support the handshake, so it supports none of the capabilities
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.True(t, client.Capabilities().Negotiated)
+	})
+	assert.Zero(t, client.Capabilities().ProtocolVersion)
+	assert.Empty(t, client.Capabilities().Capabilities)
+
+	// Requests are sent unary, as the peer doesn't support the stream
+	for i := 0; i < 3; i++ {
+		resp, err := client.GetPeerRateLimit(context.Background(), &gubernator.RateLimitReq{
+			Name:      t.Name(),
+			UniqueKey: "key",
+			Hits:      1,
+			Limit:     10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), resp.Remaining)
+	}
+	assert.Equal(t, int32(3), peer.requests.Load())
+}
+
+// stalledPeer is a peer which accepts the stream, but stops reading from it
+type stalledPeer struct {
+	gubernator.UnimplementedPeersV1Server
+	requests atomic.Int32
+}
+
+func (p *stalledPeer) GetPeerCapabilities(context.Context, *gubernator.GetPeerCapabilitiesReq) (*gubernator.GetPeerCapabilitiesResp, error) {
+	return &gubernator.GetPeerCapabilitiesResp{
+		ProtocolVersion: gubernator.PeerProtocolVersion,
+		Capabilities:    []string{gubernator.CapabilityPeerStream},
+	}, nil
+}
+
+func (p *stalledPeer) PeerStream(stream gubernator.PeersV1_PeerStreamServer) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func (p *stalledPeer) GetPeerRateLimits(_ context.Context, r *gubernator.GetPeerRateLimitsReq) (*gubernator.GetPeerRateLimitsResp, error) {
+	p.requests.Add(1)
+	resp := &gubernator.GetPeerRateLimitsResp{}
+	for _, req := range r.Requests {
+		resp.RateLimits = append(resp.RateLimits, &gubernator.RateLimitResp{Limit: req.Limit, Remaining: req.Limit - req.Hits})
+	}
+	return resp, nil
+}
+
+func TestPeerClientStreamStalled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	peer := &stalledPeer{}
+	srv := grpc.NewServer()
+	gubernator.RegisterPeersV1Server(srv, peer)
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Stop()
+
+	client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info: gubernator.PeerInfo{GRPCAddress: listener.Addr().String()},
+		Behavior: gubernator.BehaviorConfig{
+			DisableBatching: true,
+			PeerStreaming:   true,
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.Shutdown(context.Background()) }()
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.True(t, client.HasCapability(gubernator.CapabilityPeerStream))
+	})
+
+	// The first request fills the flow control window of the stream, so sending the next
+	// request blocks until the peer reads from the stream, which it never does. Each request
+	// fails once the caller's context expires.
+	for i := 0; i < 2; i++ {
+		done := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), clock.Millisecond*200)
+			defer cancel()
+			_, err := client.GetPeerRateLimits(ctx, &gubernator.GetPeerRateLimitsReq{
+				Requests: []*gubernator.RateLimitReq{{
+					Name:      strings.Repeat("a", 1<<20),
+					UniqueKey: "key",
+					Hits:      1,
+					Limit:     10,
+				}},
+			})
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(5 * time.Second):
+			t.Fatal("request to the stalled peer did not return once its context expired")
+		}
+	}
+
+	// The failed stream is replaced by unary requests, which the peer answers
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*5)
+	defer cancel()
+	resp, err := client.GetPeerRateLimit(ctx, &gubernator.RateLimitReq{
+		Name:      t.Name(),
+		UniqueKey: "key",
+		Hits:      1,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), resp.Remaining)
+	assert.Equal(t, int32(1), peer.requests.Load())
+}
+
+func TestPeerClientCircuitBreaker(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	// Nothing listens on the address, so every request to the peer fails
+	client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info: gubernator.PeerInfo{GRPCAddress: "127.0.0.1:9299"},
+		Behavior: gubernator.BehaviorConfig{
+			DisableBatching:           true,
+			PeerCircuitBreaker:        true,
+			CircuitBreakerMinRequests: 2,
+			CircuitBreakerOpenWait:    clock.Second,
+		},
+	})
+	require.NoError(t, err)
+	defer client.Shutdown(context.Background())
+
+	send := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*5)
+		defer cancel()
+		_, err := client.GetPeerRateLimits(ctx, &gubernator.GetPeerRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{{Name: "test", UniqueKey: "key", Limit: 10}},
+		})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		err := send()
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "circuit breaker is open")
+	}
+	assert.True(t, client.CircuitOpen())
+	assert.Equal(t, gubernator.CircuitOpen, client.CircuitBreaker().State)
+	assert.Equal(t, 1.0, client.CircuitBreaker().ErrorRate)
+
+	// While open, requests fail without being sent
+	err = send()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit breaker is open")
+
+	// Once open for CircuitBreakerOpenWait a single request probes the peer, which still fails
+	clock.Advance(clock.Second * 2)
+	assert.False(t, client.CircuitOpen())
+	err = send()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "circuit breaker is open")
+	assert.True(t, client.CircuitOpen())
+
+	err = send()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit breaker is open")
+}
+
+func TestPeerClientAdaptiveTimeout(t *testing.T) {
+	client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info: cluster.GetRandomPeer(cluster.DataCenterNone),
+		Behavior: gubernator.BehaviorConfig{
+			BatchTimeout:        clock.Second,
+			DisableBatching:     true,
+			PeerAdaptiveTimeout: true,
+		},
+	})
+	require.NoError(t, err)
+	defer client.Shutdown(context.Background())
+
+	// Until enough latencies are observed, the timeout is the BatchTimeout
+	assert.Equal(t, int64(1000), client.CircuitBreaker().TimeoutMs)
+	assert.Equal(t, gubernator.CircuitClosed, client.CircuitBreaker().State)
+
+	for i := 0; i < 64; i++ {
+		_, err := client.GetPeerRateLimits(context.Background(), &gubernator.GetPeerRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{{Name: t.Name(), UniqueKey: "key", Limit: 1000, Duration: 1000}},
+		})
+		require.NoError(t, err)
+	}
+
+	// The peer responds well within the BatchTimeout, so the timeout adapts to its latency
+	assert.Less(t, client.CircuitBreaker().TimeoutMs, int64(1000))
+}
+
+// compressedPeer is a peer which negotiates it supports compression
+type compressedPeer struct {
+	unaryPeer
+}
+
+func (p *compressedPeer) GetPeerCapabilities(_ context.Context, _ *gubernator.GetPeerCapabilitiesReq) (*gubernator.GetPeerCapabilitiesResp, error) {
+	return &gubernator.GetPeerCapabilitiesResp{
+		ProtocolVersion: gubernator.PeerProtocolVersion,
+		Capabilities:    []string{gubernator.CapabilityCompression},
+	}, nil
+}
+
+// compressionStats records the codec which compressed the last GetPeerRateLimits request
+type compressionStats struct {
+	compression atomic.Value
+}
+
+func (s *compressionStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s *compressionStats) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	if h, ok := rs.(*stats.InHeader); ok && strings.HasSuffix(h.FullMethod, "/GetPeerRateLimits") {
+		s.compression.Store(h.Compression)
+	}
+}
+
+func (s *compressionStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *compressionStats) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestPeerClientCompression(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Peer        gubernator.PeersV1Server
+		Compression string
+		Expected    string
+	}{
+		{"Gzip", &compressedPeer{}, gubernator.CompressionGzip, gubernator.CompressionGzip},
+		{"Snappy", &compressedPeer{}, gubernator.CompressionSnappy, gubernator.CompressionSnappy},
+		{"Zstd", &compressedPeer{}, gubernator.CompressionZstd, gubernator.CompressionZstd},
+		// The peer runs a version without compression, so requests are sent uncompressed
+		{"Unsupported", &unaryPeer{}, gubernator.CompressionZstd, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			handler := &compressionStats{}
+			srv := grpc.NewServer(grpc.StatsHandler(handler))
+			gubernator.RegisterPeersV1Server(srv, c.Peer)
+			go func() { _ = srv.Serve(listener) }()
+			defer srv.Stop()
+
+			client, err := gubernator.NewPeerClient(gubernator.PeerConfig{
+				Info: gubernator.PeerInfo{GRPCAddress: listener.Addr().String()},
+				Behavior: gubernator.BehaviorConfig{
+					BatchTimeout:    clock.Second,
+					BatchWait:       clock.Millisecond,
+					BatchLimit:      100,
+					PeerCompression: c.Compression,
+				},
+			})
+			require.NoError(t, err)
+			defer func() { _ = client.Shutdown(context.Background()) }()
+
+			testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+				assert.True(t, client.Capabilities().Negotiated)
+			})
+
+			resp, err := client.GetPeerRateLimit(context.Background(), &gubernator.RateLimitReq{
+				Name:      t.Name(),
+				UniqueKey: "key",
+				Hits:      1,
+				Limit:     10,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(9), resp.Remaining)
+			assert.Equal(t, c.Expected, handler.compression.Load())
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"net"
+	"testing"
+
+	"github.com/segmentio/fasthash/fnv1"
+	"github.com/segmentio/fasthash/fnv1a"
+	"github.com/stretchr/testify/assert"
+)
+
+// hashPicker is a PeerPicker which assigns keys to peers by hashing the keys
+type hashPicker interface {
+	PeerPicker
+	peerOwnership
+	Size() int
+}
+
+var hashPickers = []struct {
+	name      string
+	newPicker func(HashString64) hashPicker
+	// The number of keys assigned to each peer by each hash function
+	distribution map[string]map[string]int
+	// The max number of keys reassigned to peers other than a new peer
+	maxDisruption int
+}{{
+	name:      "MaglevHash",
+	newPicker: func(fn HashString64) hashPicker { return NewMaglevHash(fn, DefaultMaglevTableSize) },
+	distribution: map[string]map[string]int{
+		"default":        {"a.svc.local": 3303, "b.svc.local": 3377, "c.svc.local": 3320},
+		"fasthash/fnv1a": {"a.svc.local": 3348, "b.svc.local": 3346, "c.svc.local": 3306},
+		"fasthash/fnv1":  {"a.svc.local": 3303, "b.svc.local": 3377, "c.svc.local": 3320},
+	},
+	// Fewer than 1% of the keys besides those of the new peer are reassigned
+	maxDisruption: 99,
+}, {
+	name:      "RendezvousHash",
+	newPicker: func(fn HashString64) hashPicker { return NewRendezvousHash(fn) },
+	distribution: map[string]map[string]int{
+		"default":        {"a.svc.local": 3340, "b.svc.local": 3326, "c.svc.local": 3334},
+		"fasthash/fnv1a": {"a.svc.local": 3317, "b.svc.local": 3335, "c.svc.local": 3348},
+		"fasthash/fnv1":  {"a.svc.local": 3340, "b.svc.local": 3326, "c.svc.local": 3334},
+	},
+	// Only the keys of the new peer are reassigned
+	maxDisruption: 0,
+}}
+
+var hashFuncs = []struct {
+	name string
+	fn   HashString64
+}{
+	{name: "default"},
+	{name: "fasthash/fnv1a", fn: fnv1a.HashString64},
+	{name: "fasthash/fnv1", fn: fnv1.HashString64},
+}
+
+func TestHashPickers(t *testing.T) {
+	hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
+	strings := make([]string, 10000)
+	for i := range strings {
+		ip := net.IPv4(192, 168, byte(i>>8), byte(i))
+		strings[i] = ip.String()
+	}
+
+	for _, tp := range hashPickers {
+		t.Run(tp.name, func(t *testing.T) {
+			t.Run("Size", func(t *testing.T) {
+				hash := tp.newPicker(nil)
+
+				for _, h := range hosts {
+					hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+				}
+
+				assert.Equal(t, len(hosts), hash.Size())
+			})
+
+			t.Run("Host", func(t *testing.T) {
+				hash := tp.newPicker(nil)
+				hostMap := map[string]*PeerClient{}
+
+				for _, h := range hosts {
+					peer := &PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}}
+					hash.Add(peer)
+					hostMap[h] = peer
+				}
+
+				for host, peer := range hostMap {
+					assert.Equal(t, peer, hash.GetByPeerInfo(PeerInfo{GRPCAddress: host}))
+				}
+			})
+
+			t.Run("distribution", func(t *testing.T) {
+				for _, tf := range hashFuncs {
+					t.Run(tf.name, func(t *testing.T) {
+						hash := tp.newPicker(tf.fn)
+						distribution := make(map[string]int)
+
+						for _, h := range hosts {
+							hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+							distribution[h] = 0
+						}
+
+						for i := range strings {
+							peer, _ := hash.Get(strings[i])
+							distribution[peer.Info().GRPCAddress]++
+						}
+						assert.Equal(t, tp.distribution[tf.name], distribution)
+					})
+				}
+			})
+
+			t.Run("minimal disruption", func(t *testing.T) {
+				hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local", "d.svc.local", "e.svc.local"}
+				hash := tp.newPicker(nil)
+				for _, h := range hosts {
+					hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+				}
+
+				before := make(map[string]string)
+				for i := range strings {
+					peer, _ := hash.Get(strings[i])
+					before[strings[i]] = peer.Info().GRPCAddress
+				}
+
+				// Add a sixth peer, which should take about a sixth of the keys from the other peers
+				hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "f.svc.local"}}})
+				var toNewPeer, toOtherPeers int
+				for i := range strings {
+					peer, _ := hash.Get(strings[i])
+					switch peer.Info().GRPCAddress {
+					case before[strings[i]]:
+						// Unchanged
+					case "f.svc.local":
+						toNewPeer++
+					default:
+						toOtherPeers++
+					}
+				}
+				assert.InDelta(t, len(strings)/6, toNewPeer, float64(len(strings))*0.02)
+				assert.LessOrEqual(t, toOtherPeers, tp.maxDisruption)
+			})
+
+			t.Run("weighted", func(t *testing.T) {
+				hash := tp.newPicker(nil)
+				weights := map[string]int{"a.svc.local": 1, "b.svc.local": 2, "c.svc.local": 1}
+				for h, w := range weights {
+					hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h, Weight: w}}})
+				}
+
+				distribution := make(map[string]int)
+				for i := range strings {
+					peer, _ := hash.Get(strings[i])
+					distribution[peer.Info().GRPCAddress]++
+				}
+
+				// Peers own keys in proportion to their weight, as reported by Ownership()
+				ownership := hash.Ownership()
+				for h, w := range weights {
+					assert.InDelta(t, float64(w)/4, float64(distribution[h])/float64(len(strings)), 0.03, h)
+					assert.InDelta(t, float64(distribution[h])/float64(len(strings)), ownership[h], 0.03, h)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkHashPickers(b *testing.B) {
+	for _, tp := range hashPickers {
+		for _, tf := range hashFuncs {
+			// The default hash function is fasthash/fnv1
+			if tf.fn == nil {
+				continue
+			}
+			b.Run(tp.name+"/"+tf.name, func(b *testing.B) {
+				ips := make([]string, b.N)
+				for i := range ips {
+					ips[i] = net.IPv4(byte(i>>24), byte(i>>16), byte(i>>8), byte(i)).String()
+				}
+
+				hash := tp.newPicker(tf.fn)
+				hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
+				for _, h := range hosts {
+					hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+				}
+
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					_, _ = hash.Get(ips[i])
+				}
+			})
+		}
+	}
+}
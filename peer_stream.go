@@ -0,0 +1,287 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// How long requests are sent unary after the stream to a peer failed, before a new stream is opened
+	peerStreamRetryWait = clock.Second
+	// How long requests are sent unary after a peer reported it doesn't support the stream, such that
+	// the stream is used once the peer is upgraded
+	peerStreamUnsupportedWait = clock.Minute
+	// How long opening the stream may take, such as while connecting to the peer
+	peerStreamDialTimeout = clock.Second * 5
+)
+
+// errPeerStreamUnavailable is returned when the request was not sent to the peer, and should be
+// sent as a unary request instead
+var errPeerStreamUnavailable = errors.New("peer stream is unavailable")
+
+// peerStream multiplexes requests to a peer over a single long-lived PeerStream. The stream is
+// opened on the first request and opened again after it fails. While there is no stream to the
+// peer, including when the peer doesn't support the stream, requests are sent as unary requests.
+type peerStream struct {
+	client PeersV1Client
+	log    FieldLogger
+	// Limits the requests in flight on the stream, such that callers wait rather than queue
+	// an unbounded number of requests on the peer
+	window chan struct{}
+	// Send() must not be called concurrently. A channel rather than a mutex, such that callers
+	// stop waiting for their turn once their context expires.
+	sendLock chan struct{}
+
+	mutex   sync.Mutex
+	stream  PeersV1_PeerStreamClient     // GUARDED_BY(mutex)
+	cancel  context.CancelFunc           // GUARDED_BY(mutex)
+	pending map[uint64]chan streamResult // GUARDED_BY(mutex)
+	nextID  uint64                       // GUARDED_BY(mutex)
+	retryAt time.Time                    // GUARDED_BY(mutex)
+	closed  bool                         // GUARDED_BY(mutex)
+	// Closed once the stream being opened is opened or failed to open, nil if not opening. GUARDED_BY(mutex)
+	dialing chan struct{}
+}
+
+type streamResult struct {
+	resp *PeerStreamResp
+	err  error
+}
+
+func newPeerStream(client PeersV1Client, conf PeerConfig) *peerStream {
+	window := conf.Behavior.PeerStreamWindow
+	setter.SetDefault(&window, 100)
+
+	return &peerStream{
+		client:   client,
+		log:      conf.Log,
+		window:   make(chan struct{}, window),
+		sendLock: make(chan struct{}, 1),
+		pending:  make(map[uint64]chan streamResult),
+	}
+}
+
+// call sends the request on the stream and waits for the response. Returns errPeerStreamUnavailable
+// if the request was not sent to the peer.
+func (ps *peerStream) call(ctx context.Context, req *PeerStreamReq) (*PeerStreamResp, error) {
+	select {
+	case ps.window <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-ps.window }()
+
+	stream, id, result, err := ps.register(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Id = id
+	// The peer doesn't apply the request once the caller stopped waiting for the response
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = max(time.Until(deadline).Milliseconds(), 1)
+	}
+
+	select {
+	case ps.sendLock <- struct{}{}:
+	case <-ctx.Done():
+		ps.unregister(id)
+		return nil, ctx.Err()
+	}
+
+	// Send() blocks while the peer doesn't read from the stream, so it's raced against the context
+	sent := make(chan error, 1)
+	go func() {
+		sent <- stream.Send(req)
+		<-ps.sendLock
+	}()
+
+	select {
+	case err = <-sent:
+	case <-ctx.Done():
+		// The peer stopped reading from the stream. Failing the stream returns Send() and the
+		// requests queued behind it are sent unary instead. The request may have been sent.
+		ps.fail(stream, errors.Wrap(ctx.Err(), "while sending on PeerStream"))
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		// The request wasn't sent, so it's safe to send it unary instead. When Send() returns
+		// io.EOF the stream was closed by the peer, and receive() fails it with the peer's error.
+		ps.unregister(id)
+		if !errors.Is(err, io.EOF) {
+			ps.fail(stream, err)
+		}
+		return nil, errPeerStreamUnavailable
+	}
+
+	select {
+	case r := <-result:
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.resp.Error != "" {
+			return nil, errors.New(r.resp.Error)
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		ps.unregister(id)
+		return nil, ctx.Err()
+	}
+}
+
+// unregister stops waiting for the response to the request
+func (ps *peerStream) unregister(id uint64) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	delete(ps.pending, id)
+}
+
+// register returns the stream to send the next request on, opening it if needed, along with
+// the id of the request and the channel its result is delivered to.
+func (ps *peerStream) register(ctx context.Context) (PeersV1_PeerStreamClient, uint64, chan streamResult, error) {
+	for {
+		ps.mutex.Lock()
+		if ps.stream != nil {
+			ps.nextID++
+			result := make(chan streamResult, 1)
+			ps.pending[ps.nextID] = result
+			stream, id := ps.stream, ps.nextID
+			ps.mutex.Unlock()
+			return stream, id, result, nil
+		}
+		if ps.closed || clock.Now().Before(ps.retryAt) {
+			ps.mutex.Unlock()
+			return nil, 0, nil, errPeerStreamUnavailable
+		}
+		// Only one caller opens the stream, the others wait for it
+		dialing := ps.dialing
+		if dialing == nil {
+			dialing = make(chan struct{})
+			ps.dialing = dialing
+			go ps.dial(dialing)
+		}
+		ps.mutex.Unlock()
+
+		select {
+		case <-dialing:
+		case <-ctx.Done():
+			return nil, 0, nil, ctx.Err()
+		}
+	}
+}
+
+// dial opens the stream and closes `dialing` once it's opened or failed to open. The stream
+// outlives the callers waiting for it, so it's opened in the background.
+func (ps *peerStream) dial(dialing chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// Real time rather than the clock, such that opening the stream is bounded while the clock is frozen
+	timer := time.AfterFunc(peerStreamDialTimeout, cancel)
+	stream, err := ps.client.PeerStream(ctx)
+	if !timer.Stop() && err == nil {
+		err = errors.Wrap(ctx.Err(), "while opening PeerStream")
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.dialing = nil
+	close(dialing)
+
+	if err != nil || ps.closed {
+		cancel()
+		if err != nil {
+			ps.log.WithError(err).Debug("while opening peer stream")
+			ps.retryAt = clock.Now().Add(peerStreamRetryWait)
+		}
+		return
+	}
+	ps.stream, ps.cancel = stream, cancel
+	go ps.receive(stream)
+}
+
+// receive delivers the responses received on the stream until the stream fails
+func (ps *peerStream) receive(stream PeersV1_PeerStreamClient) {
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			ps.fail(stream, err)
+			return
+		}
+
+		ps.mutex.Lock()
+		result, ok := ps.pending[resp.Id]
+		delete(ps.pending, resp.Id)
+		ps.mutex.Unlock()
+
+		// The caller may have stopped waiting for the response
+		if ok {
+			result <- streamResult{resp: resp}
+		}
+	}
+}
+
+// fail closes the stream after it failed and fails the requests waiting on it. The requests
+// were sent to the peer, so the peer may have applied them and they are not sent again.
+func (ps *peerStream) fail(stream PeersV1_PeerStreamClient, err error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	// The stream may have already failed or been closed
+	if ps.stream != stream {
+		return
+	}
+
+	if status.Code(err) == codes.Unimplemented {
+		// The peer runs a version which doesn't support the stream, so it applied none of the requests
+		ps.log.WithError(err).Info("peer does not support PeerStream; sending unary requests")
+		ps.retryAt = clock.Now().Add(peerStreamUnsupportedWait)
+		err = errPeerStreamUnavailable
+	} else {
+		ps.log.WithError(err).Debug("peer stream failed")
+		ps.retryAt = clock.Now().Add(peerStreamRetryWait)
+		err = errors.Wrap(err, "Error in PeerStream")
+	}
+	ps.reset(err)
+}
+
+// reset closes the current stream and fails all pending requests with the error. GUARDED_BY(mutex)
+func (ps *peerStream) reset(err error) {
+	if ps.stream != nil {
+		ps.cancel()
+		ps.stream = nil
+	}
+	for id, result := range ps.pending {
+		result <- streamResult{err: err}
+		delete(ps.pending, id)
+	}
+}
+
+// close closes the stream, after which requests are sent as unary requests
+func (ps *peerStream) close() {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.closed = true
+	ps.reset(status.Error(codes.Canceled, "grpc: the client connection is closing"))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestHandlePeerStreamExpired(t *testing.T) {
+	s, err := NewV1Instance(Config{GRPCServers: []*grpc.Server{grpc.NewServer()}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	createdAt := MillisecondNow()
+	req := func(hits int64) *PeerStreamReq {
+		return &PeerStreamReq{
+			Id: 1,
+			Request: &PeerStreamReq_GetPeerRateLimits{GetPeerRateLimits: &GetPeerRateLimitsReq{
+				Requests: []*RateLimitReq{{
+					Name:      t.Name(),
+					UniqueKey: "key",
+					Hits:      hits,
+					Limit:     10,
+					Duration:  Minute,
+					CreatedAt: &createdAt,
+				}},
+			}},
+		}
+	}
+
+	// The sender stopped waiting for the response before the request was handled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := s.handlePeerStream(ctx, req(1))
+	assert.Equal(t, uint64(1), resp.Id)
+	assert.Contains(t, resp.Error, "request was not applied")
+	assert.Nil(t, resp.Response)
+
+	// So the hit was not applied
+	resp = s.handlePeerStream(context.Background(), req(0))
+	require.Empty(t, resp.Error)
+	assert.Equal(t, int64(10), resp.GetGetPeerRateLimits().RateLimits[0].Remaining)
+}
+
+// dialingPeer opens a PeerStream once `release` is closed
+type dialingPeer struct {
+	PeersV1Client
+	release chan struct{}
+	dials   atomic.Int32
+}
+
+func (p *dialingPeer) PeerStream(ctx context.Context, _ ...grpc.CallOption) (PeersV1_PeerStreamClient, error) {
+	p.dials.Add(1)
+	select {
+	case <-p.release:
+		return nil, context.Canceled
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestPeerStreamDialing(t *testing.T) {
+	peer := &dialingPeer{release: make(chan struct{})}
+	ps := newPeerStream(peer, PeerConfig{Log: logrus.StandardLogger()})
+	defer close(peer.release)
+
+	// The callers wait for the stream being opened until their context expires
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+			_, err := ps.call(ctx, &PeerStreamReq{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second * 5):
+			t.Fatal("request did not return once its context expired")
+		}
+	}
+	assert.Equal(t, int32(1), peer.dials.Load())
+
+	// Closing doesn't wait for the stream being opened
+	ps.close()
+	_, err := ps.call(context.Background(), &PeerStreamReq{})
+	assert.ErrorIs(t, err, errPeerStreamUnavailable)
+}
@@ -43,6 +43,16 @@ type GetPeerRateLimitsReq struct {
 	// Must specify at least one RateLimit. The peer that recives this request MUST be authoritative for
 	// each rate_limit[x].unique_key provided, as the peer will not forward the request to any other peers
 	Requests []*RateLimitReq `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// The GRPC address of the peer which sent the requests. The owner broadcasts updates of GLOBAL
+	// rate limits to the peers which recently sent requests for them, see `GUBER_GLOBAL_INTEREST_TTL`
+	PeerAddress string `protobuf:"bytes,2,opt,name=peer_address,json=peerAddress,proto3" json:"peer_address,omitempty"`
+	// Identifies a batch of GLOBAL hits aggregated by `peer_address`, increasing with each batch.
+	// The owner ignores the hits of a retried batch it already applied. Zero if not a batch.
+	Sequence uint64 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// The client requests with a `request_id` whose hits are included in the aggregated hits of
+	// `requests`. The owner ignores the hits of the client requests it already applied, such as
+	// when the client retried the request on another peer.
+	AggregatedRequests []*AggregatedRequest `protobuf:"bytes,4,rep,name=aggregated_requests,json=aggregatedRequests,proto3" json:"aggregated_requests,omitempty"`
 }
 
 func (x *GetPeerRateLimitsReq) Reset() {
@@ -84,6 +94,93 @@ func (x *GetPeerRateLimitsReq) GetRequests() []*RateLimitReq {
 	return nil
 }
 
+func (x *GetPeerRateLimitsReq) GetPeerAddress() string {
+	if x != nil {
+		return x.PeerAddress
+	}
+	return ""
+}
+
+func (x *GetPeerRateLimitsReq) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *GetPeerRateLimitsReq) GetAggregatedRequests() []*AggregatedRequest {
+	if x != nil {
+		return x.AggregatedRequests
+	}
+	return nil
+}
+
+type AggregatedRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The hash key of the rate limit in `requests` which includes the hits
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The `request_id` of the client request
+	RequestId string `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// The hits of the client request
+	Hits int64 `protobuf:"varint,3,opt,name=hits,proto3" json:"hits,omitempty"`
+}
+
+func (x *AggregatedRequest) Reset() {
+	*x = AggregatedRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AggregatedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregatedRequest) ProtoMessage() {}
+
+func (x *AggregatedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregatedRequest.ProtoReflect.Descriptor instead.
+func (*AggregatedRequest) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AggregatedRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *AggregatedRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *AggregatedRequest) GetHits() int64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
 type GetPeerRateLimitsResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -96,7 +193,7 @@ type GetPeerRateLimitsResp struct {
 func (x *GetPeerRateLimitsResp) Reset() {
 	*x = GetPeerRateLimitsResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_peers_proto_msgTypes[1]
+		mi := &file_peers_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -109,7 +206,7 @@ func (x *GetPeerRateLimitsResp) String() string {
 func (*GetPeerRateLimitsResp) ProtoMessage() {}
 
 func (x *GetPeerRateLimitsResp) ProtoReflect() protoreflect.Message {
-	mi := &file_peers_proto_msgTypes[1]
+	mi := &file_peers_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -122,7 +219,7 @@ func (x *GetPeerRateLimitsResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPeerRateLimitsResp.ProtoReflect.Descriptor instead.
 func (*GetPeerRateLimitsResp) Descriptor() ([]byte, []int) {
-	return file_peers_proto_rawDescGZIP(), []int{1}
+	return file_peers_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *GetPeerRateLimitsResp) GetRateLimits() []*RateLimitResp {
@@ -144,7 +241,7 @@ type UpdatePeerGlobalsReq struct {
 func (x *UpdatePeerGlobalsReq) Reset() {
 	*x = UpdatePeerGlobalsReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_peers_proto_msgTypes[2]
+		mi := &file_peers_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -157,7 +254,7 @@ func (x *UpdatePeerGlobalsReq) String() string {
 func (*UpdatePeerGlobalsReq) ProtoMessage() {}
 
 func (x *UpdatePeerGlobalsReq) ProtoReflect() protoreflect.Message {
-	mi := &file_peers_proto_msgTypes[2]
+	mi := &file_peers_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -170,7 +267,7 @@ func (x *UpdatePeerGlobalsReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePeerGlobalsReq.ProtoReflect.Descriptor instead.
 func (*UpdatePeerGlobalsReq) Descriptor() ([]byte, []int) {
-	return file_peers_proto_rawDescGZIP(), []int{2}
+	return file_peers_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *UpdatePeerGlobalsReq) GetGlobals() []*UpdatePeerGlobal {
@@ -209,7 +306,7 @@ type UpdatePeerGlobal struct {
 func (x *UpdatePeerGlobal) Reset() {
 	*x = UpdatePeerGlobal{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_peers_proto_msgTypes[3]
+		mi := &file_peers_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -222,7 +319,7 @@ func (x *UpdatePeerGlobal) String() string {
 func (*UpdatePeerGlobal) ProtoMessage() {}
 
 func (x *UpdatePeerGlobal) ProtoReflect() protoreflect.Message {
-	mi := &file_peers_proto_msgTypes[3]
+	mi := &file_peers_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -235,7 +332,7 @@ func (x *UpdatePeerGlobal) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePeerGlobal.ProtoReflect.Descriptor instead.
 func (*UpdatePeerGlobal) Descriptor() ([]byte, []int) {
-	return file_peers_proto_rawDescGZIP(), []int{3}
+	return file_peers_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *UpdatePeerGlobal) GetKey() string {
@@ -282,7 +379,7 @@ type UpdatePeerGlobalsResp struct {
 func (x *UpdatePeerGlobalsResp) Reset() {
 	*x = UpdatePeerGlobalsResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_peers_proto_msgTypes[4]
+		mi := &file_peers_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -295,7 +392,7 @@ func (x *UpdatePeerGlobalsResp) String() string {
 func (*UpdatePeerGlobalsResp) ProtoMessage() {}
 
 func (x *UpdatePeerGlobalsResp) ProtoReflect() protoreflect.Message {
-	mi := &file_peers_proto_msgTypes[4]
+	mi := &file_peers_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -308,153 +405,1232 @@ func (x *UpdatePeerGlobalsResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePeerGlobalsResp.ProtoReflect.Descriptor instead.
 func (*UpdatePeerGlobalsResp) Descriptor() ([]byte, []int) {
-	return file_peers_proto_rawDescGZIP(), []int{4}
+	return file_peers_proto_rawDescGZIP(), []int{5}
 }
 
-var File_peers_proto protoreflect.FileDescriptor
+type GetPeerLoadReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
 
-var file_peers_proto_rawDesc = []byte{
-	0x0a, 0x0b, 0x70, 0x65, 0x65, 0x72, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x70,
-	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x1a, 0x10, 0x67, 0x75,
-	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x4f,
-	0x0a, 0x14, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d,
-	0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x12, 0x37, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75,
-	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d,
-	0x69, 0x74, 0x52, 0x65, 0x71, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x22,
-	0x56, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
-	0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x3d, 0x0a, 0x0b, 0x72, 0x61, 0x74, 0x65,
-	0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
-	0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61,
-	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x52, 0x0a, 0x72, 0x61, 0x74,
-	0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x22, 0x51, 0x0a, 0x14, 0x55, 0x70, 0x64, 0x61, 0x74,
-	0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x12,
-	0x39, 0x0a, 0x07, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72,
-	0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61,
-	0x6c, 0x52, 0x07, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x22, 0xcd, 0x01, 0x0a, 0x10, 0x55,
-	0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x12,
-	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
-	0x79, 0x12, 0x34, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
-	0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x52,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72,
-	0x69, 0x74, 0x68, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e,
-	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72,
-	0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12,
-	0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x63,
-	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52,
-	0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x17, 0x0a, 0x15, 0x55, 0x70,
-	0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52,
-	0x65, 0x73, 0x70, 0x32, 0xcd, 0x01, 0x0a, 0x07, 0x50, 0x65, 0x65, 0x72, 0x73, 0x56, 0x31, 0x12,
-	0x60, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
-	0x6d, 0x69, 0x74, 0x73, 0x12, 0x23, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e,
-	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65,
-	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67,
-	0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65,
-	0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22,
-	0x00, 0x12, 0x60, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47,
-	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x12, 0x23, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65,
-	0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65,
-	0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x24, 0x2e, 0x70, 0x62,
-	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61,
-	0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x22, 0x00, 0x42, 0x28, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
-	0x6d, 0x2f, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2d, 0x69, 0x6f, 0x2f,
-	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x80, 0x01, 0x01, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *GetPeerLoadReq) Reset() {
+	*x = GetPeerLoadReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-var (
-	file_peers_proto_rawDescOnce sync.Once
-	file_peers_proto_rawDescData = file_peers_proto_rawDesc
-)
+func (x *GetPeerLoadReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_peers_proto_rawDescGZIP() []byte {
-	file_peers_proto_rawDescOnce.Do(func() {
-		file_peers_proto_rawDescData = protoimpl.X.CompressGZIP(file_peers_proto_rawDescData)
-	})
-	return file_peers_proto_rawDescData
+func (*GetPeerLoadReq) ProtoMessage() {}
+
+func (x *GetPeerLoadReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_peers_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
-var file_peers_proto_goTypes = []interface{}{
-	(*GetPeerRateLimitsReq)(nil),  // 0: pb.gubernator.GetPeerRateLimitsReq
-	(*GetPeerRateLimitsResp)(nil), // 1: pb.gubernator.GetPeerRateLimitsResp
-	(*UpdatePeerGlobalsReq)(nil),  // 2: pb.gubernator.UpdatePeerGlobalsReq
-	(*UpdatePeerGlobal)(nil),      // 3: pb.gubernator.UpdatePeerGlobal
-	(*UpdatePeerGlobalsResp)(nil), // 4: pb.gubernator.UpdatePeerGlobalsResp
-	(*RateLimitReq)(nil),          // 5: pb.gubernator.RateLimitReq
-	(*RateLimitResp)(nil),         // 6: pb.gubernator.RateLimitResp
-	(Algorithm)(0),                // 7: pb.gubernator.Algorithm
+// Deprecated: Use GetPeerLoadReq.ProtoReflect.Descriptor instead.
+func (*GetPeerLoadReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{6}
 }
-var file_peers_proto_depIdxs = []int32{
-	5, // 0: pb.gubernator.GetPeerRateLimitsReq.requests:type_name -> pb.gubernator.RateLimitReq
-	6, // 1: pb.gubernator.GetPeerRateLimitsResp.rate_limits:type_name -> pb.gubernator.RateLimitResp
-	3, // 2: pb.gubernator.UpdatePeerGlobalsReq.globals:type_name -> pb.gubernator.UpdatePeerGlobal
-	6, // 3: pb.gubernator.UpdatePeerGlobal.status:type_name -> pb.gubernator.RateLimitResp
-	7, // 4: pb.gubernator.UpdatePeerGlobal.algorithm:type_name -> pb.gubernator.Algorithm
-	0, // 5: pb.gubernator.PeersV1.GetPeerRateLimits:input_type -> pb.gubernator.GetPeerRateLimitsReq
-	2, // 6: pb.gubernator.PeersV1.UpdatePeerGlobals:input_type -> pb.gubernator.UpdatePeerGlobalsReq
-	1, // 7: pb.gubernator.PeersV1.GetPeerRateLimits:output_type -> pb.gubernator.GetPeerRateLimitsResp
-	4, // 8: pb.gubernator.PeersV1.UpdatePeerGlobals:output_type -> pb.gubernator.UpdatePeerGlobalsResp
-	7, // [7:9] is the sub-list for method output_type
-	5, // [5:7] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+
+type GetPeerLoadResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The rate of rate limit checks per second this peer hashed to each peer, by GRPC address. The
+	// rate counts the peer each check hashed to before the load was bounded, such that moving keys
+	// between peers doesn't change the load of the peers.
+	Load map[string]float64 `protobuf:"bytes,1,rep,name=load,proto3" json:"load,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	// The fraction of the keys of each peer which are moved to other peers, by GRPC address. The
+	// peer with the lowest GRPC address computes the table from the load of every peer, and the
+	// other peers use its table such that every peer moves the same keys.
+	Shed map[string]float64 `protobuf:"bytes,2,rep,name=shed,proto3" json:"shed,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	// The version of the shed table, which changes whenever the table is computed. Is 0 until the
+	// peer has a table.
+	ShedVersion int64 `protobuf:"varint,3,opt,name=shed_version,json=shedVersion,proto3" json:"shed_version,omitempty"`
 }
 
-func init() { file_peers_proto_init() }
-func file_peers_proto_init() {
-	if File_peers_proto != nil {
-		return
+func (x *GetPeerLoadResp) Reset() {
+	*x = GetPeerLoadResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	file_gubernator_proto_init()
-	if !protoimpl.UnsafeEnabled {
-		file_peers_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetPeerRateLimitsReq); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+}
+
+func (x *GetPeerLoadResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerLoadResp) ProtoMessage() {}
+
+func (x *GetPeerLoadResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_peers_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetPeerRateLimitsResp); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerLoadResp.ProtoReflect.Descriptor instead.
+func (*GetPeerLoadResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPeerLoadResp) GetLoad() map[string]float64 {
+	if x != nil {
+		return x.Load
+	}
+	return nil
+}
+
+func (x *GetPeerLoadResp) GetShed() map[string]float64 {
+	if x != nil {
+		return x.Shed
+	}
+	return nil
+}
+
+func (x *GetPeerLoadResp) GetShedVersion() int64 {
+	if x != nil {
+		return x.ShedVersion
+	}
+	return 0
+}
+
+type UpdatePeerCountersReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Must specify at least one counter
+	Counters []*PeerCounter `protobuf:"bytes,1,rep,name=counters,proto3" json:"counters,omitempty"`
+}
+
+func (x *UpdatePeerCountersReq) Reset() {
+	*x = UpdatePeerCountersReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdatePeerCountersReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePeerCountersReq) ProtoMessage() {}
+
+func (x *UpdatePeerCountersReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_peers_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UpdatePeerGlobalsReq); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePeerCountersReq.ProtoReflect.Descriptor instead.
+func (*UpdatePeerCountersReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdatePeerCountersReq) GetCounters() []*PeerCounter {
+	if x != nil {
+		return x.Counters
+	}
+	return nil
+}
+
+type PeerCounter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Uniquely identifies this rate limit IE: 'ip:10.2.10.7' or 'account:123445'
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The end of the window the hits were counted in, in Epoch milliseconds
+	WindowEnd int64 `protobuf:"varint,2,opt,name=window_end,json=windowEnd,proto3" json:"window_end,omitempty"`
+	// The hits counted during the window by each peer, by the id of the peer. The id is unique to
+	// each run of a peer, such that a restarted peer doesn't count from zero again under the same id.
+	Hits map[string]int64 `protobuf:"bytes,3,rep,name=hits,proto3" json:"hits,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *PeerCounter) Reset() {
+	*x = PeerCounter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerCounter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerCounter) ProtoMessage() {}
+
+func (x *PeerCounter) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_peers_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UpdatePeerGlobal); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerCounter.ProtoReflect.Descriptor instead.
+func (*PeerCounter) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PeerCounter) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PeerCounter) GetWindowEnd() int64 {
+	if x != nil {
+		return x.WindowEnd
+	}
+	return 0
+}
+
+func (x *PeerCounter) GetHits() map[string]int64 {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+type UpdatePeerCountersResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *UpdatePeerCountersResp) Reset() {
+	*x = UpdatePeerCountersResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdatePeerCountersResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePeerCountersResp) ProtoMessage() {}
+
+func (x *UpdatePeerCountersResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePeerCountersResp.ProtoReflect.Descriptor instead.
+func (*UpdatePeerCountersResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{10}
+}
+
+type PeerStreamReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Identifies the request within the stream. The response to the request has the same id
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Request:
+	//	*PeerStreamReq_GetPeerRateLimits
+	//	*PeerStreamReq_UpdatePeerGlobals
+	Request isPeerStreamReq_Request `protobuf_oneof:"request"`
+	// How long the sender waits for the response in milliseconds from when the request was sent,
+	// like the `grpc-timeout` of a unary request. The peer doesn't apply a request which is still
+	// waiting to be handled once the timeout elapsed. 0 means no timeout
+	Timeout int64 `protobuf:"varint,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
+}
+
+func (x *PeerStreamReq) Reset() {
+	*x = PeerStreamReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerStreamReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStreamReq) ProtoMessage() {}
+
+func (x *PeerStreamReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStreamReq.ProtoReflect.Descriptor instead.
+func (*PeerStreamReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PeerStreamReq) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (m *PeerStreamReq) GetRequest() isPeerStreamReq_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (x *PeerStreamReq) GetGetPeerRateLimits() *GetPeerRateLimitsReq {
+	if x, ok := x.GetRequest().(*PeerStreamReq_GetPeerRateLimits); ok {
+		return x.GetPeerRateLimits
+	}
+	return nil
+}
+
+func (x *PeerStreamReq) GetUpdatePeerGlobals() *UpdatePeerGlobalsReq {
+	if x, ok := x.GetRequest().(*PeerStreamReq_UpdatePeerGlobals); ok {
+		return x.UpdatePeerGlobals
+	}
+	return nil
+}
+
+func (x *PeerStreamReq) GetTimeout() int64 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+type isPeerStreamReq_Request interface {
+	isPeerStreamReq_Request()
+}
+
+type PeerStreamReq_GetPeerRateLimits struct {
+	GetPeerRateLimits *GetPeerRateLimitsReq `protobuf:"bytes,2,opt,name=get_peer_rate_limits,json=getPeerRateLimits,proto3,oneof"`
+}
+
+type PeerStreamReq_UpdatePeerGlobals struct {
+	UpdatePeerGlobals *UpdatePeerGlobalsReq `protobuf:"bytes,3,opt,name=update_peer_globals,json=updatePeerGlobals,proto3,oneof"`
+}
+
+func (*PeerStreamReq_GetPeerRateLimits) isPeerStreamReq_Request() {}
+
+func (*PeerStreamReq_UpdatePeerGlobals) isPeerStreamReq_Request() {}
+
+type PeerStreamResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The id of the request this is the response to
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Response:
+	//	*PeerStreamResp_GetPeerRateLimits
+	//	*PeerStreamResp_UpdatePeerGlobals
+	Response isPeerStreamResp_Response `protobuf_oneof:"response"`
+	// The error if the request failed, in which case no response is set
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *PeerStreamResp) Reset() {
+	*x = PeerStreamResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerStreamResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStreamResp) ProtoMessage() {}
+
+func (x *PeerStreamResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStreamResp.ProtoReflect.Descriptor instead.
+func (*PeerStreamResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PeerStreamResp) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (m *PeerStreamResp) GetResponse() isPeerStreamResp_Response {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+func (x *PeerStreamResp) GetGetPeerRateLimits() *GetPeerRateLimitsResp {
+	if x, ok := x.GetResponse().(*PeerStreamResp_GetPeerRateLimits); ok {
+		return x.GetPeerRateLimits
+	}
+	return nil
+}
+
+func (x *PeerStreamResp) GetUpdatePeerGlobals() *UpdatePeerGlobalsResp {
+	if x, ok := x.GetResponse().(*PeerStreamResp_UpdatePeerGlobals); ok {
+		return x.UpdatePeerGlobals
+	}
+	return nil
+}
+
+func (x *PeerStreamResp) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type isPeerStreamResp_Response interface {
+	isPeerStreamResp_Response()
+}
+
+type PeerStreamResp_GetPeerRateLimits struct {
+	GetPeerRateLimits *GetPeerRateLimitsResp `protobuf:"bytes,2,opt,name=get_peer_rate_limits,json=getPeerRateLimits,proto3,oneof"`
+}
+
+type PeerStreamResp_UpdatePeerGlobals struct {
+	UpdatePeerGlobals *UpdatePeerGlobalsResp `protobuf:"bytes,3,opt,name=update_peer_globals,json=updatePeerGlobals,proto3,oneof"`
+}
+
+func (*PeerStreamResp_GetPeerRateLimits) isPeerStreamResp_Response() {}
+
+func (*PeerStreamResp_UpdatePeerGlobals) isPeerStreamResp_Response() {}
+
+type GetPeerCapabilitiesReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetPeerCapabilitiesReq) Reset() {
+	*x = GetPeerCapabilitiesReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerCapabilitiesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerCapabilitiesReq) ProtoMessage() {}
+
+func (x *GetPeerCapabilitiesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerCapabilitiesReq.ProtoReflect.Descriptor instead.
+func (*GetPeerCapabilitiesReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{13}
+}
+
+type GetPeerCapabilitiesResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The version of the peer protocol of the peer
+	ProtocolVersion uint32 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	// The peer protocol capabilities the peer supports, IE: 'peer_stream' or 'global_interest'
+	Capabilities []string `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+}
+
+func (x *GetPeerCapabilitiesResp) Reset() {
+	*x = GetPeerCapabilitiesResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerCapabilitiesResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerCapabilitiesResp) ProtoMessage() {}
+
+func (x *GetPeerCapabilitiesResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerCapabilitiesResp.ProtoReflect.Descriptor instead.
+func (*GetPeerCapabilitiesResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetPeerCapabilitiesResp) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *GetPeerCapabilitiesResp) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type GetPeerRingHashReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetPeerRingHashReq) Reset() {
+	*x = GetPeerRingHashReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerRingHashReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerRingHashReq) ProtoMessage() {}
+
+func (x *GetPeerRingHashReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerRingHashReq.ProtoReflect.Descriptor instead.
+func (*GetPeerRingHashReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{15}
+}
+
+type GetPeerRingHashResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// A hash of the local peers known by the peer
+	RingHash string `protobuf:"bytes,1,opt,name=ring_hash,json=ringHash,proto3" json:"ring_hash,omitempty"`
+}
+
+func (x *GetPeerRingHashResp) Reset() {
+	*x = GetPeerRingHashResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerRingHashResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerRingHashResp) ProtoMessage() {}
+
+func (x *GetPeerRingHashResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerRingHashResp.ProtoReflect.Descriptor instead.
+func (*GetPeerRingHashResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetPeerRingHashResp) GetRingHash() string {
+	if x != nil {
+		return x.RingHash
+	}
+	return ""
+}
+
+type HandoffPeerRateLimitsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The rate limits the draining peer owned. Rate limits already in the cache of
+	// the receiving peer are not replaced.
+	RateLimits []*HandoffRateLimit `protobuf:"bytes,1,rep,name=rate_limits,json=rateLimits,proto3" json:"rate_limits,omitempty"`
+}
+
+func (x *HandoffPeerRateLimitsReq) Reset() {
+	*x = HandoffPeerRateLimitsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandoffPeerRateLimitsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffPeerRateLimitsReq) ProtoMessage() {}
+
+func (x *HandoffPeerRateLimitsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffPeerRateLimitsReq.ProtoReflect.Descriptor instead.
+func (*HandoffPeerRateLimitsReq) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *HandoffPeerRateLimitsReq) GetRateLimits() []*HandoffRateLimit {
+	if x != nil {
+		return x.RateLimits
+	}
+	return nil
+}
+
+type HandoffRateLimit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The hash key of the rate limit IE: 'requests_per_sec_account:12345'
+	Key       string    `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Algorithm Algorithm `protobuf:"varint,2,opt,name=algorithm,proto3,enum=pb.gubernator.Algorithm" json:"algorithm,omitempty"`
+	// When the rate limit expires in epoch milliseconds
+	ExpireAt int64 `protobuf:"varint,3,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	// The status of a token bucket
+	Status Status `protobuf:"varint,4,opt,name=status,proto3,enum=pb.gubernator.Status" json:"status,omitempty"`
+	Limit  int64  `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	// The duration of the rate limit in milliseconds
+	Duration int64 `protobuf:"varint,6,opt,name=duration,proto3" json:"duration,omitempty"`
+	// The remaining of the rate limit, which may be fractional for a leaky bucket
+	Remaining float64 `protobuf:"fixed64,7,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// The burst of a leaky bucket
+	Burst int64 `protobuf:"varint,8,opt,name=burst,proto3" json:"burst,omitempty"`
+	// When the token bucket was created or the leaky bucket was last updated in epoch milliseconds
+	UpdatedAt int64 `protobuf:"varint,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+}
+
+func (x *HandoffRateLimit) Reset() {
+	*x = HandoffRateLimit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandoffRateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffRateLimit) ProtoMessage() {}
+
+func (x *HandoffRateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffRateLimit.ProtoReflect.Descriptor instead.
+func (*HandoffRateLimit) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *HandoffRateLimit) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HandoffRateLimit) GetAlgorithm() Algorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return Algorithm_TOKEN_BUCKET
+}
+
+func (x *HandoffRateLimit) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+func (x *HandoffRateLimit) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_UNDER_LIMIT
+}
+
+func (x *HandoffRateLimit) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *HandoffRateLimit) GetDuration() int64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *HandoffRateLimit) GetRemaining() float64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *HandoffRateLimit) GetBurst() int64 {
+	if x != nil {
+		return x.Burst
+	}
+	return 0
+}
+
+func (x *HandoffRateLimit) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type HandoffPeerRateLimitsResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The number of rate limits added to the cache of the receiving peer
+	Added int32 `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	// The number of rate limits merged with a rate limit the receiving peer already had, which
+	// keeps the lower remaining of both
+	Merged int32 `protobuf:"varint,2,opt,name=merged,proto3" json:"merged,omitempty"`
+}
+
+func (x *HandoffPeerRateLimitsResp) Reset() {
+	*x = HandoffPeerRateLimitsResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_peers_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandoffPeerRateLimitsResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffPeerRateLimitsResp) ProtoMessage() {}
+
+func (x *HandoffPeerRateLimitsResp) ProtoReflect() protoreflect.Message {
+	mi := &file_peers_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffPeerRateLimitsResp.ProtoReflect.Descriptor instead.
+func (*HandoffPeerRateLimitsResp) Descriptor() ([]byte, []int) {
+	return file_peers_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *HandoffPeerRateLimitsResp) GetAdded() int32 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *HandoffPeerRateLimitsResp) GetMerged() int32 {
+	if x != nil {
+		return x.Merged
+	}
+	return 0
+}
+
+var File_peers_proto protoreflect.FileDescriptor
+
+var file_peers_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x70, 0x65, 0x65, 0x72, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x70,
+	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x1a, 0x10, 0x67, 0x75,
+	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xe1,
+	0x01, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x12, 0x37, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x67,
+	0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73,
+	0x12, 0x21, 0x0a, 0x0c, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x65, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12,
+	0x51, 0x0a, 0x13, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x72, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x70,
+	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x67, 0x67,
+	0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x12,
+	0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x73, 0x22, 0x58, 0x0a, 0x11, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x64,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x69, 0x74, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x68, 0x69, 0x74, 0x73, 0x22, 0x56, 0x0a, 0x15,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x3d, 0x0a, 0x0b, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c,
+	0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x52, 0x0a, 0x72, 0x61, 0x74, 0x65, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x22, 0x51, 0x0a, 0x14, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65,
+	0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x12, 0x39, 0x0a, 0x07,
+	0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e,
+	0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x52, 0x07,
+	0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x22, 0xcd, 0x01, 0x0a, 0x10, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x34,
+	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c,
+	0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52,
+	0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x52, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68,
+	0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62,
+	0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68,
+	0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x1a, 0x0a, 0x08,
+	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
+	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x17, 0x0a, 0x15, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x22, 0x10, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64, 0x52,
+	0x65, 0x71, 0x22, 0xa2, 0x02, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f,
+	0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x12, 0x3c, 0x0a, 0x04, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e,
+	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64,
+	0x52, 0x65, 0x73, 0x70, 0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04,
+	0x6c, 0x6f, 0x61, 0x64, 0x12, 0x3c, 0x0a, 0x04, 0x73, 0x68, 0x65, 0x64, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x28, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64, 0x52, 0x65,
+	0x73, 0x70, 0x2e, 0x53, 0x68, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x73, 0x68,
+	0x65, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73, 0x68, 0x65, 0x64, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x37, 0x0a, 0x09, 0x4c, 0x6f, 0x61, 0x64, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x37,
+	0x0a, 0x09, 0x53, 0x68, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x4f, 0x0a, 0x15, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71,
+	0x12, 0x36, 0x0a, 0x08, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x52, 0x08,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x22, 0xb1, 0x01, 0x0a, 0x0b, 0x50, 0x65, 0x65,
+	0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x77, 0x69,
+	0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x45, 0x6e, 0x64, 0x12, 0x38, 0x0a, 0x04, 0x68, 0x69, 0x74,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62,
+	0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x65, 0x72, 0x2e, 0x48, 0x69, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x68,
+	0x69, 0x74, 0x73, 0x1a, 0x37, 0x0a, 0x09, 0x48, 0x69, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x18, 0x0a, 0x16,
+	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65,
+	0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0xf3, 0x01, 0x0a, 0x0d, 0x50, 0x65, 0x65, 0x72, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x56, 0x0a, 0x14, 0x67, 0x65, 0x74, 0x5f,
+	0x70, 0x65, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65,
+	0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61,
+	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x48, 0x00, 0x52, 0x11, 0x67,
+	0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x12, 0x55, 0x0a, 0x13, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f,
+	0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e,
+	0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52,
+	0x65, 0x71, 0x48, 0x00, 0x52, 0x11, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72,
+	0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75,
+	0x74, 0x42, 0x09, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xf3, 0x01, 0x0a,
+	0x0e, 0x50, 0x65, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x57, 0x0a, 0x14, 0x67, 0x65, 0x74, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x74, 0x65,
+	0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e,
+	0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65,
+	0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x48, 0x00, 0x52, 0x11, 0x67, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61,
+	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x56, 0x0a, 0x13, 0x75, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72,
+	0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72,
+	0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x48, 0x00, 0x52, 0x11, 0x75,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x42, 0x0a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x18, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x43, 0x61, 0x70,
+	0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x22, 0x68, 0x0a, 0x17,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x29, 0x0a, 0x10, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x22, 0x14, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65,
+	0x72, 0x52, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73, 0x68, 0x52, 0x65, 0x71, 0x22, 0x32, 0x0a, 0x13,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73, 0x68, 0x52,
+	0x65, 0x73, 0x70, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x69, 0x6e, 0x67, 0x5f, 0x68, 0x61, 0x73, 0x68,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73, 0x68,
+	0x22, 0x5c, 0x0a, 0x18, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x50, 0x65, 0x65, 0x72, 0x52,
+	0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x12, 0x40, 0x0a, 0x0b,
+	0x72, 0x61, 0x74, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
+	0x72, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x52, 0x0a, 0x72, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x22, 0xad,
+	0x02, 0x0a, 0x10, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
+	0x68, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75,
+	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
+	0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x1b, 0x0a,
+	0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x41, 0x74, 0x12, 0x2d, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x72,
+	0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09,
+	0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x75, 0x72,
+	0x73, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x49,
+	0x0a, 0x19, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74,
+	0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x61,
+	0x64, 0x64, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x61, 0x64, 0x64, 0x65,
+	0x64, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x06, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x64, 0x32, 0xd9, 0x06, 0x0a, 0x07, 0x50, 0x65,
+	0x65, 0x72, 0x73, 0x56, 0x31, 0x12, 0x60, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72,
+	0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x23, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65,
+	0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a,
+	0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x60, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x12, 0x23, 0x2e, 0x70,
+	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x73, 0x52, 0x65,
+	0x71, 0x1a, 0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
+	0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62,
+	0x61, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x0f, 0x4c, 0x65, 0x61,
+	0x73, 0x65, 0x50, 0x65, 0x65, 0x72, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x12, 0x1d, 0x2e, 0x70,
+	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x4c, 0x65, 0x61,
+	0x73, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x1e, 0x2e, 0x70, 0x62,
+	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x4c, 0x65, 0x61, 0x73,
+	0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x4e, 0x0a,
+	0x0b, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64, 0x12, 0x1d, 0x2e, 0x70,
+	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74,
+	0x50, 0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x1a, 0x1e, 0x2e, 0x70, 0x62,
+	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50,
+	0x65, 0x65, 0x72, 0x4c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x63, 0x0a,
+	0x12, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x65, 0x72, 0x73, 0x12, 0x24, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x25, 0x2e, 0x70, 0x62, 0x2e, 0x67,
+	0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x22, 0x00, 0x12, 0x4f, 0x0a, 0x0a, 0x50, 0x65, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x12, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72,
+	0x2e, 0x50, 0x65, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x1a, 0x1d,
+	0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x50,
+	0x65, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x28,
+	0x01, 0x30, 0x01, 0x12, 0x66, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x43, 0x61,
+	0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x25, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65,
+	0x65, 0x72, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65,
+	0x71, 0x1a, 0x26, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
+	0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x5a, 0x0a, 0x0f, 0x47,
+	0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73, 0x68, 0x12, 0x21,
+	0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47,
+	0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73, 0x68, 0x52, 0x65,
+	0x71, 0x1a, 0x22, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
+	0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x52, 0x69, 0x6e, 0x67, 0x48, 0x61, 0x73,
+	0x68, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x6c, 0x0a, 0x15, 0x48, 0x61, 0x6e, 0x64, 0x6f,
+	0x66, 0x66, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x12, 0x27, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72,
+	0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x28, 0x2e, 0x70, 0x62, 0x2e, 0x67,
+	0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66,
+	0x66, 0x50, 0x65, 0x65, 0x72, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x22, 0x00, 0x42, 0x28, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2d, 0x69,
+	0x6f, 0x2f, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x80, 0x01, 0x01, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_peers_proto_rawDescOnce sync.Once
+	file_peers_proto_rawDescData = file_peers_proto_rawDesc
+)
+
+func file_peers_proto_rawDescGZIP() []byte {
+	file_peers_proto_rawDescOnce.Do(func() {
+		file_peers_proto_rawDescData = protoimpl.X.CompressGZIP(file_peers_proto_rawDescData)
+	})
+	return file_peers_proto_rawDescData
+}
+
+var file_peers_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_peers_proto_goTypes = []interface{}{
+	(*GetPeerRateLimitsReq)(nil),      // 0: pb.gubernator.GetPeerRateLimitsReq
+	(*AggregatedRequest)(nil),         // 1: pb.gubernator.AggregatedRequest
+	(*GetPeerRateLimitsResp)(nil),     // 2: pb.gubernator.GetPeerRateLimitsResp
+	(*UpdatePeerGlobalsReq)(nil),      // 3: pb.gubernator.UpdatePeerGlobalsReq
+	(*UpdatePeerGlobal)(nil),          // 4: pb.gubernator.UpdatePeerGlobal
+	(*UpdatePeerGlobalsResp)(nil),     // 5: pb.gubernator.UpdatePeerGlobalsResp
+	(*GetPeerLoadReq)(nil),            // 6: pb.gubernator.GetPeerLoadReq
+	(*GetPeerLoadResp)(nil),           // 7: pb.gubernator.GetPeerLoadResp
+	(*UpdatePeerCountersReq)(nil),     // 8: pb.gubernator.UpdatePeerCountersReq
+	(*PeerCounter)(nil),               // 9: pb.gubernator.PeerCounter
+	(*UpdatePeerCountersResp)(nil),    // 10: pb.gubernator.UpdatePeerCountersResp
+	(*PeerStreamReq)(nil),             // 11: pb.gubernator.PeerStreamReq
+	(*PeerStreamResp)(nil),            // 12: pb.gubernator.PeerStreamResp
+	(*GetPeerCapabilitiesReq)(nil),    // 13: pb.gubernator.GetPeerCapabilitiesReq
+	(*GetPeerCapabilitiesResp)(nil),   // 14: pb.gubernator.GetPeerCapabilitiesResp
+	(*GetPeerRingHashReq)(nil),        // 15: pb.gubernator.GetPeerRingHashReq
+	(*GetPeerRingHashResp)(nil),       // 16: pb.gubernator.GetPeerRingHashResp
+	(*HandoffPeerRateLimitsReq)(nil),  // 17: pb.gubernator.HandoffPeerRateLimitsReq
+	(*HandoffRateLimit)(nil),          // 18: pb.gubernator.HandoffRateLimit
+	(*HandoffPeerRateLimitsResp)(nil), // 19: pb.gubernator.HandoffPeerRateLimitsResp
+	nil,                               // 20: pb.gubernator.GetPeerLoadResp.LoadEntry
+	nil,                               // 21: pb.gubernator.GetPeerLoadResp.ShedEntry
+	nil,                               // 22: pb.gubernator.PeerCounter.HitsEntry
+	(*RateLimitReq)(nil),              // 23: pb.gubernator.RateLimitReq
+	(*RateLimitResp)(nil),             // 24: pb.gubernator.RateLimitResp
+	(Algorithm)(0),                    // 25: pb.gubernator.Algorithm
+	(Status)(0),                       // 26: pb.gubernator.Status
+	(*LeaseTokensReq)(nil),            // 27: pb.gubernator.LeaseTokensReq
+	(*LeaseTokensResp)(nil),           // 28: pb.gubernator.LeaseTokensResp
+}
+var file_peers_proto_depIdxs = []int32{
+	23, // 0: pb.gubernator.GetPeerRateLimitsReq.requests:type_name -> pb.gubernator.RateLimitReq
+	1,  // 1: pb.gubernator.GetPeerRateLimitsReq.aggregated_requests:type_name -> pb.gubernator.AggregatedRequest
+	24, // 2: pb.gubernator.GetPeerRateLimitsResp.rate_limits:type_name -> pb.gubernator.RateLimitResp
+	4,  // 3: pb.gubernator.UpdatePeerGlobalsReq.globals:type_name -> pb.gubernator.UpdatePeerGlobal
+	24, // 4: pb.gubernator.UpdatePeerGlobal.status:type_name -> pb.gubernator.RateLimitResp
+	25, // 5: pb.gubernator.UpdatePeerGlobal.algorithm:type_name -> pb.gubernator.Algorithm
+	20, // 6: pb.gubernator.GetPeerLoadResp.load:type_name -> pb.gubernator.GetPeerLoadResp.LoadEntry
+	21, // 7: pb.gubernator.GetPeerLoadResp.shed:type_name -> pb.gubernator.GetPeerLoadResp.ShedEntry
+	9,  // 8: pb.gubernator.UpdatePeerCountersReq.counters:type_name -> pb.gubernator.PeerCounter
+	22, // 9: pb.gubernator.PeerCounter.hits:type_name -> pb.gubernator.PeerCounter.HitsEntry
+	0,  // 10: pb.gubernator.PeerStreamReq.get_peer_rate_limits:type_name -> pb.gubernator.GetPeerRateLimitsReq
+	3,  // 11: pb.gubernator.PeerStreamReq.update_peer_globals:type_name -> pb.gubernator.UpdatePeerGlobalsReq
+	2,  // 12: pb.gubernator.PeerStreamResp.get_peer_rate_limits:type_name -> pb.gubernator.GetPeerRateLimitsResp
+	5,  // 13: pb.gubernator.PeerStreamResp.update_peer_globals:type_name -> pb.gubernator.UpdatePeerGlobalsResp
+	18, // 14: pb.gubernator.HandoffPeerRateLimitsReq.rate_limits:type_name -> pb.gubernator.HandoffRateLimit
+	25, // 15: pb.gubernator.HandoffRateLimit.algorithm:type_name -> pb.gubernator.Algorithm
+	26, // 16: pb.gubernator.HandoffRateLimit.status:type_name -> pb.gubernator.Status
+	0,  // 17: pb.gubernator.PeersV1.GetPeerRateLimits:input_type -> pb.gubernator.GetPeerRateLimitsReq
+	3,  // 18: pb.gubernator.PeersV1.UpdatePeerGlobals:input_type -> pb.gubernator.UpdatePeerGlobalsReq
+	27, // 19: pb.gubernator.PeersV1.LeasePeerTokens:input_type -> pb.gubernator.LeaseTokensReq
+	6,  // 20: pb.gubernator.PeersV1.GetPeerLoad:input_type -> pb.gubernator.GetPeerLoadReq
+	8,  // 21: pb.gubernator.PeersV1.UpdatePeerCounters:input_type -> pb.gubernator.UpdatePeerCountersReq
+	11, // 22: pb.gubernator.PeersV1.PeerStream:input_type -> pb.gubernator.PeerStreamReq
+	13, // 23: pb.gubernator.PeersV1.GetPeerCapabilities:input_type -> pb.gubernator.GetPeerCapabilitiesReq
+	15, // 24: pb.gubernator.PeersV1.GetPeerRingHash:input_type -> pb.gubernator.GetPeerRingHashReq
+	17, // 25: pb.gubernator.PeersV1.HandoffPeerRateLimits:input_type -> pb.gubernator.HandoffPeerRateLimitsReq
+	2,  // 26: pb.gubernator.PeersV1.GetPeerRateLimits:output_type -> pb.gubernator.GetPeerRateLimitsResp
+	5,  // 27: pb.gubernator.PeersV1.UpdatePeerGlobals:output_type -> pb.gubernator.UpdatePeerGlobalsResp
+	28, // 28: pb.gubernator.PeersV1.LeasePeerTokens:output_type -> pb.gubernator.LeaseTokensResp
+	7,  // 29: pb.gubernator.PeersV1.GetPeerLoad:output_type -> pb.gubernator.GetPeerLoadResp
+	10, // 30: pb.gubernator.PeersV1.UpdatePeerCounters:output_type -> pb.gubernator.UpdatePeerCountersResp
+	12, // 31: pb.gubernator.PeersV1.PeerStream:output_type -> pb.gubernator.PeerStreamResp
+	14, // 32: pb.gubernator.PeersV1.GetPeerCapabilities:output_type -> pb.gubernator.GetPeerCapabilitiesResp
+	16, // 33: pb.gubernator.PeersV1.GetPeerRingHash:output_type -> pb.gubernator.GetPeerRingHashResp
+	19, // 34: pb.gubernator.PeersV1.HandoffPeerRateLimits:output_type -> pb.gubernator.HandoffPeerRateLimitsResp
+	26, // [26:35] is the sub-list for method output_type
+	17, // [17:26] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_peers_proto_init() }
+func file_peers_proto_init() {
+	if File_peers_proto != nil {
+		return
+	}
+	file_gubernator_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_peers_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerRateLimitsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AggregatedRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerRateLimitsResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdatePeerGlobalsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
 			case 2:
 				return &v.unknownFields
 			default:
@@ -462,6 +1638,18 @@ func file_peers_proto_init() {
 			}
 		}
 		file_peers_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdatePeerGlobal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*UpdatePeerGlobalsResp); i {
 			case 0:
 				return &v.state
@@ -473,6 +1661,182 @@ func file_peers_proto_init() {
 				return nil
 			}
 		}
+		file_peers_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerLoadReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerLoadResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdatePeerCountersReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerCounter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdatePeerCountersResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerStreamReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerStreamResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerCapabilitiesReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerCapabilitiesResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerRingHashReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerRingHashResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HandoffPeerRateLimitsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HandoffRateLimit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_peers_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HandoffPeerRateLimitsResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_peers_proto_msgTypes[11].OneofWrappers = []interface{}{
+		(*PeerStreamReq_GetPeerRateLimits)(nil),
+		(*PeerStreamReq_UpdatePeerGlobals)(nil),
+	}
+	file_peers_proto_msgTypes[12].OneofWrappers = []interface{}{
+		(*PeerStreamResp_GetPeerRateLimits)(nil),
+		(*PeerStreamResp_UpdatePeerGlobals)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -480,7 +1844,7 @@ func file_peers_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_peers_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   23,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -34,8 +34,15 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	PeersV1_GetPeerRateLimits_FullMethodName = "/pb.gubernator.PeersV1/GetPeerRateLimits"
-	PeersV1_UpdatePeerGlobals_FullMethodName = "/pb.gubernator.PeersV1/UpdatePeerGlobals"
+	PeersV1_GetPeerRateLimits_FullMethodName     = "/pb.gubernator.PeersV1/GetPeerRateLimits"
+	PeersV1_UpdatePeerGlobals_FullMethodName     = "/pb.gubernator.PeersV1/UpdatePeerGlobals"
+	PeersV1_LeasePeerTokens_FullMethodName       = "/pb.gubernator.PeersV1/LeasePeerTokens"
+	PeersV1_GetPeerLoad_FullMethodName           = "/pb.gubernator.PeersV1/GetPeerLoad"
+	PeersV1_UpdatePeerCounters_FullMethodName    = "/pb.gubernator.PeersV1/UpdatePeerCounters"
+	PeersV1_PeerStream_FullMethodName            = "/pb.gubernator.PeersV1/PeerStream"
+	PeersV1_GetPeerCapabilities_FullMethodName   = "/pb.gubernator.PeersV1/GetPeerCapabilities"
+	PeersV1_GetPeerRingHash_FullMethodName       = "/pb.gubernator.PeersV1/GetPeerRingHash"
+	PeersV1_HandoffPeerRateLimits_FullMethodName = "/pb.gubernator.PeersV1/HandoffPeerRateLimits"
 )
 
 // PeersV1Client is the client API for PeersV1 service.
@@ -46,6 +53,22 @@ type PeersV1Client interface {
 	GetPeerRateLimits(ctx context.Context, in *GetPeerRateLimitsReq, opts ...grpc.CallOption) (*GetPeerRateLimitsResp, error)
 	// Used by owner peers to send global rate limit updates to non-owner peers
 	UpdatePeerGlobals(ctx context.Context, in *UpdatePeerGlobalsReq, opts ...grpc.CallOption) (*UpdatePeerGlobalsResp, error)
+	// Used by peers to relay token lease requests to the owner peer
+	LeasePeerTokens(ctx context.Context, in *LeaseTokensReq, opts ...grpc.CallOption) (*LeaseTokensResp, error)
+	// Used by peers to share the load they observed on each peer with the BoundedLoadHash picker
+	GetPeerLoad(ctx context.Context, in *GetPeerLoadReq, opts ...grpc.CallOption) (*GetPeerLoadResp, error)
+	// Used by peers to gossip the hits counted for GLOBAL_CRDT rate limits to the other peers
+	UpdatePeerCounters(ctx context.Context, in *UpdatePeerCountersReq, opts ...grpc.CallOption) (*UpdatePeerCountersResp, error)
+	// Used by peers to multiplex forwarded rate limits, global hits and global updates to a peer
+	// over a single long-lived stream, see `GUBER_PEER_STREAMING`
+	PeerStream(ctx context.Context, opts ...grpc.CallOption) (PeersV1_PeerStreamClient, error)
+	// Used by peers to negotiate the peer protocol capabilities they may use with a peer
+	GetPeerCapabilities(ctx context.Context, in *GetPeerCapabilitiesReq, opts ...grpc.CallOption) (*GetPeerCapabilitiesResp, error)
+	// Used by peers to check every peer has the same view of the cluster, see `V1.GetTopology`
+	GetPeerRingHash(ctx context.Context, in *GetPeerRingHashReq, opts ...grpc.CallOption) (*GetPeerRingHashResp, error)
+	// Used by a draining peer to hand off the rate limits it owns to their next owners,
+	// see `GUBER_DRAIN_TIMEOUT`
+	HandoffPeerRateLimits(ctx context.Context, in *HandoffPeerRateLimitsReq, opts ...grpc.CallOption) (*HandoffPeerRateLimitsResp, error)
 }
 
 type peersV1Client struct {
@@ -74,6 +97,91 @@ func (c *peersV1Client) UpdatePeerGlobals(ctx context.Context, in *UpdatePeerGlo
 	return out, nil
 }
 
+func (c *peersV1Client) LeasePeerTokens(ctx context.Context, in *LeaseTokensReq, opts ...grpc.CallOption) (*LeaseTokensResp, error) {
+	out := new(LeaseTokensResp)
+	err := c.cc.Invoke(ctx, PeersV1_LeasePeerTokens_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) GetPeerLoad(ctx context.Context, in *GetPeerLoadReq, opts ...grpc.CallOption) (*GetPeerLoadResp, error) {
+	out := new(GetPeerLoadResp)
+	err := c.cc.Invoke(ctx, PeersV1_GetPeerLoad_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) UpdatePeerCounters(ctx context.Context, in *UpdatePeerCountersReq, opts ...grpc.CallOption) (*UpdatePeerCountersResp, error) {
+	out := new(UpdatePeerCountersResp)
+	err := c.cc.Invoke(ctx, PeersV1_UpdatePeerCounters_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) PeerStream(ctx context.Context, opts ...grpc.CallOption) (PeersV1_PeerStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PeersV1_ServiceDesc.Streams[0], PeersV1_PeerStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &peersV1PeerStreamClient{stream}
+	return x, nil
+}
+
+type PeersV1_PeerStreamClient interface {
+	Send(*PeerStreamReq) error
+	Recv() (*PeerStreamResp, error)
+	grpc.ClientStream
+}
+
+type peersV1PeerStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *peersV1PeerStreamClient) Send(m *PeerStreamReq) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *peersV1PeerStreamClient) Recv() (*PeerStreamResp, error) {
+	m := new(PeerStreamResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *peersV1Client) GetPeerCapabilities(ctx context.Context, in *GetPeerCapabilitiesReq, opts ...grpc.CallOption) (*GetPeerCapabilitiesResp, error) {
+	out := new(GetPeerCapabilitiesResp)
+	err := c.cc.Invoke(ctx, PeersV1_GetPeerCapabilities_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) GetPeerRingHash(ctx context.Context, in *GetPeerRingHashReq, opts ...grpc.CallOption) (*GetPeerRingHashResp, error) {
+	out := new(GetPeerRingHashResp)
+	err := c.cc.Invoke(ctx, PeersV1_GetPeerRingHash_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) HandoffPeerRateLimits(ctx context.Context, in *HandoffPeerRateLimitsReq, opts ...grpc.CallOption) (*HandoffPeerRateLimitsResp, error) {
+	out := new(HandoffPeerRateLimitsResp)
+	err := c.cc.Invoke(ctx, PeersV1_HandoffPeerRateLimits_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PeersV1Server is the server API for PeersV1 service.
 // All implementations should embed UnimplementedPeersV1Server
 // for forward compatibility
@@ -82,6 +190,22 @@ type PeersV1Server interface {
 	GetPeerRateLimits(context.Context, *GetPeerRateLimitsReq) (*GetPeerRateLimitsResp, error)
 	// Used by owner peers to send global rate limit updates to non-owner peers
 	UpdatePeerGlobals(context.Context, *UpdatePeerGlobalsReq) (*UpdatePeerGlobalsResp, error)
+	// Used by peers to relay token lease requests to the owner peer
+	LeasePeerTokens(context.Context, *LeaseTokensReq) (*LeaseTokensResp, error)
+	// Used by peers to share the load they observed on each peer with the BoundedLoadHash picker
+	GetPeerLoad(context.Context, *GetPeerLoadReq) (*GetPeerLoadResp, error)
+	// Used by peers to gossip the hits counted for GLOBAL_CRDT rate limits to the other peers
+	UpdatePeerCounters(context.Context, *UpdatePeerCountersReq) (*UpdatePeerCountersResp, error)
+	// Used by peers to multiplex forwarded rate limits, global hits and global updates to a peer
+	// over a single long-lived stream, see `GUBER_PEER_STREAMING`
+	PeerStream(PeersV1_PeerStreamServer) error
+	// Used by peers to negotiate the peer protocol capabilities they may use with a peer
+	GetPeerCapabilities(context.Context, *GetPeerCapabilitiesReq) (*GetPeerCapabilitiesResp, error)
+	// Used by peers to check every peer has the same view of the cluster, see `V1.GetTopology`
+	GetPeerRingHash(context.Context, *GetPeerRingHashReq) (*GetPeerRingHashResp, error)
+	// Used by a draining peer to hand off the rate limits it owns to their next owners,
+	// see `GUBER_DRAIN_TIMEOUT`
+	HandoffPeerRateLimits(context.Context, *HandoffPeerRateLimitsReq) (*HandoffPeerRateLimitsResp, error)
 }
 
 // UnimplementedPeersV1Server should be embedded to have forward compatible implementations.
@@ -94,6 +218,27 @@ func (UnimplementedPeersV1Server) GetPeerRateLimits(context.Context, *GetPeerRat
 func (UnimplementedPeersV1Server) UpdatePeerGlobals(context.Context, *UpdatePeerGlobalsReq) (*UpdatePeerGlobalsResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdatePeerGlobals not implemented")
 }
+func (UnimplementedPeersV1Server) LeasePeerTokens(context.Context, *LeaseTokensReq) (*LeaseTokensResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeasePeerTokens not implemented")
+}
+func (UnimplementedPeersV1Server) GetPeerLoad(context.Context, *GetPeerLoadReq) (*GetPeerLoadResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeerLoad not implemented")
+}
+func (UnimplementedPeersV1Server) UpdatePeerCounters(context.Context, *UpdatePeerCountersReq) (*UpdatePeerCountersResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePeerCounters not implemented")
+}
+func (UnimplementedPeersV1Server) PeerStream(PeersV1_PeerStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method PeerStream not implemented")
+}
+func (UnimplementedPeersV1Server) GetPeerCapabilities(context.Context, *GetPeerCapabilitiesReq) (*GetPeerCapabilitiesResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeerCapabilities not implemented")
+}
+func (UnimplementedPeersV1Server) GetPeerRingHash(context.Context, *GetPeerRingHashReq) (*GetPeerRingHashResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeerRingHash not implemented")
+}
+func (UnimplementedPeersV1Server) HandoffPeerRateLimits(context.Context, *HandoffPeerRateLimitsReq) (*HandoffPeerRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandoffPeerRateLimits not implemented")
+}
 
 // UnsafePeersV1Server may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to PeersV1Server will
@@ -142,6 +287,140 @@ func _PeersV1_UpdatePeerGlobals_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PeersV1_LeasePeerTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseTokensReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).LeasePeerTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_LeasePeerTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).LeasePeerTokens(ctx, req.(*LeaseTokensReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_GetPeerLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeerLoadReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).GetPeerLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_GetPeerLoad_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).GetPeerLoad(ctx, req.(*GetPeerLoadReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_UpdatePeerCounters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePeerCountersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).UpdatePeerCounters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_UpdatePeerCounters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).UpdatePeerCounters(ctx, req.(*UpdatePeerCountersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_PeerStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PeersV1Server).PeerStream(&peersV1PeerStreamServer{stream})
+}
+
+type PeersV1_PeerStreamServer interface {
+	Send(*PeerStreamResp) error
+	Recv() (*PeerStreamReq, error)
+	grpc.ServerStream
+}
+
+type peersV1PeerStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *peersV1PeerStreamServer) Send(m *PeerStreamResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *peersV1PeerStreamServer) Recv() (*PeerStreamReq, error) {
+	m := new(PeerStreamReq)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _PeersV1_GetPeerCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeerCapabilitiesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).GetPeerCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_GetPeerCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).GetPeerCapabilities(ctx, req.(*GetPeerCapabilitiesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_GetPeerRingHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeerRingHashReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).GetPeerRingHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_GetPeerRingHash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).GetPeerRingHash(ctx, req.(*GetPeerRingHashReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_HandoffPeerRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandoffPeerRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).HandoffPeerRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeersV1_HandoffPeerRateLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).HandoffPeerRateLimits(ctx, req.(*HandoffPeerRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PeersV1_ServiceDesc is the grpc.ServiceDesc for PeersV1 service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -157,7 +436,38 @@ var PeersV1_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdatePeerGlobals",
 			Handler:    _PeersV1_UpdatePeerGlobals_Handler,
 		},
+		{
+			MethodName: "LeasePeerTokens",
+			Handler:    _PeersV1_LeasePeerTokens_Handler,
+		},
+		{
+			MethodName: "GetPeerLoad",
+			Handler:    _PeersV1_GetPeerLoad_Handler,
+		},
+		{
+			MethodName: "UpdatePeerCounters",
+			Handler:    _PeersV1_UpdatePeerCounters_Handler,
+		},
+		{
+			MethodName: "GetPeerCapabilities",
+			Handler:    _PeersV1_GetPeerCapabilities_Handler,
+		},
+		{
+			MethodName: "GetPeerRingHash",
+			Handler:    _PeersV1_GetPeerRingHash_Handler,
+		},
+		{
+			MethodName: "HandoffPeerRateLimits",
+			Handler:    _PeersV1_HandoffPeerRateLimits_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PeerStream",
+			Handler:       _PeersV1_PeerStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "peers.proto",
 }
@@ -39,7 +39,7 @@ func NewRegionPicker(fn HashString64) *RegionPicker {
 	rp := &RegionPicker{
 		regions:                  make(map[string]PeerPicker),
 		reqQueue:                 make(chan *RateLimitReq),
-		ReplicatedConsistentHash: NewReplicatedConsistentHash(fn, defaultReplicas),
+		ReplicatedConsistentHash: NewReplicatedConsistentHash(fn, DefaultReplicas),
 	}
 	return rp
 }
@@ -0,0 +1,132 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Implements PeerPicker using Rendezvous or Highest Random Weight hashing, see
+// https://en.wikipedia.org/wiki/Rendezvous_hashing
+// Each key is assigned to the peer with the highest score for that key. Every peer is
+// assigned a share of the keys in proportion to its weight, and only the keys of a peer
+// which is added or removed are reassigned. Get() scores every peer, so it is O(n) in
+// the number of peers.
+type RendezvousHash struct {
+	hashFunc HashString64
+	// Sorted by address such that ties are broken the same way by every instance
+	peerKeys []peerInfo
+	peers    map[string]*PeerClient
+}
+
+func NewRendezvousHash(fn HashString64) *RendezvousHash {
+	rh := &RendezvousHash{
+		hashFunc: fn,
+		peers:    make(map[string]*PeerClient),
+	}
+
+	if rh.hashFunc == nil {
+		rh.hashFunc = defaultHashString64
+	}
+	return rh
+}
+
+func (rh *RendezvousHash) New() PeerPicker {
+	return &RendezvousHash{
+		hashFunc: rh.hashFunc,
+		peers:    make(map[string]*PeerClient),
+	}
+}
+
+func (rh *RendezvousHash) Peers() []*PeerClient {
+	var results []*PeerClient
+	for _, v := range rh.peers {
+		results = append(results, v)
+	}
+	return results
+}
+
+// Adds a peer to the hash
+func (rh *RendezvousHash) Add(peer *PeerClient) {
+	rh.peers[peer.Info().GRPCAddress] = peer
+	rh.peerKeys = append(rh.peerKeys, peerInfo{
+		hash: rh.hashFunc(peer.Info().GRPCAddress),
+		peer: peer,
+	})
+
+	sort.Slice(rh.peerKeys, func(i, j int) bool {
+		return rh.peerKeys[i].peer.Info().GRPCAddress < rh.peerKeys[j].peer.Info().GRPCAddress
+	})
+}
+
+// Returns number of peers in the picker
+func (rh *RendezvousHash) Size() int {
+	return len(rh.peers)
+}
+
+// Returns the peer by hostname
+func (rh *RendezvousHash) GetByPeerInfo(peer PeerInfo) *PeerClient {
+	return rh.peers[peer.GRPCAddress]
+}
+
+// Given a key, return the peer that key is assigned too
+func (rh *RendezvousHash) Get(key string) (*PeerClient, error) {
+	if rh.Size() == 0 {
+		return nil, errors.New("unable to pick a peer; pool is empty")
+	}
+	hash := rh.hashFunc(key)
+
+	var best *PeerClient
+	var bestScore float64
+	for _, p := range rh.peerKeys {
+		// Weighted rendezvous hashing scores a peer with `-weight / ln(u)`, where `u` is the hash
+		// mapped to the open interval (0, 1), such that each peer wins in proportion to its weight.
+		u := (float64(mixHash64(hash^p.hash)>>11) + 0.5) / (1 << 53)
+		score := -float64(p.peer.Info().weight()) / math.Log(u)
+		if best == nil || score > bestScore {
+			best, bestScore = p.peer, score
+		}
+	}
+	return best, nil
+}
+
+// Ownership returns the fraction of the keys owned by each peer
+func (rh *RendezvousHash) Ownership() map[string]float64 {
+	var total int
+	for _, p := range rh.peers {
+		total += p.Info().weight()
+	}
+	results := make(map[string]float64, len(rh.peers))
+	for addr, p := range rh.peers {
+		results[addr] = float64(p.Info().weight()) / float64(total)
+	}
+	return results
+}
+
+// mixHash64 is the finalizer of SplitMix64. It spreads the bits of hashes which
+// differ in only a few bits, like the FNV hashes of similar keys, across all 64 bits.
+func mixHash64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -19,6 +19,7 @@ package gubernator
 import (
 	"crypto/md5"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 
@@ -26,7 +27,8 @@ import (
 	"github.com/segmentio/fasthash/fnv1"
 )
 
-const defaultReplicas = 512
+// DefaultReplicas is the number of replicas each peer is given in the ReplicatedConsistentHash
+const DefaultReplicas = 512
 
 type HashString64 func(data string) uint64
 
@@ -74,12 +76,12 @@ func (ch *ReplicatedConsistentHash) Peers() []*PeerClient {
 	return results
 }
 
-// Adds a peer to the hash
+// Adds a peer to the hash, with a number of replicas in proportion to its weight
 func (ch *ReplicatedConsistentHash) Add(peer *PeerClient) {
 	ch.peers[peer.Info().GRPCAddress] = peer
 
 	key := fmt.Sprintf("%x", md5.Sum([]byte(peer.Info().GRPCAddress)))
-	for i := 0; i < ch.replicas; i++ {
+	for i := 0; i < ch.replicas*peer.Info().weight(); i++ {
 		hash := ch.hashFunc(strconv.Itoa(i) + key)
 		ch.peerKeys = append(ch.peerKeys, peerInfo{
 			hash: hash,
@@ -100,13 +102,31 @@ func (ch *ReplicatedConsistentHash) GetByPeerInfo(peer PeerInfo) *PeerClient {
 	return ch.peers[peer.GRPCAddress]
 }
 
+// Ownership returns the fraction of the hash space owned by each peer
+func (ch *ReplicatedConsistentHash) Ownership() map[string]float64 {
+	results := make(map[string]float64, len(ch.peers))
+	for i, p := range ch.peerKeys {
+		// Each replica owns the hashes after the previous replica, up to and including its own hash
+		prev := ch.peerKeys[(i+len(ch.peerKeys)-1)%len(ch.peerKeys)].hash
+		arc := float64(p.hash - prev)
+		if len(ch.peerKeys) == 1 {
+			arc = math.MaxUint64
+		}
+		results[p.peer.Info().GRPCAddress] += arc / math.MaxUint64
+	}
+	return results
+}
+
 // Given a key, return the peer that key is assigned too
 func (ch *ReplicatedConsistentHash) Get(key string) (*PeerClient, error) {
 	if ch.Size() == 0 {
 		return nil, errors.New("unable to pick a peer; pool is empty")
 	}
-	hash := ch.hashFunc(key)
+	return ch.peerKeys[ch.search(ch.hashFunc(key))].peer, nil
+}
 
+// search returns the index of the replica which owns the hash
+func (ch *ReplicatedConsistentHash) search(hash uint64) int {
 	// Binary search for appropriate peer
 	idx := sort.Search(len(ch.peerKeys), func(i int) bool { return ch.peerKeys[i].hash >= hash })
 
@@ -114,6 +134,5 @@ func (ch *ReplicatedConsistentHash) Get(key string) (*PeerClient, error) {
 	if idx == len(ch.peerKeys) {
 		idx = 0
 	}
-
-	return ch.peerKeys[idx].peer, nil
+	return idx
 }
@@ -29,7 +29,7 @@ func TestReplicatedConsistentHash(t *testing.T) {
 	hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
 
 	t.Run("Size", func(t *testing.T) {
-		hash := NewReplicatedConsistentHash(nil, defaultReplicas)
+		hash := NewReplicatedConsistentHash(nil, DefaultReplicas)
 
 		for _, h := range hosts {
 			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
@@ -39,7 +39,7 @@ func TestReplicatedConsistentHash(t *testing.T) {
 	})
 
 	t.Run("Host", func(t *testing.T) {
-		hash := NewReplicatedConsistentHash(nil, defaultReplicas)
+		hash := NewReplicatedConsistentHash(nil, DefaultReplicas)
 		hostMap := map[string]*PeerClient{}
 
 		for _, h := range hosts {
@@ -83,7 +83,7 @@ func TestReplicatedConsistentHash(t *testing.T) {
 			},
 		}} {
 			t.Run(tc.name, func(t *testing.T) {
-				hash := NewReplicatedConsistentHash(tc.inHashFunc, defaultReplicas)
+				hash := NewReplicatedConsistentHash(tc.inHashFunc, DefaultReplicas)
 				distribution := make(map[string]int)
 
 				for _, h := range hosts {
@@ -100,6 +100,38 @@ func TestReplicatedConsistentHash(t *testing.T) {
 		}
 	})
 
+	t.Run("weighted", func(t *testing.T) {
+		keys := make([]string, 10000)
+		for i := range keys {
+			keys[i] = net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+		}
+		hash := NewReplicatedConsistentHash(nil, DefaultReplicas)
+		weights := map[string]int{"a.svc.local": 1, "b.svc.local": 2, "c.svc.local": 1}
+		for h, w := range weights {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h, Weight: w}}})
+		}
+
+		distribution := make(map[string]int)
+		for i := range keys {
+			peer, _ := hash.Get(keys[i])
+			distribution[peer.Info().GRPCAddress]++
+		}
+
+		// Peers own keys in proportion to their weight, as reported by Ownership(). The ring
+		// of replicas is less even than the other pickers, so the share may be off by 5%.
+		ownership := hash.Ownership()
+		for h, w := range weights {
+			assert.InDelta(t, float64(w)/4, float64(distribution[h])/float64(len(keys)), 0.05, h)
+			assert.InDelta(t, float64(distribution[h])/float64(len(keys)), ownership[h], 0.03, h)
+		}
+	})
+
+	t.Run("weight is capped", func(t *testing.T) {
+		hash := NewReplicatedConsistentHash(nil, DefaultReplicas)
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "a.svc.local", Weight: 65535}}})
+		assert.Len(t, hash.peerKeys, DefaultReplicas*MaxPeerWeight)
+	})
+
 }
 
 func BenchmarkReplicatedConsistantHash(b *testing.B) {
@@ -115,7 +147,7 @@ func BenchmarkReplicatedConsistantHash(b *testing.B) {
 				ips[i] = net.IPv4(byte(i>>24), byte(i>>16), byte(i>>8), byte(i)).String()
 			}
 
-			hash := NewReplicatedConsistentHash(hashFunc, defaultReplicas)
+			hash := NewReplicatedConsistentHash(hashFunc, DefaultReplicas)
 			hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local"}
 			for _, h := range hosts {
 				hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
@@ -0,0 +1,345 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/syncutil"
+)
+
+// The rate limit name used for keys throttled via CL.THROTTLE
+const respThrottleName = "cl.throttle"
+
+// The max number of arguments or bytes in a single argument we accept from a RESP client
+const (
+	respMaxArgs     = 1024
+	respMaxArgBytes = 512 * 1024
+)
+
+var errRESPQuit = errors.New("client quit")
+
+// respServer implements a small subset of the Redis protocol (RESP), enough for clients of the
+// redis-cell module to use gubernator via `CL.THROTTLE` without code changes.
+// See https://github.com/brandur/redis-cell
+type respServer struct {
+	listener net.Listener
+	instance *V1Instance
+	log      FieldLogger
+	wg       syncutil.WaitGroup
+
+	mutex  sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+func newRESPServer(l net.Listener, instance *V1Instance, log FieldLogger) *respServer {
+	return &respServer{
+		listener: l,
+		instance: instance,
+		log:      log,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts connections until Close() is called
+func (s *respServer) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if !closed {
+				s.log.WithError(err).Error("while accepting RESP connection")
+			}
+			return
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Go(func() {
+			s.handleConn(conn)
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+			_ = conn.Close()
+		})
+	}
+}
+
+// Close stops accepting connections and closes all open connections
+func (s *respServer) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	_ = s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+func (s *respServer) handleConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			var protoErr respProtocolError
+			if errors.As(err, &protoErr) {
+				writeRESPError(w, "ERR Protocol error: "+protoErr.Error())
+				_ = w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		err = s.handleCommand(context.Background(), w, args)
+		if flushErr := w.Flush(); flushErr != nil {
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleCommand writes the reply for the command to `w`. Returns an error if the connection should be closed.
+func (s *respServer) handleCommand(ctx context.Context, w *bufio.Writer, args []string) error {
+	switch strings.ToUpper(args[0]) {
+	case "CL.THROTTLE":
+		s.throttle(ctx, w, args)
+	case "PING":
+		switch len(args) {
+		case 1:
+			writeRESPSimple(w, "PONG")
+		case 2:
+			writeRESPBulk(w, args[1])
+		default:
+			writeRESPError(w, "ERR wrong number of arguments for 'ping' command")
+		}
+	case "INFO":
+		s.info(ctx, w)
+	case "COMMAND":
+		// Sent by redis-cli on connect; we don't provide command docs
+		_, _ = w.WriteString("*0\r\n")
+	case "QUIT":
+		writeRESPSimple(w, "OK")
+		return errRESPQuit
+	default:
+		writeRESPError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	return nil
+}
+
+// throttle implements `CL.THROTTLE <key> <max_burst> <count per period> <period> [<quantity>]`
+// using a leaky bucket which leaks `count` tokens every `period` seconds and holds at most
+// `max_burst + 1` tokens. Replies with an array of
+//
+//  1. Whether the action was limited: 0 for allowed, 1 for limited
+//  2. The total limit of the key (max_burst + 1)
+//  3. The remaining limit of the key
+//  4. The number of seconds until the user should retry, -1 if the action was allowed
+//  5. The number of seconds until the limit resets to its maximum capacity
+func (s *respServer) throttle(ctx context.Context, w *bufio.Writer, args []string) {
+	if len(args) != 5 && len(args) != 6 {
+		writeRESPError(w, "ERR wrong number of arguments for 'cl.throttle' command")
+		return
+	}
+
+	ints := make([]int64, 0, 4)
+	for _, arg := range args[2:] {
+		i, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			writeRESPError(w, "ERR value is not an integer or out of range")
+			return
+		}
+		ints = append(ints, i)
+	}
+	maxBurst, count, period, quantity := ints[0], ints[1], ints[2], int64(1)
+	if len(ints) == 4 {
+		quantity = ints[3]
+	}
+
+	if maxBurst < 0 || count <= 0 || period <= 0 || quantity < 0 {
+		writeRESPError(w, "ERR invalid rate limit; max_burst and quantity must not be negative, "+
+			"count and period must be greater than 0")
+		return
+	}
+
+	req := &RateLimitReq{
+		Name:      respThrottleName,
+		UniqueKey: args[1],
+		Algorithm: Algorithm_LEAKY_BUCKET,
+		Limit:     count,
+		Duration:  period * Second,
+		Burst:     maxBurst + 1,
+		Hits:      quantity,
+	}
+
+	resp, err := s.instance.GetRateLimits(ctx, &GetRateLimitsReq{Requests: []*RateLimitReq{req}})
+	if err != nil {
+		writeRESPError(w, "ERR "+err.Error())
+		return
+	}
+	rl := resp.Responses[0]
+	if rl.Error != "" {
+		writeRESPError(w, "ERR "+rl.Error)
+		return
+	}
+
+	// Milliseconds it takes for a single token to leak
+	rate := float64(req.Duration) / float64(req.Limit)
+	toSeconds := func(tokens int64) int64 {
+		return int64(math.Ceil(float64(tokens) * rate / 1000))
+	}
+
+	limited, retryAfter := int64(0), int64(-1)
+	if rl.Status == Status_OVER_LIMIT {
+		limited = 1
+		// The request can never succeed if it asks for more than the bucket can hold
+		if quantity <= req.Burst {
+			retryAfter = toSeconds(quantity - rl.Remaining)
+		}
+	}
+
+	writeRESPIntegers(w, limited, req.Burst, rl.Remaining, retryAfter, toSeconds(req.Burst-rl.Remaining))
+}
+
+func (s *respServer) info(ctx context.Context, w *bufio.Writer) {
+	var b strings.Builder
+	b.WriteString("# Server\r\n")
+	b.WriteString("gubernator_mode:cluster\r\n")
+	fmt.Fprintf(&b, "instance_id:%s\r\n", s.instance.conf.InstanceID)
+	fmt.Fprintf(&b, "advertise_address:%s\r\n", s.instance.conf.AdvertiseAddr)
+
+	b.WriteString("\r\n# Cluster\r\n")
+	health, err := s.instance.HealthCheck(ctx, &HealthCheckReq{})
+	if err != nil {
+		fmt.Fprintf(&b, "status:%s\r\n", UnHealthy)
+		fmt.Fprintf(&b, "message:%s\r\n", strings.ReplaceAll(err.Error(), "\n", " "))
+	} else {
+		fmt.Fprintf(&b, "status:%s\r\n", health.Status)
+		fmt.Fprintf(&b, "peer_count:%d\r\n", health.PeerCount)
+	}
+	writeRESPBulk(w, b.String())
+}
+
+// respProtocolError is returned by readRESPCommand when the client sent invalid RESP
+type respProtocolError struct {
+	msg string
+}
+
+func (e respProtocolError) Error() string {
+	return e.msg
+}
+
+// readRESPCommand reads a single command as either a RESP array of bulk strings or an inline command.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inline command, as sent by telnet and `redis-cli` in some modes
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > respMaxArgs {
+		return nil, respProtocolError{msg: "invalid multibulk length"}
+	}
+
+	args := make([]string, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, respProtocolError{msg: fmt.Sprintf("expected '$', got '%.1s'", line)}
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > respMaxArgBytes {
+			return nil, respProtocolError{msg: "invalid bulk length"}
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, respProtocolError{msg: "bulk string not terminated by CRLF"}
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) > respMaxArgBytes {
+		return "", respProtocolError{msg: "line too long"}
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeRESPSimple(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("+" + s + "\r\n")
+}
+
+func writeRESPError(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("-" + strings.ReplaceAll(s, "\r\n", " ") + "\r\n")
+}
+
+func writeRESPBulk(w *bufio.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeRESPIntegers(w *bufio.Writer, ints ...int64) {
+	_, _ = fmt.Fprintf(w, "*%d\r\n", len(ints))
+	for _, i := range ints {
+		_, _ = fmt.Fprintf(w, ":%d\r\n", i)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gubernator-io/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type respConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *respConn) send(args ...string) {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	_, err := c.conn.Write([]byte(b.String()))
+	require.NoError(c.t, err)
+}
+
+// reply reads a single reply and returns it as a flat list of lines without the CRLF
+func (c *respConn) reply() []string {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	require.NoError(c.t, err)
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch line[0] {
+	case '*':
+		var n int
+		_, err := fmt.Sscanf(line, "*%d", &n)
+		require.NoError(c.t, err)
+		out := []string{line}
+		for i := 0; i < n; i++ {
+			out = append(out, c.reply()...)
+		}
+		return out
+	case '$':
+		var n int
+		_, err := fmt.Sscanf(line, "$%d", &n)
+		require.NoError(c.t, err)
+		buf := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, buf)
+		require.NoError(c.t, err)
+		return []string{line, string(buf[:n])}
+	}
+	return []string{line}
+}
+
+func TestRESP(t *testing.T) {
+	conf := gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9495",
+		HTTPListenAddress: "127.0.0.1:9485",
+		RESPListenAddress: "127.0.0.1:9475",
+		AdvertiseAddress:  "127.0.0.1:9495",
+	}
+	d := spawnDaemon(t, conf)
+	defer d.Close()
+
+	conn, err := net.Dial("tcp", d.RESPListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := &respConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	t.Run("PING", func(t *testing.T) {
+		c.send("PING")
+		assert.Equal(t, []string{"+PONG"}, c.reply())
+		c.send("ping", "hello")
+		assert.Equal(t, []string{"$5", "hello"}, c.reply())
+
+		// Inline commands
+		_, err := conn.Write([]byte("PING\r\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"+PONG"}, c.reply())
+	})
+
+	t.Run("CL.THROTTLE", func(t *testing.T) {
+		key := gubernator.RandomString(10)
+
+		// A burst of 2 tokens which leaks 1 token every 60 seconds
+		c.send("CL.THROTTLE", key, "1", "1", "60")
+		assert.Equal(t, []string{"*5", ":0", ":2", ":1", ":-1", ":60"}, c.reply())
+
+		c.send("CL.THROTTLE", key, "1", "1", "60")
+		assert.Equal(t, []string{"*5", ":0", ":2", ":0", ":-1", ":120"}, c.reply())
+
+		c.send("CL.THROTTLE", key, "1", "1", "60")
+		assert.Equal(t, []string{"*5", ":1", ":2", ":0", ":60", ":120"}, c.reply())
+
+		// Asking for more than the burst can never succeed
+		c.send("cl.throttle", gubernator.RandomString(10), "1", "1", "60", "3")
+		assert.Equal(t, []string{"*5", ":1", ":2", ":0", ":-1", ":120"}, c.reply())
+	})
+
+	t.Run("INFO", func(t *testing.T) {
+		c.send("INFO")
+		reply := c.reply()
+		require.Len(t, reply, 2)
+		assert.Contains(t, reply[1], "instance_id:")
+		assert.Contains(t, reply[1], "status:healthy")
+		assert.Contains(t, reply[1], "peer_count:1")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		c.send("CL.THROTTLE", "key", "1")
+		assert.Equal(t, []string{"-ERR wrong number of arguments for 'cl.throttle' command"}, c.reply())
+
+		c.send("CL.THROTTLE", "key", "one", "1", "60")
+		assert.Equal(t, []string{"-ERR value is not an integer or out of range"}, c.reply())
+
+		c.send("GET", "key")
+		assert.Equal(t, []string{"-ERR unknown command 'GET'"}, c.reply())
+	})
+
+	t.Run("QUIT", func(t *testing.T) {
+		c.send("QUIT")
+		assert.Equal(t, []string{"+OK"}, c.reply())
+	})
+}
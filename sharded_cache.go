@@ -0,0 +1,266 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+// Lock-striped alternative to the WorkerPool.
+// The cache key space is split into shards in the same way the WorkerPool
+// assigns keys to workers. Each shard is guarded by its own mutex, which is
+// the critical section for every key the shard owns. Requests are applied in
+// the calling goroutine, which avoids the channel hand-offs and allocations
+// of the WorkerPool at the cost of goroutines blocking on the shard mutex
+// when many requests for keys of the same shard arrive at once.
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ShardedCache struct {
+	hasher       workerHasher
+	shards       []*cacheShard
+	hashRingStep uint64
+	conf         *Config
+	wg           syncutil.WaitGroup
+}
+
+type cacheShard struct {
+	mutex sync.Mutex
+	name  string
+	cache Cache
+}
+
+var _ CacheEngine = &ShardedCache{}
+
+// NewShardedCache creates a ShardedCache with one shard per `Config.Workers`
+func NewShardedCache(conf *Config) *ShardedCache {
+	setter.SetDefault(&conf.CacheSize, 50_000)
+
+	c := &ShardedCache{
+		shards:       make([]*cacheShard, conf.Workers),
+		hasher:       newHasher(),
+		hashRingStep: uint64(1<<63) / uint64(conf.Workers),
+		conf:         conf,
+	}
+
+	conf.Logger.Infof("Starting %d Gubernator cache shards...", conf.Workers)
+	for i := range c.shards {
+		// Shares the counter with the WorkerPool such that metric labels are unique
+		shardNumber := atomic.AddInt64(&workerCounter, 1) - 1
+		c.shards[i] = &cacheShard{
+			name:  strconv.FormatInt(shardNumber, 10),
+			cache: conf.CacheFactory(conf.CacheSize / conf.Workers),
+		}
+		setCacheMaxBytes(c.shards[i].cache, conf.CacheMaxBytes/int64(conf.Workers))
+		if conf.CacheCollector != nil {
+			conf.CacheCollector.AddNamedCache(c.shards[i].name, c.shards[i].cache)
+		}
+	}
+
+	if conf.CacheSweepInterval > 0 {
+		c.runSweeper()
+	}
+	return c
+}
+
+// Close stops the background sweeper
+func (c *ShardedCache) Close() error {
+	c.wg.Stop()
+	return nil
+}
+
+// Resize is not supported, the shards are looked up without a lock and can't be replaced
+// while the ShardedCache is in use.
+func (c *ShardedCache) Resize(_ context.Context, n int) error {
+	if n == len(c.shards) {
+		return nil
+	}
+	return errors.Errorf("engine '%s' can't be resized; restart with %d workers instead",
+		EngineShardedMutex, n)
+}
+
+// runSweeper periodically removes expired items from each shard in turn, holding only
+// the lock of the shard being swept.
+func (c *ShardedCache) runSweeper() {
+	ticker := clock.NewTicker(c.conf.CacheSweepInterval)
+	c.wg.Until(func(done chan struct{}) bool {
+		select {
+		case now := <-ticker.C():
+			for _, shard := range c.shards {
+				shard.mutex.Lock()
+				sweepCache(shard.name, shard.cache, now, c.conf.CacheSweepBudget)
+				shard.mutex.Unlock()
+				metricCommandCounter.WithLabelValues(shard.name, "Sweep").Inc()
+			}
+			return true
+		case <-done:
+			ticker.Stop()
+			return false
+		}
+	})
+}
+
+// getShard returns the shard which owns the key
+func (c *ShardedCache) getShard(key string) *cacheShard {
+	return c.shards[c.hasher.ComputeHash63(key)/c.hashRingStep]
+}
+
+// lock acquires the shard mutex unless the context is cancelled first.
+func (s *cacheShard) lock(ctx context.Context, method string) error {
+	if !s.mutex.TryLock() {
+		queueGauge := metricWorkerQueue.WithLabelValues(method, s.name)
+		queueGauge.Inc()
+		defer queueGauge.Dec()
+		s.mutex.Lock()
+	}
+	if ctx.Err() != nil {
+		s.mutex.Unlock()
+		return ctx.Err()
+	}
+	metricCommandCounter.WithLabelValues(s.name, method).Inc()
+	return nil
+}
+
+// GetRateLimit applies the rate limit request to the shard which owns the rate limit.
+func (c *ShardedCache) GetRateLimit(ctx context.Context, req *RateLimitReq, reqState RateLimitReqState) (*RateLimitResp, error) {
+	shard := c.getShard(req.HashKey())
+	if err := shard.lock(ctx, "GetRateLimit"); err != nil {
+		return nil, err
+	}
+	defer shard.mutex.Unlock()
+
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("ShardedCache.GetRateLimit")).ObserveDuration()
+	return applyRateLimit(ctx, c.conf.Store, shard.cache, req, reqState)
+}
+
+// LeaseTokens returns the unused tokens of a previous lease and leases new tokens from
+// the rate limit while holding the lock of the shard which owns the rate limit.
+func (c *ShardedCache) LeaseTokens(ctx context.Context, req *LeaseReq, returned int64) (*RateLimitResp, int64, error) {
+	shard := c.getShard(req.RateLimit.HashKey())
+	if err := shard.lock(ctx, "LeaseTokens"); err != nil {
+		return nil, 0, notAppliedError{err}
+	}
+	defer shard.mutex.Unlock()
+
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("ShardedCache.LeaseTokens")).ObserveDuration()
+	return applyLeaseTokens(ctx, c.conf.Store, shard.cache, req, returned)
+}
+
+// AddCacheItem adds an item to the shard's cache.
+func (c *ShardedCache) AddCacheItem(ctx context.Context, key string, item *CacheItem) error {
+	shard := c.getShard(key)
+	if err := shard.lock(ctx, "AddCacheItem"); err != nil {
+		return err
+	}
+	defer shard.mutex.Unlock()
+
+	shard.cache.Add(item)
+	return nil
+}
+
+// GetCacheItem gets item from the shard's cache.
+func (c *ShardedCache) GetCacheItem(ctx context.Context, key string) (*CacheItem, bool, error) {
+	shard := c.getShard(key)
+	if err := shard.lock(ctx, "GetCacheItem"); err != nil {
+		return nil, false, err
+	}
+	defer shard.mutex.Unlock()
+
+	item, ok := shard.cache.GetItem(key)
+	return item, ok, nil
+}
+
+// Load reads from persistent storage and adds each item to the cache of the shard which owns it.
+func (c *ShardedCache) Load(ctx context.Context) error {
+	ch, err := c.conf.Loader.Load()
+	if err != nil {
+		return errors.Wrap(err, "Error in loader.Load")
+	}
+
+	for {
+		var item *CacheItem
+		var ok bool
+
+		select {
+		case item, ok = <-ch:
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if err := c.AddCacheItem(ctx, item.Key, item); err != nil {
+			return err
+		}
+	}
+}
+
+// Store saves the caches of all shards to persistent storage. Each shard is
+// locked while its items are sent to the Loader.
+func (c *ShardedCache) Store(ctx context.Context) error {
+	if err := c.conf.Loader.Save(c.each(ctx, "Store")); err != nil {
+		return errors.Wrap(err, "while calling c.conf.Loader.Save()")
+	}
+	return ctx.Err()
+}
+
+// Items returns all items in the caches of all shards.
+func (c *ShardedCache) Items(ctx context.Context) ([]*CacheItem, error) {
+	var items []*CacheItem
+	for item := range c.each(ctx, "Items") {
+		items = append(items, item)
+	}
+	return items, ctx.Err()
+}
+
+// each iterates the cache of each shard to the returned channel, which is closed once
+// all shards are done. Each shard is locked while its items are iterated.
+func (c *ShardedCache) each(ctx context.Context, method string) chan *CacheItem {
+	out := make(chan *CacheItem, 500)
+
+	go func() {
+		defer close(out)
+		for _, shard := range c.shards {
+			if err := shard.lock(ctx, method); err != nil {
+				return
+			}
+			items := shard.cache.Each()
+			for item := range items {
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					// Drain the iterator such that its goroutine exits
+					for range items {
+					}
+					shard.mutex.Unlock()
+					return
+				}
+			}
+			shard.mutex.Unlock()
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	guber "github.com/gubernator-io/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCache(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name   string
+		shards int
+	}{
+		{"Single-shard", 1},
+		{"Multi-shard", 4},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Setup mock data.
+			const NumCacheItems = 100
+			cacheItems := []*guber.CacheItem{}
+			for i := 0; i < NumCacheItems; i++ {
+				cacheItems = append(cacheItems, &guber.CacheItem{
+					Key:      fmt.Sprintf("Foobar%04d", i),
+					Value:    fmt.Sprintf("Stuff%04d", i),
+					ExpireAt: 4131978658000,
+				})
+			}
+
+			t.Run("Load()", func(t *testing.T) {
+				mockLoader := &MockLoader2{}
+				mockCache := &MockCache{}
+				conf := &guber.Config{
+					CacheFactory: func(maxSize int) guber.Cache {
+						return mockCache
+					},
+					Loader:  mockLoader,
+					Workers: testCase.shards,
+					Engine:  guber.EngineShardedMutex,
+				}
+				require.NoError(t, conf.SetDefaults())
+				engine := guber.NewCacheEngine(conf)
+				require.IsType(t, &guber.ShardedCache{}, engine)
+
+				fakeLoadCh := make(chan *guber.CacheItem, NumCacheItems)
+				for _, item := range cacheItems {
+					fakeLoadCh <- item
+				}
+				close(fakeLoadCh)
+				mockLoader.On("Load").Once().Return(fakeLoadCh, nil)
+				for _, item := range cacheItems {
+					mockCache.On("Add", item).Once().Return(false)
+				}
+
+				require.NoError(t, engine.Load(ctx))
+				mockCache.AssertExpectations(t)
+			})
+
+			t.Run("Resize()", func(t *testing.T) {
+				conf := &guber.Config{Workers: testCase.shards}
+				require.NoError(t, conf.SetDefaults())
+				engine := guber.NewShardedCache(conf)
+				defer engine.Close()
+
+				require.NoError(t, engine.Resize(ctx, testCase.shards))
+				err := engine.Resize(ctx, testCase.shards+1)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "can't be resized")
+			})
+
+			t.Run("Store()", func(t *testing.T) {
+				mockLoader := &MockLoader2{}
+				mockCache := &MockCache{}
+				conf := &guber.Config{
+					CacheFactory: func(maxSize int) guber.Cache {
+						return mockCache
+					},
+					Loader:  mockLoader,
+					Workers: testCase.shards,
+				}
+				require.NoError(t, conf.SetDefaults())
+				engine := guber.NewShardedCache(conf)
+
+				mockLoader.On("Save", mock.Anything).Once().Return(nil).
+					Run(func(args mock.Arguments) {
+						saveCh := args.Get(0).(chan *guber.CacheItem)
+						savedItems := []*guber.CacheItem{}
+						for item := range saveCh {
+							savedItems = append(savedItems, item)
+						}
+
+						sort.Slice(savedItems, func(a, b int) bool {
+							return savedItems[a].Key < savedItems[b].Key
+						})
+						assert.Equal(t, cacheItems, savedItems)
+					})
+
+				// Every shard shares the mock cache, the items are only read from the channel once
+				eachCh := make(chan *guber.CacheItem, NumCacheItems)
+				for _, item := range cacheItems {
+					eachCh <- item
+				}
+				close(eachCh)
+				mockCache.On("Each").Times(testCase.shards).Return(eachCh)
+
+				require.NoError(t, engine.Store(ctx))
+				mockLoader.AssertExpectations(t)
+			})
+		})
+	}
+
+	t.Run("Concurrent GetRateLimit()", func(t *testing.T) {
+		conf := &guber.Config{Workers: 4}
+		require.NoError(t, conf.SetDefaults())
+		engine := guber.NewShardedCache(conf)
+		defer engine.Close()
+
+		const concurrency, hits = 50, 20
+		createdAt := epochMillis(time.Now())
+		req := func() *guber.RateLimitReq {
+			return &guber.RateLimitReq{
+				Name:      t.Name(),
+				UniqueKey: "account:1234",
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     concurrency * hits,
+				Hits:      1,
+				CreatedAt: &createdAt,
+			}
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < hits; j++ {
+					resp, err := engine.GetRateLimit(ctx, req(), guber.RateLimitReqState{IsOwner: true})
+					assert.NoError(t, err)
+					assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Status)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Every hit was applied exactly once
+		resp, err := engine.GetRateLimit(ctx, req(), guber.RateLimitReqState{IsOwner: true})
+		require.NoError(t, err)
+		assert.Equal(t, guber.Status_OVER_LIMIT, resp.Status)
+		assert.Equal(t, int64(0), resp.Remaining)
+	})
+}
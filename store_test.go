@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"testing"
 
 	"github.com/gubernator-io/gubernator/v2"
@@ -28,6 +29,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 type v1Server struct {
@@ -531,3 +533,85 @@ func TestStore(t *testing.T) {
 		})
 	}
 }
+
+// memoryStore is a Store which keeps a copy of the token bucket rate limits it is told about
+type memoryStore struct {
+	mutex sync.Mutex
+	items map[string]gubernator.CacheItem
+}
+
+func (m *memoryStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	clone := *item
+	value := *item.Value.(*gubernator.TokenBucketItem)
+	clone.Value = &value
+	m.items[item.Key] = clone
+}
+
+func (m *memoryStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, ok := m.items[r.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	value := *item.Value.(*gubernator.TokenBucketItem)
+	item.Value = &value
+	return &item, true
+}
+
+func (m *memoryStore) Remove(ctx context.Context, key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.items, key)
+}
+
+func (m *memoryStore) remaining(key string) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return -1
+	}
+	return item.Value.(*gubernator.TokenBucketItem).Remaining
+}
+
+func TestStoreLeaseReturnedTokens(t *testing.T) {
+	ctx := context.Background()
+	req := &gubernator.RateLimitReq{
+		Name:      "test_lease",
+		UniqueKey: "account:1234",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+	}
+	store := &memoryStore{items: make(map[string]gubernator.CacheItem)}
+
+	// A cache of a single rate limit, such that leasing another rate limit evicts the first
+	srv := newV1Server(t, "localhost:0", gubernator.Config{Store: store, Workers: 1, CacheSize: 1})
+	defer func() { require.NoError(t, srv.Close()) }()
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	lease := func(r *gubernator.LeaseReq) *gubernator.LeaseResp {
+		resp, err := client.LeaseTokens(ctx, &gubernator.LeaseTokensReq{Requests: []*gubernator.LeaseReq{r}})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	first := lease(&gubernator.LeaseReq{RateLimit: req, Tokens: 5})
+	assert.Equal(t, int64(5), first.Tokens)
+	assert.Equal(t, int64(5), store.remaining(req.HashKey()))
+
+	other := proto.Clone(req).(*gubernator.RateLimitReq)
+	other.UniqueKey = "account:5678"
+	lease(&gubernator.LeaseReq{RateLimit: other, Tokens: 1})
+
+	// Tokens returned to a rate limit evicted from the cache are returned to the state in
+	// the store, and the store is told about the returned tokens
+	resp := lease(&gubernator.LeaseReq{RateLimit: req, ReturnLeaseId: first.LeaseId, Returned: 3})
+	assert.Equal(t, int64(8), resp.RateLimit.Remaining)
+	assert.Equal(t, int64(8), store.remaining(req.HashKey()))
+}
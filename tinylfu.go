@@ -0,0 +1,461 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+// W-TinyLFU as described in https://arxiv.org/abs/1512.00727
+// New items enter a small LRU window. Items which fall out of the window must
+// compete with the least recently used item of the main cache to be admitted;
+// the item which was accessed more often according to a frequency sketch wins.
+// Ties keep the item already in the main cache. A scan of unique keys is
+// therefore confined to the window and cannot flush frequently used or long
+// lived rate limits out of the main cache.
+//
+// The main cache is a segmented LRU. Items admitted from the window enter the
+// probation segment and are promoted to the protected segment when accessed again.
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/mailgun/holster/v4/setter"
+)
+
+const (
+	tinyLFUWindow = iota
+	tinyLFUProbation
+	tinyLFUProtected
+)
+
+// Seeds the frequency sketch hash such that it's independent of the hash used to assign keys to workers
+const tinyLFUHashSeed = 0x9e3779b97f4a7c15
+
+// TinyLFUCache is a scan resistant cache that supports expiration and is not thread-safe
+// Be sure to use a mutex to prevent concurrent method calls.
+type TinyLFUCache struct {
+	cache    map[string]*list.Element
+	segments [3]*list.List
+	sketch   *frequencySketch
+	// The max number of items in the window and in the protected segment
+	windowSize    int
+	protectedSize int
+	cacheSize     int
+	cacheLen      int64
+	maxBytes      int64
+	bytes         int64
+	// The segment and next element Sweep() inspects, nil to start over from the oldest element of the window
+	sweepSegment int
+	sweepCursor  *list.Element
+	stats        CacheStats
+}
+
+type tinyLFUEntry struct {
+	item    *CacheItem
+	segment int
+}
+
+var _ Cache = &TinyLFUCache{}
+var _ cacheStatsProvider = &TinyLFUCache{}
+var _ cacheSweeper = &TinyLFUCache{}
+var _ cacheByteLimiter = &TinyLFUCache{}
+
+// NewTinyLFUCache creates a new Cache with a maximum size which uses W-TinyLFU to decide which items to evict.
+func NewTinyLFUCache(maxSize int) *TinyLFUCache {
+	setter.SetDefault(&maxSize, 50_000)
+
+	// 1% of the cache is the window, 80% of the remaining main cache is protected
+	windowSize := max(1, maxSize/100)
+	c := &TinyLFUCache{
+		cache:         make(map[string]*list.Element),
+		sketch:        newFrequencySketch(maxSize),
+		windowSize:    windowSize,
+		protectedSize: (maxSize - windowSize) * 8 / 10,
+		cacheSize:     maxSize,
+	}
+	for i := range c.segments {
+		c.segments[i] = list.New()
+	}
+	return c
+}
+
+// SetMaxBytes limits the estimated bytes used by the items in the cache. Once the max bytes is
+// exceeded, the least recently used items of the main cache are evicted, then those of the window.
+// A `maxBytes` of 0 means the bytes are not limited.
+func (c *TinyLFUCache) SetMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.evictBytes()
+}
+
+// Each is not thread-safe. Each() maintains a goroutine that iterates.
+// Other go routines cannot safely access the Cache while iterating.
+func (c *TinyLFUCache) Each() chan *CacheItem {
+	out := make(chan *CacheItem)
+	go func() {
+		for _, ele := range c.cache {
+			out <- ele.Value.(*tinyLFUEntry).item
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Add adds a value to the cache. New items are added to the window and may cause the
+// least recently used item in the window or the main cache to be evicted.
+func (c *TinyLFUCache) Add(item *CacheItem) bool {
+	if ele, ok := c.cache[item.Key]; ok {
+		entry := ele.Value.(*tinyLFUEntry)
+		c.addBytes(CacheItemBytes(item) - CacheItemBytes(entry.item))
+		entry.item = item
+		c.segments[entry.segment].MoveToFront(ele)
+		c.evictBytes()
+		return true
+	}
+
+	c.cache[item.Key] = c.segments[tinyLFUWindow].PushFront(&tinyLFUEntry{item: item, segment: tinyLFUWindow})
+	c.addBytes(CacheItemBytes(item))
+	if c.segments[tinyLFUWindow].Len() > c.windowSize {
+		c.admit(c.segments[tinyLFUWindow].Back())
+	}
+	c.evictBytes()
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
+	return false
+}
+
+// evictBytes evicts the least recently used items of the main cache, then those of the window,
+// until the cache is within its max bytes. The most recently added item is never evicted.
+func (c *TinyLFUCache) evictBytes() {
+	for c.overBytes() && len(c.cache) > 1 {
+		for _, segment := range []int{tinyLFUProbation, tinyLFUProtected, tinyLFUWindow} {
+			if victim := c.segments[segment].Back(); victim != nil {
+				c.evict(victim)
+				break
+			}
+		}
+	}
+}
+
+func (c *TinyLFUCache) overBytes() bool {
+	return c.maxBytes != 0 && c.bytes > c.maxBytes
+}
+
+func (c *TinyLFUCache) addBytes(n int64) {
+	atomic.StoreInt64(&c.bytes, c.bytes+n)
+}
+
+// admit moves the candidate from the window to the main cache if there is room, otherwise
+// either the candidate or the least recently used item of the main cache is evicted.
+func (c *TinyLFUCache) admit(candidate *list.Element) {
+	if len(c.cache) <= c.cacheSize && !c.overBytes() {
+		c.moveTo(candidate, tinyLFUProbation)
+		return
+	}
+
+	candidateItem := candidate.Value.(*tinyLFUEntry).item
+	if candidateItem.IsExpired() {
+		c.evict(candidate)
+		return
+	}
+
+	// Expired items make room before any item competes with the candidate
+	for _, segment := range []int{tinyLFUProbation, tinyLFUProtected} {
+		if victim := c.segments[segment].Back(); victim != nil && victim.Value.(*tinyLFUEntry).item.IsExpired() {
+			c.evict(victim)
+			c.moveTo(candidate, tinyLFUProbation)
+			return
+		}
+	}
+
+	victim := c.segments[tinyLFUProbation].Back()
+	if victim == nil {
+		victim = c.segments[tinyLFUProtected].Back()
+	}
+	if victim != nil && c.sketch.estimate(candidateItem.Key) > c.sketch.estimate(victim.Value.(*tinyLFUEntry).item.Key) {
+		c.evict(victim)
+		c.moveTo(candidate, tinyLFUProbation)
+		return
+	}
+	c.evict(candidate)
+}
+
+// moveTo moves the element to the front of the segment
+func (c *TinyLFUCache) moveTo(ele *list.Element, segment int) {
+	entry := ele.Value.(*tinyLFUEntry)
+	c.segments[entry.segment].Remove(ele)
+	entry.segment = segment
+	c.cache[entry.item.Key] = c.segments[segment].PushFront(entry)
+}
+
+// evict removes the element to make room for other items
+func (c *TinyLFUCache) evict(ele *list.Element) {
+	if MillisecondNow() < ele.Value.(*tinyLFUEntry).item.ExpireAt {
+		metricCacheUnexpiredEvictions.Add(1)
+		atomic.AddInt64(&c.stats.Evicted, 1)
+	} else {
+		atomic.AddInt64(&c.stats.Expired, 1)
+	}
+	c.removeElement(ele)
+}
+
+func (c *TinyLFUCache) removeElement(ele *list.Element) {
+	entry := ele.Value.(*tinyLFUEntry)
+	c.segments[entry.segment].Remove(ele)
+	delete(c.cache, entry.item.Key)
+	c.addBytes(-CacheItemBytes(entry.item))
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
+}
+
+// GetItem returns the item stored in the cache
+func (c *TinyLFUCache) GetItem(key string) (item *CacheItem, ok bool) {
+	c.sketch.increment(key)
+
+	ele, hit := c.cache[key]
+	if !hit {
+		metricCacheAccess.WithLabelValues("miss").Add(1)
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return
+	}
+
+	entry := ele.Value.(*tinyLFUEntry)
+	if entry.item.IsExpired() {
+		c.removeElement(ele)
+		metricCacheAccess.WithLabelValues("miss").Add(1)
+		atomic.AddInt64(&c.stats.Expired, 1)
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return
+	}
+
+	metricCacheAccess.WithLabelValues("hit").Add(1)
+	atomic.AddInt64(&c.stats.Hits, 1)
+
+	switch entry.segment {
+	case tinyLFUProbation:
+		// Promote the item, demoting the least recently used protected item if the segment is full
+		c.moveTo(ele, tinyLFUProtected)
+		if c.segments[tinyLFUProtected].Len() > c.protectedSize {
+			c.moveTo(c.segments[tinyLFUProtected].Back(), tinyLFUProbation)
+		}
+	default:
+		c.segments[entry.segment].MoveToFront(ele)
+	}
+	return entry.item, true
+}
+
+// Remove removes the provided key from the cache.
+func (c *TinyLFUCache) Remove(key string) {
+	if ele, hit := c.cache[key]; hit {
+		c.removeElement(ele)
+	}
+}
+
+// UpdateExpiration updates the expiration time for the key
+func (c *TinyLFUCache) UpdateExpiration(key string, expireAt int64) bool {
+	if ele, hit := c.cache[key]; hit {
+		ele.Value.(*tinyLFUEntry).item.ExpireAt = expireAt
+		return true
+	}
+	return false
+}
+
+// Sweep removes the items expired at now from the cache, starting where the previous call stopped. Stops once
+// all items have been inspected or the budget is spent, returns the number of items removed.
+func (c *TinyLFUCache) Sweep(now time.Time, budget time.Duration) int {
+	if c.cache == nil {
+		return 0
+	}
+	// See LRUCache.Sweep()
+	start := time.Now()
+	nowMs := now.UnixNano() / 1000000
+
+	// Start over if the cursor was removed from the cache or moved to another segment since the last sweep
+	segment, ele := c.sweepSegment, c.sweepCursor
+	if ele == nil || c.cache[ele.Value.(*tinyLFUEntry).item.Key] != ele {
+		segment, ele = tinyLFUWindow, c.segments[tinyLFUWindow].Back()
+	}
+
+	var swept int
+	for i := 1; ; i++ {
+		if ele == nil {
+			if segment == tinyLFUProtected {
+				break
+			}
+			segment++
+			ele = c.segments[segment].Back()
+			continue
+		}
+		// Checking the clock is expensive relative to inspecting an item
+		if i%64 == 0 && time.Since(start) >= budget {
+			break
+		}
+		prev := ele.Prev()
+		item := ele.Value.(*tinyLFUEntry).item
+		if item.ExpireAt < nowMs || (item.InvalidAt != 0 && item.InvalidAt < nowMs) {
+			c.removeElement(ele)
+			swept++
+		}
+		ele = prev
+	}
+	c.sweepSegment, c.sweepCursor = segment, ele
+	atomic.AddInt64(&c.stats.Expired, int64(swept))
+	return swept
+}
+
+// Size returns the number of items in the cache.
+func (c *TinyLFUCache) Size() int64 {
+	return atomic.LoadInt64(&c.cacheLen)
+}
+
+// Bytes returns the estimated bytes used by the items in the cache.
+func (c *TinyLFUCache) Bytes() int64 {
+	return atomic.LoadInt64(&c.bytes)
+}
+
+// Stats returns the counts of accesses to the cache. Unlike other methods, Stats is safe to
+// call concurrently.
+func (c *TinyLFUCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:        atomic.LoadInt64(&c.stats.Hits),
+		Misses:      atomic.LoadInt64(&c.stats.Misses),
+		StoreHits:   atomic.LoadInt64(&c.stats.StoreHits),
+		StoreMisses: atomic.LoadInt64(&c.stats.StoreMisses),
+		Expired:     atomic.LoadInt64(&c.stats.Expired),
+		Evicted:     atomic.LoadInt64(&c.stats.Evicted),
+	}
+}
+
+func (c *TinyLFUCache) countStoreAccess(found bool) {
+	if found {
+		atomic.AddInt64(&c.stats.StoreHits, 1)
+		return
+	}
+	atomic.AddInt64(&c.stats.StoreMisses, 1)
+}
+
+func (c *TinyLFUCache) Close() error {
+	c.cache = nil
+	for i := range c.segments {
+		c.segments[i].Init()
+	}
+	c.cacheLen = 0
+	c.bytes = 0
+	c.sweepCursor = nil
+	return nil
+}
+
+// frequencySketch is a count-min sketch which estimates how often each key was accessed
+// recently. The first access of a key only sets its bits in the doorkeeper, a bloom filter,
+// such that keys which are accessed once don't add to the error of the estimates of other
+// keys. Counters saturate at 15 and are halved once the number of accesses reaches 10 times
+// the cache size, such that keys which are no longer accessed are forgotten.
+type frequencySketch struct {
+	counters   []uint8
+	doorkeeper []uint64
+	width      uint64
+	// Shifts the product of a hash and a multiplier to an index of a counter or doorkeeper bit
+	shift           uint64
+	doorkeeperShift uint64
+	additions       int
+	sampleSize      int
+}
+
+// Multipliers which derive an independent counter index for each row from the hash of a key,
+// the number of rows is the number of counters incremented for each key
+var sketchMultipliers = [...]uint64{
+	0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xd6e8feb86659fd93,
+}
+
+func newFrequencySketch(maxSize int) *frequencySketch {
+	sampleSize := 10 * maxSize
+	// Several counters per item keeps the error of the estimates caused by collisions low, the
+	// doorkeeper needs about 8 bits per key accessed between resets for few false positives.
+	bits, doorkeeperBits := log2Ceil(4*maxSize), log2Ceil(8*sampleSize)
+	return &frequencySketch{
+		counters:        make([]uint8, len(sketchMultipliers)<<bits),
+		doorkeeper:      make([]uint64, 1<<doorkeeperBits/64),
+		width:           1 << bits,
+		shift:           64 - bits,
+		doorkeeperShift: 64 - doorkeeperBits,
+		sampleSize:      sampleSize,
+	}
+}
+
+// log2Ceil returns the smallest power of 2 exponent >= 6 such that 1<<n >= size
+func log2Ceil(size int) uint64 {
+	n := uint64(6)
+	for 1<<n < size {
+		n++
+	}
+	return n
+}
+
+// index returns the index of the counter for the key in row `i`
+func (s *frequencySketch) index(hash uint64, i int) uint64 {
+	return uint64(i)*s.width + (hash*sketchMultipliers[i])>>s.shift
+}
+
+// inDoorkeeper returns true if the doorkeeper bits of the key are set
+func (s *frequencySketch) inDoorkeeper(hash uint64) bool {
+	for _, m := range sketchMultipliers {
+		idx := (hash * m) >> s.doorkeeperShift
+		if s.doorkeeper[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *frequencySketch) increment(key string) {
+	hash := xxhash.ChecksumString64S(key, tinyLFUHashSeed)
+	if !s.inDoorkeeper(hash) {
+		for _, m := range sketchMultipliers {
+			idx := (hash * m) >> s.doorkeeperShift
+			s.doorkeeper[idx/64] |= 1 << (idx % 64)
+		}
+	} else {
+		for i := range sketchMultipliers {
+			if idx := s.index(hash, i); s.counters[idx] < 15 {
+				s.counters[idx]++
+			}
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		for i := range s.counters {
+			s.counters[i] >>= 1
+		}
+		for i := range s.doorkeeper {
+			s.doorkeeper[i] = 0
+		}
+		s.additions /= 2
+	}
+}
+
+// estimate returns the estimated number of recent accesses of the key
+func (s *frequencySketch) estimate(key string) uint8 {
+	hash := xxhash.ChecksumString64S(key, tinyLFUHashSeed)
+	freq := uint8(15)
+	for i := range sketchMultipliers {
+		if c := s.counters[s.index(hash, i)]; c < freq {
+			freq = c
+		}
+	}
+	if freq < 15 && s.inDoorkeeper(hash) {
+		freq++
+	}
+	return freq
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gubernator-io/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTinyLFUCache(t *testing.T) {
+	const iterations = 1000
+	const concurrency = 100
+	expireAt := clock.Now().Add(1 * time.Hour).UnixMilli()
+	var mutex sync.Mutex
+
+	// get simulates a rate limit check, which adds the item to the cache on a miss
+	get := func(cache gubernator.Cache, key string, expireAt int64) {
+		if _, ok := cache.GetItem(key); !ok {
+			cache.Add(&gubernator.CacheItem{
+				Key:      key,
+				Value:    key,
+				ExpireAt: expireAt,
+			})
+		}
+	}
+
+	t.Run("Happy path", func(t *testing.T) {
+		cache := gubernator.NewTinyLFUCache(0)
+
+		// Populate cache.
+		for i := 0; i < iterations; i++ {
+			key := strconv.Itoa(i)
+			item := &gubernator.CacheItem{
+				Key:      key,
+				Value:    i,
+				ExpireAt: expireAt,
+			}
+			exists := cache.Add(item)
+			assert.False(t, exists)
+		}
+
+		// Validate cache.
+		assert.Equal(t, int64(iterations), cache.Size())
+
+		for i := 0; i < iterations; i++ {
+			key := strconv.Itoa(i)
+			item, ok := cache.GetItem(key)
+			require.True(t, ok)
+			require.NotNil(t, item)
+			assert.Equal(t, item.Value, i)
+		}
+
+		var count int
+		for range cache.Each() {
+			count++
+		}
+		assert.Equal(t, iterations, count)
+
+		// Clear cache.
+		for i := 0; i < iterations; i++ {
+			cache.Remove(strconv.Itoa(i))
+		}
+
+		assert.Zero(t, cache.Size())
+	})
+
+	t.Run("Update an existing key", func(t *testing.T) {
+		cache := gubernator.NewTinyLFUCache(0)
+		const key = "foobar"
+
+		// Add key.
+		item1 := &gubernator.CacheItem{
+			Key:      key,
+			Value:    "initial value",
+			ExpireAt: expireAt,
+		}
+		exists1 := cache.Add(item1)
+		require.False(t, exists1)
+
+		// Update same key.
+		item2 := &gubernator.CacheItem{
+			Key:      key,
+			Value:    "new value",
+			ExpireAt: expireAt,
+		}
+		exists2 := cache.Add(item2)
+		require.True(t, exists2)
+
+		// Verify.
+		verifyItem, ok := cache.GetItem(key)
+		require.True(t, ok)
+		assert.Equal(t, item2, verifyItem)
+	})
+
+	t.Run("Never exceeds the max size", func(t *testing.T) {
+		cache := gubernator.NewTinyLFUCache(100)
+
+		for i := 0; i < iterations; i++ {
+			get(cache, strconv.Itoa(i), expireAt)
+			require.LessOrEqual(t, cache.Size(), int64(100))
+		}
+		assert.Equal(t, int64(100), cache.Size())
+	})
+
+	t.Run("Never exceeds the max bytes", func(t *testing.T) {
+		cache := gubernator.NewTinyLFUCache(0)
+		itemBytes := gubernator.CacheItemBytes(&gubernator.CacheItem{Key: "0000", Value: "0000"})
+		cache.SetMaxBytes(itemBytes * 100)
+
+		for i := 0; i < iterations; i++ {
+			get(cache, fmt.Sprintf("%04d", i), expireAt)
+			require.LessOrEqual(t, cache.Bytes(), itemBytes*100)
+		}
+		assert.Equal(t, int64(100), cache.Size())
+		assert.Equal(t, itemBytes*100, cache.Bytes())
+
+		// Longer keys use more bytes
+		get(cache, "a-much-longer-key", expireAt)
+		assert.Equal(t, int64(99), cache.Size())
+		assert.LessOrEqual(t, cache.Bytes(), itemBytes*100)
+
+		cache.Remove("a-much-longer-key")
+		assert.Equal(t, itemBytes*98, cache.Bytes())
+	})
+
+	t.Run("Hot keys survive a scan", func(t *testing.T) {
+		const size = 1000
+		lru := gubernator.NewLRUCache(size)
+		lfu := gubernator.NewTinyLFUCache(size)
+
+		// Half of the cache is used by keys which are checked frequently
+		for _, cache := range []gubernator.Cache{lru, lfu} {
+			for round := 0; round < 5; round++ {
+				for i := 0; i < size/2; i++ {
+					get(cache, fmt.Sprintf("hot-%d", i), expireAt)
+				}
+			}
+		}
+
+		// A scan of unique keys, each checked once, twice the size of the cache
+		for _, cache := range []gubernator.Cache{lru, lfu} {
+			for i := 0; i < size*2; i++ {
+				get(cache, fmt.Sprintf("scan-%d", i), expireAt)
+			}
+		}
+
+		var lruHits, lfuHits int
+		for i := 0; i < size/2; i++ {
+			key := fmt.Sprintf("hot-%d", i)
+			if _, ok := lru.GetItem(key); ok {
+				lruHits++
+			}
+			if _, ok := lfu.GetItem(key); ok {
+				lfuHits++
+			}
+		}
+		assert.Zero(t, lruHits)
+		assert.Equal(t, size/2, lfuHits)
+	})
+
+	t.Run("Long duration buckets survive a scan", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		const size = 1000
+		lru := gubernator.NewLRUCache(size)
+		lfu := gubernator.NewTinyLFUCache(size)
+		dailyExpireAt := clock.Now().Add(24 * time.Hour).UnixMilli()
+
+		// Daily quota buckets which are each checked only once
+		for _, cache := range []gubernator.Cache{lru, lfu} {
+			for i := 0; i < size/2; i++ {
+				get(cache, fmt.Sprintf("daily-%d", i), dailyExpireAt)
+			}
+		}
+
+		// Followed by a burst of unique keys which fills the cache
+		for _, cache := range []gubernator.Cache{lru, lfu} {
+			for i := 0; i < size; i++ {
+				get(cache, fmt.Sprintf("scan-%d", i), expireAt)
+			}
+		}
+
+		var lruHits, lfuHits int
+		for i := 0; i < size/2; i++ {
+			key := fmt.Sprintf("daily-%d", i)
+			if _, ok := lru.GetItem(key); ok {
+				lruHits++
+			}
+			if _, ok := lfu.GetItem(key); ok {
+				lfuHits++
+			}
+		}
+		assert.Zero(t, lruHits)
+		assert.Equal(t, size/2, lfuHits)
+
+		// Once the daily buckets expire, new keys replace them without evicting unexpired items
+		evicted := lfu.Stats().Evicted
+		clock.Advance(25 * time.Hour)
+		for i := 0; i < size; i++ {
+			get(lfu, fmt.Sprintf("new-%d", i), clock.Now().Add(time.Hour).UnixMilli())
+		}
+		for i := 0; i < size; i++ {
+			_, ok := lfu.GetItem(fmt.Sprintf("new-%d", i))
+			require.True(t, ok)
+		}
+		assert.Equal(t, evicted, lfu.Stats().Evicted)
+	})
+
+	t.Run("Expired items are removed", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		cache := gubernator.NewTinyLFUCache(0)
+
+		for i := 0; i < 200; i++ {
+			ttl := time.Hour
+			if i%2 == 0 {
+				ttl = time.Minute
+			}
+			cache.Add(&gubernator.CacheItem{
+				Key:      fmt.Sprintf("key-%03d", i),
+				Value:    i,
+				ExpireAt: clock.Now().Add(ttl).UnixMilli(),
+			})
+		}
+		// Promote some of the items to the protected segment
+		for i := 0; i < 200; i += 3 {
+			_, ok := cache.GetItem(fmt.Sprintf("key-%03d", i))
+			require.True(t, ok)
+		}
+
+		clock.Advance(2 * time.Minute)
+		_, ok := cache.GetItem("key-000")
+		assert.False(t, ok)
+
+		// Sweeps every segment
+		assert.Equal(t, 99, cache.Sweep(clock.Now(), time.Second))
+		assert.Equal(t, int64(100), cache.Size())
+		for item := range cache.Each() {
+			assert.False(t, item.IsExpired())
+		}
+		assert.Equal(t, int64(100), cache.Stats().Expired)
+	})
+
+	t.Run("Concurrent reads and writes", func(t *testing.T) {
+		cache := gubernator.NewTinyLFUCache(iterations / 2)
+		var doneWg sync.WaitGroup
+
+		for thread := 0; thread < concurrency; thread++ {
+			doneWg.Add(1)
+			go func() {
+				defer doneWg.Done()
+				for i := 0; i < iterations; i++ {
+					mutex.Lock()
+					get(cache, strconv.Itoa(i), expireAt)
+					mutex.Unlock()
+				}
+			}()
+		}
+
+		doneWg.Wait()
+		assert.Equal(t, int64(iterations/2), cache.Size())
+	})
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/gubernator-io/gubernator/v2"
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/retry"
+	"github.com/mailgun/holster/v4/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -308,6 +309,16 @@ func TestHTTPSClientAuth(t *testing.T) {
 	d := spawnDaemon(t, conf)
 	defer d.Close()
 
+	// Wait for the capabilities handshake with the peer, which is reported by the health check
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		assert.True(t, d.V1Server.GetPeerList()[0].Capabilities().Negotiated)
+	})
+	expected := `{"status":"healthy","message":"","peer_count":1,"advertise_address":"127.0.0.1:9695",` +
+		`"capabilities":["peer_stream","global_interest","global_crdt","peer_load","handoff","compression","peer_load_shed"],` +
+		`"peer_capabilities":[{"grpc_address":"127.0.0.1:9695","negotiated":true,"protocol_version":1,` +
+		`"capabilities":["peer_stream","global_interest","global_crdt","peer_load","handoff","compression","peer_load_shed"]}],` +
+		`"peer_circuit_breakers":[]}`
+
 	clientWithCert := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: conf.TLS.ClientTLS,
@@ -333,7 +344,7 @@ func TestHTTPSClientAuth(t *testing.T) {
 	defer resp.Body.Close()
 	b, err := io.ReadAll(resp.Body)
 	require.NoError(t, err)
-	assert.Equal(t, `{"status":"healthy","message":"","peer_count":1,"advertise_address":"127.0.0.1:9695"}`, strings.ReplaceAll(string(b), " ", ""))
+	assert.Equal(t, expected, strings.ReplaceAll(string(b), " ", ""))
 
 	// Verify we get an error when we try to access existing HTTPListenAddress without cert
 	//nolint:bodyclose // Expect error, no body to close.
@@ -346,7 +357,7 @@ func TestHTTPSClientAuth(t *testing.T) {
 	defer resp2.Body.Close()
 	b, err = io.ReadAll(resp2.Body)
 	require.NoError(t, err)
-	assert.Equal(t, `{"status":"healthy","message":"","peer_count":1,"advertise_address":"127.0.0.1:9695"}`, strings.ReplaceAll(string(b), " ", ""))
+	assert.Equal(t, expected, strings.ReplaceAll(string(b), " ", ""))
 
 }
 
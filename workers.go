@@ -30,6 +30,9 @@ package gubernator
 //   the next step.)
 // - Workers are assigned equal size hash ranges.  The worker is selected by
 //   choosing the worker index associated with that linear hash value range.
+// - The workers and their hash ranges form a ring which is replaced atomically
+//   by Resize().  Requests sent to a worker retired by Resize() are sent again
+//   to the worker which owns the key in the new ring.
 // - The worker has command channels for each method call.  The request is
 //   enqueued to the appropriate channel.
 // - The worker pulls the request from the appropriate channel and executes the
@@ -38,12 +41,13 @@ package gubernator
 
 import (
 	"context"
-	"io"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/OneOfOne/xxhash"
+	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/errors"
 	"github.com/mailgun/holster/v4/setter"
 	"github.com/prometheus/client_golang/prometheus"
@@ -52,13 +56,19 @@ import (
 )
 
 type WorkerPool struct {
-	hasher          workerHasher
-	workers         []*Worker
-	workerCacheSize int
-	hashRingStep    uint64
-	conf            *Config
-	done            chan struct{}
-	isClosed        atomic.Bool
+	hasher   workerHasher
+	ring     atomic.Pointer[workerRing]
+	conf     *Config
+	done     chan struct{}
+	isClosed atomic.Bool
+	// Serializes Resize() with Load() and Store(), which use every worker of the ring
+	mutex sync.Mutex
+}
+
+// workerRing assigns each worker an equal size range of the 63-bit hash space
+type workerRing struct {
+	workers      []*Worker
+	hashRingStep uint64
 }
 
 type Worker struct {
@@ -70,6 +80,12 @@ type Worker struct {
 	loadRequest         chan workerLoadRequest
 	addCacheItemRequest chan workerAddCacheItemRequest
 	getCacheItemRequest chan workerGetCacheItemRequest
+	leaseTokensRequest  chan workerLeaseTokensRequest
+	resizeRequest       chan workerResizeRequest
+	// Closed once the worker is retired by Resize()
+	done chan struct{}
+	// The number of requests queued for the worker
+	queued atomic.Int64
 }
 
 type workerHasher interface {
@@ -118,7 +134,33 @@ type workerGetCacheItemResponse struct {
 	ok   bool
 }
 
-var _ io.Closer = &WorkerPool{}
+type workerLeaseTokensRequest struct {
+	ctx      context.Context
+	response chan workerLeaseTokensResponse
+	req      *LeaseReq
+	returned int64
+}
+
+type workerLeaseTokensResponse struct {
+	rl      *RateLimitResp
+	granted int64
+	err     error
+}
+
+type workerResizeRequest struct {
+	ctx      context.Context
+	response chan workerResizeResponse
+	hasher   workerHasher
+	ring     *workerRing
+	// Closed once every worker has responded and the pool has switched to the new ring
+	release <-chan struct{}
+}
+
+type workerResizeResponse struct {
+	err error
+}
+
+var _ CacheEngine = &WorkerPool{}
 var _ workerHasher = &hasher{}
 
 var workerCounter int64
@@ -126,27 +168,44 @@ var workerCounter int64
 func NewWorkerPool(conf *Config) *WorkerPool {
 	setter.SetDefault(&conf.CacheSize, 50_000)
 
-	// Compute hashRingStep as interval between workers' 63-bit hash ranges.
-	// 64th bit is used here as a max value that is just out of range of 63-bit space to calculate the step.
 	chp := &WorkerPool{
-		workers:         make([]*Worker, conf.Workers),
-		workerCacheSize: conf.CacheSize / conf.Workers,
-		hasher:          newHasher(),
-		hashRingStep:    uint64(1<<63) / uint64(conf.Workers),
-		conf:            conf,
-		done:            make(chan struct{}),
+		hasher: newHasher(),
+		conf:   conf,
+		done:   make(chan struct{}),
 	}
 
 	// Create workers.
 	conf.Logger.Infof("Starting %d Gubernator workers...", conf.Workers)
-	for i := 0; i < conf.Workers; i++ {
-		chp.workers[i] = chp.newWorker()
-		go chp.dispatch(chp.workers[i])
-	}
+	chp.ring.Store(chp.newRing(conf.Workers))
 
 	return chp
 }
 
+// newRing creates and starts `n` workers which share the cache size of the pool.
+func (p *WorkerPool) newRing(n int) *workerRing {
+	// Compute hashRingStep as interval between workers' 63-bit hash ranges.
+	// 64th bit is used here as a max value that is just out of range of 63-bit space to calculate the step.
+	ring := &workerRing{
+		workers:      make([]*Worker, n),
+		hashRingStep: uint64(1<<63) / uint64(n),
+	}
+	for i := 0; i < n; i++ {
+		ring.workers[i] = p.newWorker(n)
+		// The sweep ticker is created before the worker starts, such that the worker never
+		// reads the clock from its own goroutine.
+		var sweep clock.Ticker
+		if p.conf.CacheSweepInterval > 0 {
+			sweep = clock.NewTicker(p.conf.CacheSweepInterval)
+		}
+		go p.dispatch(ring.workers[i], sweep)
+	}
+	return ring
+}
+
+func (r *workerRing) getWorker(hash uint64) *Worker {
+	return r.workers[hash/r.hashRingStep]
+}
+
 func newHasher() *hasher {
 	return &hasher{}
 }
@@ -163,35 +222,198 @@ func (p *WorkerPool) Close() error {
 	return nil
 }
 
-// Create a new pool worker instance.
-func (p *WorkerPool) newWorker() *Worker {
+// Resize changes the number of workers to `n` without losing the items in the cache.
+// Each worker finishes the request it is handling and moves its items to the worker which
+// owns them in the new ring, then the pool switches to the new ring. Requests wait while
+// the items are moved and are then handled by the new workers. Should the context be
+// canceled before all items are moved, the pool continues with the current workers.
+func (p *WorkerPool) Resize(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.Errorf("invalid worker count '%d'; must be greater than 0", n)
+	}
+	if p.isClosed.Load() {
+		return errors.New("worker pool is closed")
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	old := p.ring.Load()
+	if len(old.workers) == n {
+		return nil
+	}
+
+	p.conf.Logger.Infof("Resizing from %d to %d Gubernator workers...", len(old.workers), n)
+	ring := p.newRing(n)
+	release := make(chan struct{})
+
+	err := func() error {
+		// Tie up every worker while its items are moved.
+		respChans := make([]chan workerResizeResponse, 0, len(old.workers))
+		for _, worker := range old.workers {
+			respChan := make(chan workerResizeResponse, 1)
+			req := workerResizeRequest{
+				ctx:      ctx,
+				response: respChan,
+				hasher:   p.hasher,
+				ring:     ring,
+				release:  release,
+			}
+
+			select {
+			case worker.resizeRequest <- req:
+				// Successfully sent request.
+				respChans = append(respChans, respChan)
+
+			case <-ctx.Done():
+				// Context canceled.
+				return ctx.Err()
+			}
+		}
+
+		for _, respChan := range respChans {
+			select {
+			case resp := <-respChan:
+				// Successfully received response.
+				if resp.err != nil {
+					return resp.err
+				}
+
+			case <-ctx.Done():
+				// Context canceled.
+				return ctx.Err()
+			}
+		}
+		return nil
+	}()
+
+	if err != nil {
+		// The current workers resume with their items and the new workers are discarded.
+		for _, worker := range ring.workers {
+			close(worker.done)
+		}
+		p.removeCaches(ring.workers)
+		close(release)
+		return errors.Wrap(err, "while moving cache items to the new workers")
+	}
+
+	// Switch rings before the current workers are retired, such that requests
+	// sent to a retired worker are sent again to the new ring.
+	p.ring.Store(ring)
+	for _, worker := range old.workers {
+		close(worker.done)
+	}
+	p.removeCaches(old.workers)
+	close(release)
+	return nil
+}
+
+// Create a new pool worker instance, one of `n` workers.
+func (p *WorkerPool) newWorker(n int) *Worker {
 	worker := &Worker{
 		conf:                p.conf,
-		cache:               p.conf.CacheFactory(p.workerCacheSize),
+		cache:               p.conf.CacheFactory(p.conf.CacheSize / n),
 		getRateLimitRequest: make(chan request),
 		storeRequest:        make(chan workerStoreRequest),
 		loadRequest:         make(chan workerLoadRequest),
 		addCacheItemRequest: make(chan workerAddCacheItemRequest),
 		getCacheItemRequest: make(chan workerGetCacheItemRequest),
+		leaseTokensRequest:  make(chan workerLeaseTokensRequest),
+		resizeRequest:       make(chan workerResizeRequest),
+		done:                make(chan struct{}),
 	}
+	setCacheMaxBytes(worker.cache, p.conf.CacheMaxBytes/int64(n))
 	workerNumber := atomic.AddInt64(&workerCounter, 1) - 1
 	worker.name = strconv.FormatInt(workerNumber, 10)
+	if p.conf.CacheCollector != nil {
+		p.conf.CacheCollector.AddNamedCache(worker.name, worker.cache)
+	}
 	return worker
 }
 
+// removeCaches stops reporting the caches of workers which are retired or discarded by Resize()
+func (p *WorkerPool) removeCaches(workers []*Worker) {
+	if p.conf.CacheCollector == nil {
+		return
+	}
+	for _, worker := range workers {
+		p.conf.CacheCollector.RemoveCache(worker.cache)
+	}
+}
+
+// enqueue counts a request queued for the worker, and returns a func which counts the request
+// as no longer queued. Once the last request queued for a retired worker is no longer queued,
+// the queue metrics of the worker are deleted.
+func (worker *Worker) enqueue(method string) func() {
+	gauge := metricWorkerQueue.WithLabelValues(method, worker.name)
+	worker.queued.Add(1)
+	gauge.Inc()
+	return func() {
+		gauge.Dec()
+		if worker.queued.Add(-1) == 0 && worker.retired() {
+			metricWorkerQueue.DeletePartialMatch(prometheus.Labels{"worker": worker.name})
+		}
+	}
+}
+
+func (worker *Worker) retired() bool {
+	select {
+	case <-worker.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop deletes the metrics of a worker which was retired or discarded by Resize(). Called
+// by the worker once it stopped handling requests, such that they are not reported again.
+func (worker *Worker) stop() {
+	_ = worker.cache.Close()
+	metricCommandCounter.DeletePartialMatch(prometheus.Labels{"worker": worker.name})
+	metricCacheSweptCounter.DeleteLabelValues(worker.name)
+	if worker.queued.Load() == 0 {
+		metricWorkerQueue.DeletePartialMatch(prometheus.Labels{"worker": worker.name})
+	}
+}
+
 // getWorker Returns the request channel associated with the key.
 // Hash the key, then lookup hash ring to find the worker.
 func (p *WorkerPool) getWorker(key string) *Worker {
-	hash := p.hasher.ComputeHash63(key)
-	idx := hash / p.hashRingStep
-	return p.workers[idx]
+	return p.ring.Load().getWorker(p.hasher.ComputeHash63(key))
+}
+
+// sendToWorker sends the request to the worker which owns the key. Should the worker be
+// retired by Resize() before it receives the request, the request is sent to the worker
+// which owns the key in the new ring.
+func sendToWorker[T any](ctx context.Context, p *WorkerPool, worker *Worker, key string, channel func(*Worker) chan T, req T) error {
+	for {
+		select {
+		case channel(worker) <- req:
+			// Successfully sent request.
+			return nil
+
+		case <-worker.done:
+			// Worker retired.
+			worker = p.getWorker(key)
+
+		case <-ctx.Done():
+			// Context canceled.
+			return ctx.Err()
+		}
+	}
 }
 
 // Pool worker for processing Gubernator requests.
 // Each worker maintains its own state.
 // A hash ring will distribute requests to an assigned worker by key.
 // See: getWorker()
-func (p *WorkerPool) dispatch(worker *Worker) {
+func (p *WorkerPool) dispatch(worker *Worker, sweepTicker clock.Ticker) {
+	// Expired items are swept between requests such that the cache is never accessed concurrently
+	var sweep <-chan time.Time
+	if sweepTicker != nil {
+		defer sweepTicker.Stop()
+		sweep = sweepTicker.C()
+	}
+
 	for {
 		// Dispatch requests from each channel.
 		select {
@@ -254,6 +476,35 @@ func (p *WorkerPool) dispatch(worker *Worker) {
 			worker.handleGetCacheItem(req, worker.cache)
 			metricCommandCounter.WithLabelValues(worker.name, "GetCacheItem").Inc()
 
+		case req, ok := <-worker.leaseTokensRequest:
+			if !ok {
+				// Channel closed.  Unexpected, but should be handled.
+				logrus.Error("workerPool worker stopped because channel closed")
+				return
+			}
+
+			worker.handleLeaseTokens(req, worker.cache)
+			metricCommandCounter.WithLabelValues(worker.name, "LeaseTokens").Inc()
+
+		case req := <-worker.resizeRequest:
+			retired := worker.handleResize(req, worker.cache)
+			metricCommandCounter.WithLabelValues(worker.name, "Resize").Inc()
+			if retired {
+				// Items were moved to the new ring, any requests still sent to this
+				// worker are sent again to the new ring.
+				worker.stop()
+				return
+			}
+
+		case <-worker.done:
+			// Worker of a canceled resize.
+			worker.stop()
+			return
+
+		case now := <-sweep:
+			sweepCache(worker.name, worker.cache, now, p.conf.CacheSweepBudget)
+			metricCommandCounter.WithLabelValues(worker.name, "Sweep").Inc()
+
 		case <-p.done:
 			// Clean up.
 			return
@@ -265,9 +516,7 @@ func (p *WorkerPool) dispatch(worker *Worker) {
 func (p *WorkerPool) GetRateLimit(ctx context.Context, rlRequest *RateLimitReq, reqState RateLimitReqState) (*RateLimitResp, error) {
 	// Delegate request to assigned channel based on request key.
 	worker := p.getWorker(rlRequest.HashKey())
-	queueGauge := metricWorkerQueue.WithLabelValues("GetRateLimit", worker.name)
-	queueGauge.Inc()
-	defer queueGauge.Dec()
+	defer worker.enqueue("GetRateLimit")()
 	handlerRequest := request{
 		ctx:      ctx,
 		resp:     make(chan *response, 1),
@@ -276,11 +525,11 @@ func (p *WorkerPool) GetRateLimit(ctx context.Context, rlRequest *RateLimitReq,
 	}
 
 	// Send request.
-	select {
-	case worker.getRateLimitRequest <- handlerRequest:
-		// Successfully sent request.
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	err := sendToWorker(ctx, p, worker, rlRequest.HashKey(), func(w *Worker) chan request {
+		return w.getRateLimitRequest
+	}, handlerRequest)
+	if err != nil {
+		return nil, err
 	}
 
 	// Wait for response.
@@ -296,12 +545,18 @@ func (p *WorkerPool) GetRateLimit(ctx context.Context, rlRequest *RateLimitReq,
 // Handle request received by worker.
 func (worker *Worker) handleGetRateLimit(ctx context.Context, req *RateLimitReq, reqState RateLimitReqState, cache Cache) (*RateLimitResp, error) {
 	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("Worker.handleGetRateLimit")).ObserveDuration()
+	return applyRateLimit(ctx, worker.conf.Store, cache, req, reqState)
+}
+
+// applyRateLimit applies the algorithm of the request to the cache. The caller must ensure
+// no other calls access the cache concurrently.
+func applyRateLimit(ctx context.Context, s Store, cache Cache, req *RateLimitReq, reqState RateLimitReqState) (*RateLimitResp, error) {
 	var rlResponse *RateLimitResp
 	var err error
 
 	switch req.Algorithm {
 	case Algorithm_TOKEN_BUCKET:
-		rlResponse, err = tokenBucket(ctx, worker.conf.Store, cache, req, reqState)
+		rlResponse, err = tokenBucket(ctx, s, cache, req, reqState)
 		if err != nil {
 			msg := "Error in tokenBucket"
 			countError(err, msg)
@@ -310,7 +565,7 @@ func (worker *Worker) handleGetRateLimit(ctx context.Context, req *RateLimitReq,
 		}
 
 	case Algorithm_LEAKY_BUCKET:
-		rlResponse, err = leakyBucket(ctx, worker.conf.Store, cache, req, reqState)
+		rlResponse, err = leakyBucket(ctx, s, cache, req, reqState)
 		if err != nil {
 			msg := "Error in leakyBucket"
 			countError(err, msg)
@@ -334,6 +589,8 @@ func (p *WorkerPool) Load(ctx context.Context) (err error) {
 	queueGauge := metricWorkerQueue.WithLabelValues("Load", "")
 	queueGauge.Inc()
 	defer queueGauge.Dec()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	ch, err := p.conf.Loader.Load()
 	if err != nil {
 		return errors.Wrap(err, "Error in loader.Load")
@@ -456,11 +713,44 @@ func (p *WorkerPool) Store(ctx context.Context) (err error) {
 	queueGauge := metricWorkerQueue.WithLabelValues("Store", "")
 	queueGauge.Inc()
 	defer queueGauge.Dec()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	out := p.each(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err = p.conf.Loader.Save(out); err != nil {
+		return errors.Wrap(err, "while calling p.conf.Loader.Save()")
+	}
+
+	return nil
+}
+
+// Items returns all items in the workers' caches.
+func (p *WorkerPool) Items(ctx context.Context) ([]*CacheItem, error) {
+	queueGauge := metricWorkerQueue.WithLabelValues("Items", "")
+	queueGauge.Inc()
+	defer queueGauge.Dec()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var items []*CacheItem
+	for item := range p.each(ctx) {
+		items = append(items, item)
+	}
+	return items, ctx.Err()
+}
+
+// each iterates each worker's cache to the returned channel, which is closed once all
+// workers are done. GUARDED_BY(mutex)
+func (p *WorkerPool) each(ctx context.Context) chan *CacheItem {
 	var wg sync.WaitGroup
 	out := make(chan *CacheItem, 500)
 
 	// Iterate each worker's cache to `out` channel.
-	for _, worker := range p.workers {
+	for _, worker := range p.ring.Load().workers {
 		wg.Add(1)
 
 		go func(ctx context.Context, worker *Worker) {
@@ -501,15 +791,7 @@ func (p *WorkerPool) Store(ctx context.Context) (err error) {
 		close(out)
 	}()
 
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	if err = p.conf.Loader.Save(out); err != nil {
-		return errors.Wrap(err, "while calling p.conf.Loader.Save()")
-	}
-
-	return nil
+	return out
 }
 
 func (worker *Worker) handleStore(request workerStoreRequest, cache Cache) {
@@ -540,9 +822,7 @@ func (worker *Worker) handleStore(request workerStoreRequest, cache Cache) {
 // AddCacheItem adds an item to the worker's cache.
 func (p *WorkerPool) AddCacheItem(ctx context.Context, key string, item *CacheItem) (err error) {
 	worker := p.getWorker(key)
-	queueGauge := metricWorkerQueue.WithLabelValues("AddCacheItem", worker.name)
-	queueGauge.Inc()
-	defer queueGauge.Dec()
+	defer worker.enqueue("AddCacheItem")()
 	respChan := make(chan workerAddCacheItemResponse)
 	req := workerAddCacheItemRequest{
 		ctx:      ctx,
@@ -550,18 +830,17 @@ func (p *WorkerPool) AddCacheItem(ctx context.Context, key string, item *CacheIt
 		item:     item,
 	}
 
-	select {
-	case worker.addCacheItemRequest <- req:
-		// Successfully sent request.
-		select {
-		case <-respChan:
-			// Successfully received response.
-			return nil
+	err = sendToWorker(ctx, p, worker, key, func(w *Worker) chan workerAddCacheItemRequest {
+		return w.addCacheItemRequest
+	}, req)
+	if err != nil {
+		return err
+	}
 
-		case <-ctx.Done():
-			// Context canceled.
-			return ctx.Err()
-		}
+	select {
+	case <-respChan:
+		// Successfully received response.
+		return nil
 
 	case <-ctx.Done():
 		// Context canceled.
@@ -586,9 +865,7 @@ func (worker *Worker) handleAddCacheItem(request workerAddCacheItemRequest, cach
 // GetCacheItem gets item from worker's cache.
 func (p *WorkerPool) GetCacheItem(ctx context.Context, key string) (item *CacheItem, found bool, err error) {
 	worker := p.getWorker(key)
-	queueGauge := metricWorkerQueue.WithLabelValues("GetCacheItem", worker.name)
-	queueGauge.Inc()
-	defer queueGauge.Dec()
+	defer worker.enqueue("GetCacheItem")()
 	respChan := make(chan workerGetCacheItemResponse)
 	req := workerGetCacheItemRequest{
 		ctx:      ctx,
@@ -596,18 +873,17 @@ func (p *WorkerPool) GetCacheItem(ctx context.Context, key string) (item *CacheI
 		key:      key,
 	}
 
-	select {
-	case worker.getCacheItemRequest <- req:
-		// Successfully sent request.
-		select {
-		case resp := <-respChan:
-			// Successfully received response.
-			return resp.item, resp.ok, nil
+	err = sendToWorker(ctx, p, worker, key, func(w *Worker) chan workerGetCacheItemRequest {
+		return w.getCacheItemRequest
+	}, req)
+	if err != nil {
+		return nil, false, err
+	}
 
-		case <-ctx.Done():
-			// Context canceled.
-			return nil, false, ctx.Err()
-		}
+	select {
+	case resp := <-respChan:
+		// Successfully received response.
+		return resp.item, resp.ok, nil
 
 	case <-ctx.Done():
 		// Context canceled.
@@ -628,3 +904,124 @@ func (worker *Worker) handleGetCacheItem(request workerGetCacheItemRequest, cach
 		trace.SpanFromContext(request.ctx).RecordError(request.ctx.Err())
 	}
 }
+
+// LeaseTokens returns the unused tokens of a previous lease and leases new tokens from
+// the rate limit in a single operation on the worker which owns the rate limit.
+func (p *WorkerPool) LeaseTokens(ctx context.Context, req *LeaseReq, returned int64) (rl *RateLimitResp, granted int64, err error) {
+	worker := p.getWorker(req.RateLimit.HashKey())
+	defer worker.enqueue("LeaseTokens")()
+	respChan := make(chan workerLeaseTokensResponse)
+	handlerRequest := workerLeaseTokensRequest{
+		ctx:      ctx,
+		response: respChan,
+		req:      req,
+		returned: returned,
+	}
+
+	err = sendToWorker(ctx, p, worker, req.RateLimit.HashKey(), func(w *Worker) chan workerLeaseTokensRequest {
+		return w.leaseTokensRequest
+	}, handlerRequest)
+	if err != nil {
+		return nil, 0, notAppliedError{err}
+	}
+
+	select {
+	case resp := <-respChan:
+		// Successfully received response.
+		return resp.rl, resp.granted, resp.err
+
+	case <-ctx.Done():
+		// Context canceled.
+		return nil, 0, ctx.Err()
+	}
+}
+
+func (worker *Worker) handleLeaseTokens(request workerLeaseTokensRequest, cache Cache) {
+	defer prometheus.NewTimer(metricFuncTimeDuration.WithLabelValues("Worker.handleLeaseTokens")).ObserveDuration()
+	var response workerLeaseTokensResponse
+
+	response.rl, response.granted, response.err = applyLeaseTokens(request.ctx, worker.conf.Store, cache, request.req, request.returned)
+
+	select {
+	case request.response <- response:
+		// Successfully sent response.
+
+	case <-request.ctx.Done():
+		// Context canceled.
+		trace.SpanFromContext(request.ctx).RecordError(request.ctx.Err())
+	}
+}
+
+// handleResize moves the items in the cache to the workers which own them in the new ring,
+// then waits until the pool either switched to the new ring or canceled the resize. Returns
+// true if the worker was retired.
+func (worker *Worker) handleResize(request workerResizeRequest, cache Cache) bool {
+	var response workerResizeResponse
+	respChan := make(chan workerAddCacheItemResponse)
+
+	for item := range cache.Each() {
+		// Once an error occurs, the remaining items are drained such that the iterator exits.
+		if response.err != nil || item.IsExpired() {
+			continue
+		}
+
+		target := request.ring.getWorker(request.hasher.ComputeHash63(item.Key))
+		req := workerAddCacheItemRequest{
+			ctx:      request.ctx,
+			response: respChan,
+			item:     item,
+		}
+
+		select {
+		case target.addCacheItemRequest <- req:
+			// Successfully sent request.
+			select {
+			case <-respChan:
+				// Successfully received response.
+
+			case <-request.ctx.Done():
+				// Context canceled.
+				response.err = request.ctx.Err()
+			}
+
+		case <-request.ctx.Done():
+			// Context canceled.
+			response.err = request.ctx.Err()
+		}
+	}
+
+	// Buffered, such that the response never blocks.
+	request.response <- response
+	<-request.release
+
+	select {
+	case <-worker.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sweepCache removes the items expired at now from the cache if the cache supports it. The
+// caller must ensure no other calls access the cache concurrently.
+func sweepCache(name string, cache Cache, now time.Time, budget time.Duration) {
+	sweeper, ok := cache.(cacheSweeper)
+	if !ok {
+		return
+	}
+	defer prometheus.NewTimer(metricCacheSweepDuration).ObserveDuration()
+	metricCacheSweptCounter.WithLabelValues(name).Add(float64(sweeper.Sweep(now, budget)))
+}
+
+// applyLeaseTokens leases tokens from the rate limit in the cache. The caller must ensure
+// no other calls access the cache concurrently.
+func applyLeaseTokens(ctx context.Context, s Store, cache Cache, req *LeaseReq, returned int64) (*RateLimitResp, int64, error) {
+	rl, granted, err := leaseTokens(ctx, s, cache, req, returned)
+	if err != nil {
+		msg := "Error in leaseTokens"
+		countError(err, msg)
+		err = errors.Wrap(err, msg)
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+	return rl, granted, err
+}
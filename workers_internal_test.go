@@ -17,8 +17,16 @@ limitations under the License.
 package gubernator
 
 import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -71,8 +79,8 @@ func TestWorkersInternal(t *testing.T) {
 				require.NotNil(t, worker)
 
 				var actualIdx int
-				for ; actualIdx < len(pool.workers); actualIdx++ {
-					if pool.workers[actualIdx] == worker {
+				for ; actualIdx < len(pool.ring.Load().workers); actualIdx++ {
+					if pool.ring.Load().workers[actualIdx] == worker {
 						break
 					}
 				}
@@ -81,4 +89,109 @@ func TestWorkersInternal(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("CacheCollector", func(t *testing.T) {
+		conf := &Config{
+			Workers:        3,
+			CacheCollector: NewLRUCacheCollector(),
+		}
+		require.NoError(t, conf.SetDefaults())
+		pool := NewWorkerPool(conf)
+		defer pool.Close()
+
+		// The caches are labeled with the names of the workers, like the other worker metrics
+		assert.Equal(t, workerNames(pool), collectedWorkers(t, conf.CacheCollector, "gubernator_cache_bytes"))
+
+		// The caches of retired workers are no longer reported
+		require.NoError(t, pool.Resize(context.Background(), 5))
+		assert.Len(t, collectedWorkers(t, conf.CacheCollector, "gubernator_cache_bytes"), 5)
+		assert.Equal(t, workerNames(pool), collectedWorkers(t, conf.CacheCollector, "gubernator_cache_bytes"))
+
+		require.NoError(t, pool.Resize(context.Background(), 2))
+		assert.Equal(t, workerNames(pool), collectedWorkers(t, conf.CacheCollector, "gubernator_cache_bytes"))
+
+		// Nor are the caches of the workers discarded by a canceled resize
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Error(t, pool.Resize(ctx, 4))
+		assert.Equal(t, workerNames(pool), collectedWorkers(t, conf.CacheCollector, "gubernator_cache_bytes"))
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		conf := &Config{Workers: 3}
+		require.NoError(t, conf.SetDefaults())
+		pool := NewWorkerPool(conf)
+		defer pool.Close()
+
+		// Each worker handles some of the requests, and sweeps its cache
+		createdAt := MillisecondNow()
+		for i := 0; i < 100; i++ {
+			_, err := pool.GetRateLimit(context.Background(), &RateLimitReq{
+				Name:      t.Name(),
+				UniqueKey: strconv.Itoa(i),
+				Hits:      1,
+				Limit:     10,
+				Duration:  Minute,
+				CreatedAt: &createdAt,
+			}, RateLimitReqState{IsOwner: true})
+			require.NoError(t, err)
+		}
+		for _, worker := range pool.ring.Load().workers {
+			sweepCache(worker.name, worker.cache, clock.Now(), 0)
+		}
+		metrics := map[string]prometheus.Collector{
+			"gubernator_command_counter":     metricCommandCounter,
+			"gubernator_worker_queue_length": metricWorkerQueue,
+			"gubernator_cache_swept_count":   metricCacheSweptCounter,
+		}
+		retired := workerNames(pool)
+		for name, m := range metrics {
+			assert.Subset(t, collectedWorkers(t, m, name), retired, name)
+		}
+
+		// The series of retired workers are no longer reported once they stopped
+		require.NoError(t, pool.Resize(context.Background(), 2))
+		testutil.UntilPass(t, 20, clock.Millisecond*10, func(t testutil.TestingT) {
+			for name, m := range metrics {
+				for _, worker := range retired {
+					assert.NotContains(t, collectedWorkers(t, m, name), worker, name)
+				}
+			}
+		})
+	})
+}
+
+// workerNames returns the sorted names of the current workers of the pool
+func workerNames(pool *WorkerPool) []string {
+	var names []string
+	for _, worker := range pool.ring.Load().workers {
+		names = append(names, worker.name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// collectedWorkers returns the sorted `worker` labels of the metric reported by the collector
+func collectedWorkers(t require.TestingT, collector prometheus.Collector, name string) []string {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	var names []string
+	for m := range ch {
+		if !strings.Contains(m.Desc().String(), `"`+name+`"`) {
+			continue
+		}
+		met := new(dto.Metric)
+		require.NoError(t, m.Write(met))
+		for _, label := range met.GetLabel() {
+			if label.GetName() == "worker" {
+				names = append(names, label.GetValue())
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
 }
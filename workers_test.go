@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	guber "github.com/gubernator-io/gubernator/v2"
 	"github.com/stretchr/testify/assert"
@@ -131,4 +133,67 @@ func TestGubernatorPool(t *testing.T) {
 			})
 		})
 	}
+
+	t.Run("Resize()", func(t *testing.T) {
+		conf := &guber.Config{Workers: 4}
+		require.NoError(t, conf.SetDefaults())
+		chp := guber.NewWorkerPool(conf)
+		defer chp.Close()
+
+		const numKeys, hits = 100, 10
+		createdAt := epochMillis(time.Now())
+		req := func(i int) *guber.RateLimitReq {
+			return &guber.RateLimitReq{
+				Name:      t.Name(),
+				UniqueKey: fmt.Sprintf("account:%d", i),
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     hits * 4,
+				Hits:      1,
+				CreatedAt: &createdAt,
+			}
+		}
+		check := func() {
+			var wg sync.WaitGroup
+			for i := 0; i < numKeys; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < hits; j++ {
+						_, err := chp.GetRateLimit(ctx, req(i), guber.RateLimitReqState{IsOwner: true})
+						assert.NoError(t, err)
+					}
+				}(i)
+			}
+			wg.Wait()
+		}
+
+		check()
+		require.NoError(t, chp.Resize(ctx, 7))
+
+		// Requests in flight while the workers are resized are applied exactly once
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			check()
+		}()
+		require.NoError(t, chp.Resize(ctx, 1))
+		require.NoError(t, chp.Resize(ctx, 3))
+		wg.Wait()
+
+		// A canceled resize keeps the current workers
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.Error(t, chp.Resize(canceled, 5))
+		assert.Error(t, chp.Resize(ctx, 0))
+		check()
+
+		for i := 0; i < numKeys; i++ {
+			item, ok, err := chp.GetCacheItem(ctx, req(i).HashKey())
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, int64(hits), item.Value.(*guber.TokenBucketItem).Remaining)
+		}
+	})
 }